@@ -70,8 +70,8 @@ func main() {
 	var messageQueue ports.MessageQueue = rabbitMQAdapter
 
 	// 4) Servicios (application)
-	versionService := application.NewVersionService(versionRepository, *cfg.App)             // <- AJUSTAR a tu firma real
-	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App) // <- AJUSTAR a tu firma real
+	versionService := application.NewVersionService(versionRepository, *cfg.App)                              // <- AJUSTAR a tu firma real
+	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App, rabbitMQAdapter) // <- AJUSTAR a tu firma real
 	AiReservesService := application.NewAiReservesService(AiReservesRepository, *cfg.App, messageQueue, httpClient)
 
 	// 5) Handlers (adapters in/http)
@@ -84,14 +84,27 @@ func main() {
 	defer cancel()
 
 	Consumer := eventin.NewEventConsumer(AiReservesService, rabbitMQAdapter)
-	queueName := os.Getenv("USER_CREATED_QUEUE")
+	// Cola propia con los eventos de usuarios de auth-security (user.created / updated / revoked / deleted)
+	queueName := os.Getenv("USER_EVENTS_QUEUE")
 	if queueName == "" {
-		queueName = "user_created_q"
+		queueName = "ai_reserves_users_q"
 	}
 
 	go Consumer.Start(ctx, queueName)
 	logger.LoggerInfo().Infof("🎧 Listening RabbitMQ queue: %s", queueName)
 
+	// Vence las ofertas de la lista de espera sin respuesta y pasa el turno a la siguiente persona
+	startListaEsperaWorker(ctx, AiReservesService)
+
+	// Libera las retenciones de slot de checkouts abandonados
+	startRetencionesWorker(ctx, AiReservesService)
+
+	// Publica los recordatorios de las reservas confirmadas que empiezan pronto
+	startRecordatoriosWorker(ctx, AiReservesService)
+
+	// Publica en RabbitMQ los eventos grabados en el outbox
+	startOutboxRelay(ctx, AiReservesService)
+
 	// 8️⃣ Señales para cerrar graceful
 	go func() {
 		stop := make(chan os.Signal, 1)
@@ -134,3 +147,75 @@ func main() {
 	<-ctx.Done()
 	fmt.Println("✅ Microservicio finalizado correctamente")
 }
+
+func startListaEsperaWorker(ctx context.Context, svc ports.AiReservesService) {
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := svc.ProcessOfertasVencidasAPI(ctx); err != nil {
+					fmt.Println("❌ Lista de espera worker error:", err)
+				}
+			}
+		}
+	}()
+}
+
+func startRetencionesWorker(ctx context.Context, svc ports.AiReservesService) {
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := svc.ReleaseExpiredSlotHoldsAPI(ctx); err != nil {
+					fmt.Println("❌ Retenciones worker error:", err)
+				}
+			}
+		}
+	}()
+}
+
+func startRecordatoriosWorker(ctx context.Context, svc ports.AiReservesService) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := svc.ProcessRecordatoriosAPI(ctx); err != nil {
+					fmt.Println("❌ Recordatorios worker error:", err)
+				}
+			}
+		}
+	}()
+}
+
+func startOutboxRelay(ctx context.Context, svc ports.AiReservesService) {
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := svc.ProcessOutboxAPI(ctx); err != nil {
+					fmt.Println("❌ Outbox relay error:", err)
+				}
+			}
+		}
+	}()
+}
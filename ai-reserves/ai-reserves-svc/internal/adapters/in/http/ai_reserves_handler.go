@@ -1,10 +1,15 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/adapters/in/http/dto"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/adapters/in/http/middlewares"
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
 	"github.com/gin-gonic/gin"
@@ -21,8 +26,33 @@ func NewAiReservesHandler(serv ports.AiReservesService) *AiReservesHandler {
 }
 
 func newErrorResponse(c *gin.Context, err error) {
-	c.JSON(http.StatusBadRequest, dto.DefaultResponse{
-		Code:    400,
+	status := http.StatusBadRequest
+
+	switch {
+	case errors.Is(err, domain.ErrAgendaNotFound), errors.Is(err, domain.ErrSlotNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrReservaNotFound), errors.Is(err, domain.ErrFeriadoNotFound),
+		errors.Is(err, domain.ErrBloqueoNotFound), errors.Is(err, domain.ErrSerieNotFound),
+		errors.Is(err, domain.ErrOcurrenciaNotFound), errors.Is(err, domain.ErrListaEsperaNotFound),
+		errors.Is(err, domain.ErrOfertaNotFound), errors.Is(err, domain.ErrFeedNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrSlotAlreadyBooked), errors.Is(err, domain.ErrReservaEstadoInvalido),
+		errors.Is(err, domain.ErrOfertaNoVigente), errors.Is(err, domain.ErrSlotRetenido),
+		errors.Is(err, domain.ErrRetencionInvalida):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrConflictoConcurrente):
+		// La transacción chocó con otra y se deshizo: el cliente puede repetir el pedido
+		status = http.StatusConflict
+		c.Header("Retry-After", "1")
+	case errors.Is(err, domain.ErrCancelacionFueraDePlazo), errors.Is(err, domain.ErrAnticipacionMinima),
+		errors.Is(err, domain.ErrHorizonteReserva), errors.Is(err, domain.ErrMaxReservasDia),
+		errors.Is(err, domain.ErrMaxReservasSemana), errors.Is(err, domain.ErrBufferReserva),
+		errors.Is(err, domain.ErrPersonaDadaDeBaja):
+		status = http.StatusUnprocessableEntity
+	}
+
+	c.JSON(status, dto.DefaultResponse{
+		Code:    status,
 		Message: err.Error(),
 	})
 }
@@ -307,6 +337,110 @@ func (h *AiReservesHandler) CreateReserve(c *gin.Context) {
 }
 
 func (h *AiReservesHandler) CancelReserve(c *gin.Context) {
+	var req dto.ReservaCancel
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+	}
+
+	// Compatibilidad: el id también puede venir como query ?idReserva=
+	if idStr := c.Query("idReserva"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+		req.IDReserva = id
+	}
+
+	// Quien cancela sale del token; el texto libre del cliente va en Motivo
+	req.CanceladoPor = actorFromContext(c)
+
+	domainReq := domain.ReservaCancel(req)
+
+	if err := h.serv.CancelReserveAPI(c, domainReq); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Reserva cancelada")
+}
+
+func (h *AiReservesHandler) RescheduleReserve(c *gin.Context) {
+	var req dto.ReservaReprogramacion
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	req.Actor = actorFromContext(c)
+
+	reserva, err := h.serv.RescheduleReserveAPI(c, domain.ReservaReprogramacion(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reserva,
+	})
+}
+
+func (h *AiReservesHandler) ConfirmReserve(c *gin.Context) {
+	req, ok := bindTransicion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.serv.ConfirmReserveAPI(c, req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Reserva confirmada")
+}
+
+func (h *AiReservesHandler) CompleteReserve(c *gin.Context) {
+	req, ok := bindTransicion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.serv.CompleteReserveAPI(c, req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Reserva finalizada")
+}
+
+func (h *AiReservesHandler) NoShowReserve(c *gin.Context) {
+	req, ok := bindTransicion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.serv.NoShowReserveAPI(c, req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Reserva marcada como ausente")
+}
+
+// bindTransicion lee el pedido de cambio de estado; el actor sale del token validado
+func bindTransicion(c *gin.Context) (domain.ReservaTransicion, bool) {
+	var req dto.ReservaTransicion
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return domain.ReservaTransicion{}, false
+	}
+
+	req.Actor = actorFromContext(c)
+
+	return domain.ReservaTransicion(req), true
+}
+
+func (h *AiReservesHandler) GetHistorialReserva(c *gin.Context) {
 	idStr := c.Query("idReserva")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -314,11 +448,84 @@ func (h *AiReservesHandler) CancelReserve(c *gin.Context) {
 		return
 	}
 
-	if err := h.serv.CancelReserveAPI(c, id); err != nil {
+	historial, err := h.serv.GetHistorialReservaAPI(c, id)
+	if err != nil {
 		newErrorResponse(c, err)
 		return
 	}
-	newSuccessResponse(c, "Reserva cancelada")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    historial,
+	})
+}
+
+// actorFromContext identifica a quien hace el pedido con la persona del token validado
+func actorFromContext(c *gin.Context) string {
+	if idPersona := c.GetInt(middlewares.IDPersonaKey); idPersona != 0 {
+		return fmt.Sprintf("persona:%d", idPersona)
+	}
+	return "ai_reserves"
+}
+
+// UpsertReglasReserva configura las reglas de reserva del sub tipo o, con IDProfesional, su override
+func (h *AiReservesHandler) UpsertReglasReserva(c *gin.Context) {
+	var req dto.ReglasReservaConfig
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	reglas, err := h.serv.UpsertReglasReservaAPI(c, domain.ReglasReservaConfig(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    dto.ReglasReserva(reglas),
+	})
+}
+
+// GetReglasReserva devuelve las reglas efectivas del sub tipo (con el override del profesional si se indica)
+func (h *AiReservesHandler) GetReglasReserva(c *gin.Context) {
+	var req dto.ReglasReservaFiltro
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	reglas, err := h.serv.GetReglasReservaAPI(c, domain.ReglasReservaFiltro(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    dto.ReglasReserva(reglas),
+	})
+}
+
+// UpdRecordatorios define cuántas horas antes del turno se recuerdan las reservas confirmadas del sub tipo
+func (h *AiReservesHandler) UpdRecordatorios(c *gin.Context) {
+	var req dto.RecordatoriosConfig
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	config, err := h.serv.UpdRecordatoriosAPI(c, domain.RecordatoriosConfig(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    dto.RecordatoriosConfig(config),
+	})
 }
 
 func (h *AiReservesHandler) SearchReserve(c *gin.Context) {
@@ -328,13 +535,58 @@ func (h *AiReservesHandler) SearchReserve(c *gin.Context) {
 		return
 	}
 
+	// Los topes por paciente se aplican sobre quien busca si no se indica otro
+	if req.IDPaciente == 0 {
+		req.IDPaciente = c.GetInt(middlewares.IDPersonaKey)
+	}
+
 	domainReq := domain.SearchReserve(req)
 
-	if err := h.serv.SearchReserveAPI(c, domainReq); err != nil {
+	resultado, err := h.serv.SearchReserveAPI(c, domainReq)
+	if err != nil {
 		newErrorResponse(c, err)
 		return
 	}
-	newSuccessResponse(c, "Búsqueda realizada")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    toResultadoBusquedaDTO(resultado),
+	})
+}
+
+func toResultadoBusquedaDTO(r domain.ResultadoBusqueda) dto.ResultadoBusqueda {
+	resp := dto.ResultadoBusqueda{
+		Dias:          make([]dto.DisponibilidadDia, 0, len(r.Dias)),
+		Pagina:        r.Pagina,
+		TamanioPagina: r.TamanioPagina,
+		TotalSlots:    r.TotalSlots,
+	}
+
+	for _, dia := range r.Dias {
+		d := dto.DisponibilidadDia{
+			Fecha: dia.Fecha.Format("2006-01-02"),
+			Slots: make([]dto.SlotDisponible, 0, len(dia.Slots)),
+		}
+		for _, slot := range dia.Slots {
+			d.Slots = append(d.Slots, dto.SlotDisponible{
+				IDSlot:                slot.IDSlot,
+				IDAgenda:              slot.IDAgenda,
+				IDConfPersonal:        slot.IDConfPersonal,
+				IDProfesional:         slot.IDProfesional,
+				IDConfEstablecimiento: slot.IDConfEstablecimiento,
+				HoraInicio:            slot.HoraInicio,
+				HoraFin:               slot.HoraFin,
+				ZonaHoraria:           slot.ZonaHoraria,
+				Inicio:                slot.Inicio,
+				Fin:                   slot.Fin,
+				Capacidad:             slot.Capacidad,
+				Cupos:                 slot.Cupos,
+			})
+		}
+		resp.Dias = append(resp.Dias, d)
+	}
+
+	return resp
 }
 
 func (h *AiReservesHandler) InitAgenda(c *gin.Context) {
@@ -346,11 +598,16 @@ func (h *AiReservesHandler) InitAgenda(c *gin.Context) {
 
 	domainReq := domain.Agenda(req)
 
-	if err := h.serv.InitAgendaAPI(c, domainReq); err != nil {
+	resumen, err := h.serv.InitAgendaAPI(c, domainReq)
+	if err != nil {
 		newErrorResponse(c, err)
 		return
 	}
-	newSuccessResponse(c, "Agenda inicializada")
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    dto.AgendaResumen(resumen),
+	})
 }
 
 func (h *AiReservesHandler) GetInfoPersona(c *gin.Context) {
@@ -470,3 +727,465 @@ func (hh *AiReservesHandler) PushEventToQueue(c *gin.Context) {
 	c.JSON(http.StatusOK, responseDefault)
 }
 */
+
+func (h *AiReservesHandler) CreateFeriado(c *gin.Context) {
+	var req dto.Feriado
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	id, err := h.serv.CreateFeriadoAPI(c, domain.Feriado(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"id": id},
+	})
+}
+
+func (h *AiReservesHandler) UpdFeriado(c *gin.Context) {
+	var req dto.Feriado
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	if err := h.serv.UpdFeriadoAPI(c, domain.Feriado(req)); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Feriado actualizado")
+}
+
+func (h *AiReservesHandler) DeleteFeriado(c *gin.Context) {
+	idStr := c.Query("idFeriado")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	if err := h.serv.DeleteFeriadoAPI(c, id); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Feriado eliminado")
+}
+
+func (h *AiReservesHandler) GetFeriados(c *gin.Context) {
+	var req dto.FeriadoFiltro
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	feriados, err := h.serv.GetFeriadosAPI(c, domain.FeriadoFiltro(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    feriados,
+	})
+}
+
+// ImportFeriados acepta el archivo como multipart (campo "archivo") o el contenido en el body JSON
+func (h *AiReservesHandler) ImportFeriados(c *gin.Context) {
+	var req dto.FeriadoImport
+
+	if archivo, err := c.FormFile("archivo"); err == nil {
+		f, err := archivo.Open()
+		if err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+		defer f.Close()
+
+		contenido, err := io.ReadAll(f)
+		if err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+
+		req.Contenido = string(contenido)
+		req.Formato = c.PostForm("formato")
+		if idStr := c.PostForm("idConfEstablecimiento"); idStr != "" {
+			if req.IDConfEstablecimiento, err = strconv.Atoi(idStr); err != nil {
+				newErrorResponse(c, err)
+				return
+			}
+		}
+		if req.Formato == "" && strings.HasSuffix(strings.ToLower(archivo.Filename), ".ics") {
+			req.Formato = domain.FeriadoOrigenICal
+		}
+	} else if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	resumen, err := h.serv.ImportFeriadosAPI(c, domain.FeriadoImport(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    dto.FeriadoImportResumen(resumen),
+	})
+}
+
+func (h *AiReservesHandler) CreateBloqueo(c *gin.Context) {
+	var req dto.AgendaBloqueo
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	req.CreatedBy = actorFromContext(c)
+
+	resultado, err := h.serv.CreateBloqueoAPI(c, domain.AgendaBloqueo(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+	})
+}
+
+func (h *AiReservesHandler) GetBloqueos(c *gin.Context) {
+	var req dto.BloqueoFiltro
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	bloqueos, err := h.serv.GetBloqueosAPI(c, domain.BloqueoFiltro(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bloqueos,
+	})
+}
+
+func (h *AiReservesHandler) DeleteBloqueo(c *gin.Context) {
+	idStr := c.Query("idBloqueo")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	if err := h.serv.DeleteBloqueoAPI(c, id); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Bloqueo de agenda eliminado")
+}
+
+// ImportBloqueosICal acepta el .ics como multipart (campo "archivo") o el contenido en el body JSON
+func (h *AiReservesHandler) ImportBloqueosICal(c *gin.Context) {
+	var req dto.BloqueoImport
+
+	if archivo, err := c.FormFile("archivo"); err == nil {
+		f, err := archivo.Open()
+		if err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+		defer f.Close()
+
+		contenido, err := io.ReadAll(f)
+		if err != nil {
+			newErrorResponse(c, err)
+			return
+		}
+
+		req.Contenido = string(contenido)
+		if idStr := c.PostForm("idProfesional"); idStr != "" {
+			if req.IDProfesional, err = strconv.Atoi(idStr); err != nil {
+				newErrorResponse(c, err)
+				return
+			}
+		}
+		if idStr := c.PostForm("idConfPersonal"); idStr != "" {
+			if req.IDConfPersonal, err = strconv.Atoi(idStr); err != nil {
+				newErrorResponse(c, err)
+				return
+			}
+		}
+	} else if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	req.CreatedBy = actorFromContext(c)
+
+	resumen, err := h.serv.ImportBloqueosICalAPI(c, domain.BloqueoImport(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resumen,
+	})
+}
+
+func (h *AiReservesHandler) CreateSerie(c *gin.Context) {
+	var req dto.ReservaSerie
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	domainReq := domain.ReservaSerie{
+		Reserva:     domain.Reserva(req.Reserva),
+		FechaInicio: req.FechaInicio,
+		RRule:       req.RRule,
+		Intervalo:   req.Intervalo,
+		Cantidad:    req.Cantidad,
+		Hasta:       req.Hasta,
+		CreatedBy:   actorFromContext(c),
+	}
+
+	resultado, err := h.serv.CreateSerieAPI(c, domainReq)
+	if errors.Is(err, domain.ErrSerieConConflictos) {
+		// La serie no se creó: se devuelve el detalle de las ocurrencias que chocan
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+			"data":    resultado,
+		})
+		return
+	}
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+	})
+}
+
+func (h *AiReservesHandler) CancelSerie(c *gin.Context) {
+	var req dto.SerieCancel
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	// Quien cancela sale del token; el texto libre del cliente va en Motivo
+	req.CanceladoPor = actorFromContext(c)
+
+	resultado, err := h.serv.CancelSerieAPI(c, domain.SerieCancel(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+	})
+}
+
+func (h *AiReservesHandler) JoinListaEspera(c *gin.Context) {
+	var req dto.ListaEspera
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	// Cada persona se anota a sí misma
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+
+	id, err := h.serv.JoinListaEsperaAPI(c, domain.ListaEspera(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"id": id},
+	})
+}
+
+func (h *AiReservesHandler) LeaveListaEspera(c *gin.Context) {
+	idStr := c.Query("idListaEspera")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	// Solo la persona del token sale de su entrada
+	if err := h.serv.LeaveListaEsperaAPI(c, id, c.GetInt(middlewares.IDPersonaKey)); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Salida de la lista de espera registrada")
+}
+
+func (h *AiReservesHandler) GetListaEspera(c *gin.Context) {
+	var req dto.ListaEsperaFiltro
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	// Solo las entradas de la persona del token
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+
+	lista, err := h.serv.GetListaEsperaAPI(c, domain.ListaEsperaFiltro(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lista,
+	})
+}
+
+func (h *AiReservesHandler) AcceptOferta(c *gin.Context) {
+	var req dto.OfertaRespuesta
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	// Solo la persona del token responde su oferta
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+
+	idReserva, err := h.serv.AcceptOfertaAPI(c, domain.OfertaRespuesta(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"id_reserva": idReserva},
+	})
+}
+
+func (h *AiReservesHandler) DeclineOferta(c *gin.Context) {
+	var req dto.OfertaRespuesta
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	// Solo la persona del token responde su oferta
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+
+	if err := h.serv.DeclineOfertaAPI(c, domain.OfertaRespuesta(req)); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Oferta rechazada")
+}
+
+func (h *AiReservesHandler) HoldSlot(c *gin.Context) {
+	var req dto.RetencionSlot
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	retencion, err := h.serv.HoldSlotAPI(c, domain.RetencionSlot(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    retencion,
+	})
+}
+
+func (h *AiReservesHandler) ReleaseSlotHold(c *gin.Context) {
+	if err := h.serv.ReleaseSlotHoldAPI(c, c.Query("token")); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Retención de slot liberada")
+}
+
+func (h *AiReservesHandler) ExportReservasICS(c *gin.Context) {
+	var req dto.CalendarioFiltro
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	// Solo el calendario de la persona del token: exportar o publicar el de otra persona no está permitido
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+
+	ics, err := h.serv.ExportReservasICSAPI(c, domain.CalendarioFiltro(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="reservas.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ics)
+}
+
+func (h *AiReservesHandler) CreateFeedCalendario(c *gin.Context) {
+	var req dto.FeedCalendario
+	if err := c.BindJSON(&req); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	// Solo el calendario de la persona del token: exportar o publicar el de otra persona no está permitido
+	req.IDPersona = c.GetInt(middlewares.IDPersonaKey)
+	req.CreatedBy = actorFromContext(c)
+
+	feed, err := h.serv.CreateFeedCalendarioAPI(c, domain.FeedCalendario(req))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    feed,
+	})
+}
+
+func (h *AiReservesHandler) RevokeFeedCalendario(c *gin.Context) {
+	if err := h.serv.RevokeFeedCalendarioAPI(c, c.Query("token"), c.GetInt(middlewares.IDPersonaKey)); err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+	newSuccessResponse(c, "Feed de calendario revocado")
+}
+
+// GetFeedCalendario es la URL pública que consumen los clientes de calendario: el token es la credencial
+func (h *AiReservesHandler) GetFeedCalendario(c *gin.Context) {
+	ics, err := h.serv.GetFeedCalendarioAPI(c, c.Param("token"))
+	if err != nil {
+		newErrorResponse(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ics)
+}
@@ -111,34 +111,65 @@ type SubTipoUnidadReservaModif struct {
 }
 
 type Reserva struct {
-	ID                     int
-	IDAgenda               int
-	Fecha                  time.Time
-	HoraInicio             string
-	HoraFin                string
-	IDPaciente             *int
-	Estado                 string
-	Observaciones          *string
-	IDSubTipoUnidadReserva int
+	ID                      int
+	IDAgenda                int
+	Fecha                   time.Time
+	HoraInicio              string
+	HoraFin                 string
+	IDPaciente              *int
+	Estado                  string
+	Observaciones           *string
+	IDSubTipoUnidadReserva  int
+	IDConfEstablecimiento   int
+	IDProfesional           int
+	IDSerie                 int
+	TokenRetencion          string
+	ZonaHoraria             string
+	Inicio                  time.Time
+	Fin                     time.Time
+	RecursosProfesional     []int
+	RecursosEstablecimiento []int
 }
 
 type ReservaCancel struct {
+	IDReserva    int
+	Status       string
+	CanceladoPor string
+	Motivo       string
+}
+
+type ReservaTransicion struct {
 	IDReserva int
-	Status    string
+	Estado    string
+	Actor     string
+	Motivo    string
 }
 
 type SearchReserve struct {
-	IDProfesional   int
-	IDUnidadReserva int
-	FechaDesde      time.Time
-	FechaHasta      time.Time
+	IDProfesional           int
+	IDUnidadReserva         int
+	IDSubTipoUnidadReserva  int
+	IDConfEstablecimiento   int
+	FechaDesde              time.Time
+	FechaHasta              time.Time
+	HoraDesde               string
+	HoraHasta               string
+	DiasSemana              []int
+	PrimeroDisponible       bool
+	IDPaciente              int
+	RecursosProfesional     []int
+	RecursosEstablecimiento []int
+	Pagina                  int
+	TamanioPagina           int
 }
 
 type Agenda struct {
-	IDProfesional   int
-	IDUnidadReserva int
-	FechaDesde      time.Time
-	FechaHasta      time.Time
+	IDProfesional          int
+	IDUnidadReserva        int
+	IDSubTipoUnidadReserva int
+	IDConfEstablecimiento  int
+	FechaDesde             time.Time
+	FechaHasta             time.Time
 }
 
 type GetReservaPersona struct {
@@ -197,6 +228,7 @@ type ConfigPersonaFull struct {
 	Domingo        bool
 	GeneraFeriados bool
 	ModoAgenda     string
+	ZonaHoraria    string
 }
 
 type ConfigPersonalSubTipo struct {
@@ -205,6 +237,26 @@ type ConfigPersonalSubTipo struct {
 	DuracionReservaMinutos int
 }
 
+type ReglasReservaConfig struct {
+	IDSubTipoUnidadReserva    int
+	IDProfesional             int
+	AnticipacionMinimaMinutos *int
+	HorizonteDias             *int
+	MaxReservasDia            *int
+	MaxReservasSemana         *int
+	BufferMinutos             *int
+}
+
+type ReglasReservaFiltro struct {
+	IDSubTipoUnidadReserva int
+	IDProfesional          int
+}
+
+type RecordatoriosConfig struct {
+	IDSubTipoUnidadReserva int
+	HorasAntes             []int
+}
+
 type ConfEstablecimiento struct {
 	ID                     int
 	IDPersona              int
@@ -220,6 +272,7 @@ type ConfEstablecimiento struct {
 	Sabado                 bool
 	Domingo                bool
 	GeneraFeriados         bool
+	ZonaHoraria            string
 }
 
 type ConfigEstablecimiento struct {
@@ -228,3 +281,140 @@ type ConfigEstablecimiento struct {
 	Atribute               string
 	Value                  string
 }
+
+type Feriado struct {
+	ID                    int
+	Fecha                 time.Time
+	Descripcion           string
+	IDConfEstablecimiento *int
+	Origen                string
+}
+
+type FeriadoFiltro struct {
+	IDConfEstablecimiento int
+	FechaDesde            time.Time
+	FechaHasta            time.Time
+}
+
+type FeriadoImport struct {
+	IDConfEstablecimiento int
+	Formato               string
+	Contenido             string
+}
+
+type AgendaBloqueo struct {
+	ID                    int
+	IDProfesional         int
+	IDConfPersonal        int
+	IDConfEstablecimiento int
+	Inicio                time.Time
+	Fin                   time.Time
+	Motivo                string
+	CreatedBy             string
+	UID                   string
+	Ocurrencia            time.Time
+}
+
+type BloqueoImport struct {
+	IDProfesional  int
+	IDConfPersonal int
+	Contenido      string
+	CreatedBy      string
+}
+
+type BloqueoFiltro struct {
+	IDProfesional         int
+	IDConfPersonal        int
+	IDConfEstablecimiento int
+	FechaDesde            time.Time
+	FechaHasta            time.Time
+}
+
+type ReservaSerie struct {
+	ID          int
+	Reserva     Reserva
+	FechaInicio time.Time
+	RRule       string
+	Intervalo   int
+	Cantidad    int
+	Hasta       time.Time
+	CreatedBy   string
+}
+
+type SerieCancel struct {
+	IDSerie      int
+	Fecha        time.Time
+	Resto        bool
+	CanceladoPor string
+	Motivo       string
+}
+
+type ListaEspera struct {
+	ID                     int
+	IDPersona              int
+	IDProfesional          int
+	IDConfPersonal         int
+	IDConfEstablecimiento  int
+	IDSubTipoUnidadReserva int
+	FechaDesde             time.Time
+	FechaHasta             time.Time
+	HoraDesde              string
+	HoraHasta              string
+	Estado                 string
+	CreatedAt              time.Time
+}
+
+type ListaEsperaFiltro struct {
+	IDPersona              int
+	IDProfesional          int
+	IDConfPersonal         int
+	IDConfEstablecimiento  int
+	IDSubTipoUnidadReserva int
+	Estado                 string
+}
+
+type OfertaRespuesta struct {
+	IDOferta  int
+	IDPersona int
+}
+
+type RetencionSlot struct {
+	IDAgenda              int
+	IDProfesional         int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	HoraInicio            string
+	Minutos               int
+	IDSlot                int
+	Token                 string
+	RetenidoHasta         time.Time
+}
+
+type ReservaReprogramacion struct {
+	IDReserva             int
+	IDAgenda              int
+	IDProfesional         int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	HoraInicio            string
+	TokenRetencion        string
+	Actor                 string
+	Motivo                string
+}
+
+type CalendarioFiltro struct {
+	IDPersona  int
+	Rol        string
+	FechaDesde time.Time
+	FechaHasta time.Time
+}
+
+type FeedCalendario struct {
+	ID        int
+	Token     string
+	IDPersona int
+	Rol       string
+	URL       string
+	CreatedBy string
+	CreatedAt time.Time
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type DefaultResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -14,3 +16,53 @@ type ExternalAPIResponse struct {
 type ResponseDefault struct {
 	Message string `json:"message"`
 }
+
+type AgendaResumen struct {
+	DiasCreados   int `json:"dias_creados"`
+	DiasOmitidos  int `json:"dias_omitidos"`
+	SlotsCreados  int `json:"slots_creados"`
+	SlotsOmitidos int `json:"slots_omitidos"`
+	DiasFeriado   int `json:"dias_feriado"`
+}
+
+type ReglasReserva struct {
+	IDSubTipoUnidadReserva    int `json:"id_sub_tipo_unidad_reserva"`
+	IDConfPersonal            int `json:"id_conf_personal,omitempty"`
+	AnticipacionMinimaMinutos int `json:"anticipacion_minima_minutos"`
+	HorizonteDias             int `json:"horizonte_dias"`
+	MaxReservasDia            int `json:"max_reservas_dia"`
+	MaxReservasSemana         int `json:"max_reservas_semana"`
+	BufferMinutos             int `json:"buffer_minutos"`
+}
+
+type FeriadoImportResumen struct {
+	Creados  int `json:"creados"`
+	Omitidos int `json:"omitidos"`
+}
+
+type SlotDisponible struct {
+	IDSlot                int       `json:"id_slot"`
+	IDAgenda              int       `json:"id_agenda"`
+	IDConfPersonal        int       `json:"id_conf_personal,omitempty"`
+	IDProfesional         int       `json:"id_profesional,omitempty"`
+	IDConfEstablecimiento int       `json:"id_conf_establecimiento,omitempty"`
+	HoraInicio            string    `json:"hora_inicio"`
+	HoraFin               string    `json:"hora_fin"`
+	ZonaHoraria           string    `json:"zona_horaria"`
+	Inicio                time.Time `json:"inicio"`
+	Fin                   time.Time `json:"fin"`
+	Capacidad             int       `json:"capacidad"`
+	Cupos                 int       `json:"cupos"`
+}
+
+type DisponibilidadDia struct {
+	Fecha string           `json:"fecha"`
+	Slots []SlotDisponible `json:"slots"`
+}
+
+type ResultadoBusqueda struct {
+	Dias          []DisponibilidadDia `json:"dias"`
+	Pagina        int                 `json:"pagina"`
+	TamanioPagina int                 `json:"tamanio_pagina"`
+	TotalSlots    int                 `json:"total_slots"`
+}
@@ -17,6 +17,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// IDPersonaKey es la clave del contexto gin con la persona dueña del token validado
+const IDPersonaKey = "id_persona"
+
 type SecurityResponse struct {
 	IdPersona   int    `json:"id_persona"`
 	TokenStatus string `json:"token_status"`
@@ -119,6 +122,7 @@ func SecurityMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		c.Set(IDPersonaKey, securityResp.IdPersona)
 		c.Next()
 	}
 }
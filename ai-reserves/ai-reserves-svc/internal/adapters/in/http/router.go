@@ -89,18 +89,53 @@ func NewRouter(
 		ai_res.Group("/upd-atribute-unidad-reserva").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ModifUnidadReservaParcial)
 		ai_res.Group("/upd-atribute-tipo-unidad-reserva").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ModifTipoUnidadReservaParcial)
 		ai_res.Group("/upd-atribute-sub-tipo-unidad-reserva").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ModifSubTipoUnidadReservaParcial)
+		ai_res.Group("/upsert-booking-rules").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.UpsertReglasReserva)
+		ai_res.Group("/get-booking-rules").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetReglasReserva)
+		ai_res.Group("/upd-reminders").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.UpdRecordatorios)
 
 		//
 		ai_res.Group("/create-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CreateReserve)
+		ai_res.Group("/hold-slot").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.HoldSlot)
+		ai_res.Group("/release-slot-hold").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ReleaseSlotHold)
 		ai_res.Group("/cancel-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CancelReserve)
+		ai_res.Group("/reschedule-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.RescheduleReserve)
+		ai_res.Group("/confirm-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ConfirmReserve)
+		ai_res.Group("/complete-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CompleteReserve)
+		ai_res.Group("/no-show-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.NoShowReserve)
+		ai_res.Group("/get-reserve-history").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetHistorialReserva)
+		ai_res.Group("/create-reserve-series").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CreateSerie)
+		ai_res.Group("/cancel-reserve-series").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CancelSerie)
+
+		ai_res.Group("/join-waitlist").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.JoinListaEspera)
+		ai_res.Group("/leave-waitlist").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.LeaveListaEspera)
+		ai_res.Group("/get-waitlist").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetListaEspera)
+		ai_res.Group("/accept-slot-offer").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.AcceptOferta)
+		ai_res.Group("/decline-slot-offer").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.DeclineOferta)
 
 		ai_res.Group("/search-reserve").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.SearchReserve)
 		ai_res.Group("/init-agenda").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.InitAgenda)
+
+		ai_res.Group("/create-holiday").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CreateFeriado)
+		ai_res.Group("/upd-holiday").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.UpdFeriado)
+		ai_res.Group("/delete-holiday").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.DeleteFeriado)
+		ai_res.Group("/get-holidays").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetFeriados)
+		ai_res.Group("/import-holidays").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ImportFeriados)
+
+		ai_res.Group("/create-agenda-block").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CreateBloqueo)
+		ai_res.Group("/get-agenda-blocks").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetBloqueos)
+		ai_res.Group("/delete-agenda-block").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.DeleteBloqueo)
+		ai_res.Group("/import-agenda-blocks").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ImportBloqueosICal)
 		//
 		ai_res.Group("/get-info-person").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetInfoPersona)
 		ai_res.Group("/get-reserves-person").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetReservasPersona)
 		ai_res.Group("/get-reserves-unidad-reserva").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetReservasUnidadReserva)
 
+		ai_res.Group("/export-reserves-ics").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.ExportReservasICS)
+		ai_res.Group("/create-calendar-feed").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.CreateFeedCalendario)
+		ai_res.Group("/revoke-calendar-feed").POST("", middlewares.SecurityMiddleware(), middlewares.NewRateLimiterMiddleware(), AiReservesHandler.RevokeFeedCalendario)
+		// Los clientes de calendario no envían credenciales: el feed se autentica con el token de la URL
+		ai_res.GET("/calendar-feed/:token", middlewares.NewRateLimiterMiddleware(), AiReservesHandler.GetFeedCalendario)
+
 	}
 
 	// 404
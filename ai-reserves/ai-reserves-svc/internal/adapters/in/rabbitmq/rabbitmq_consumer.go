@@ -3,6 +3,7 @@ package in
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/adapters/rabbitmq"
@@ -22,19 +23,37 @@ func NewEventConsumer(service *application.AiReservesService, rabbit *rabbitmq.R
 	}
 }
 
-// Start escucha la cola y enruta los eventos según el RoutingKey
+// Start escucha la cola y enruta los eventos según el RoutingKey. Un handler que devuelve error
+// hace que el adapter reintente el mensaje; cada evento se registra en la misma transacción que lo
+// aplica, así los ya procesados se confirman sin reaplicarse.
 func (c *EventConsumer) Start(ctx context.Context, queue string) {
-	handler := func(evt domain.Event) {
+	handler := func(evt domain.Event) error {
 		fmt.Printf("📩 Received event: %s | RoutingKey: %s\n", evt.ID, evt.RoutingKey)
 
+		var err error
 		switch evt.RoutingKey {
-		case "user.created":
-			c.handlePersonCreated(ctx, evt)
-		//case "user.deleted":
-		//	c.handleUserDeleted(ctx, evt)
+		case domain.EventUserCreated:
+			err = c.handlePersonCreated(ctx, evt)
+		case domain.EventUserUpdated:
+			err = c.handlePersonUpdated(ctx, evt)
+		case domain.EventUserRevoked:
+			err = c.handlePersonRevoked(ctx, evt)
+		case domain.EventUserDeleted:
+			err = c.handlePersonDeleted(ctx, evt)
 		default:
 			fmt.Printf("⚠️ Unknown routing key: %s (ignored)\n", evt.RoutingKey)
+			return nil
 		}
+
+		// Redelivery de un evento ya aplicado
+		if errors.Is(err, domain.ErrDuplicateEvent) {
+			fmt.Printf("♻️ Event %s already processed (ignored)\n", evt.ID)
+			return nil
+		}
+		if err != nil {
+			fmt.Printf("❌ Error handling %s %s: %v\n", evt.RoutingKey, evt.ID, err)
+		}
+		return err
 	}
 	fmt.Println("STARTING CONSUMER")
 	if err := c.rabbit.Consume(ctx, queue, handler); err != nil {
@@ -43,22 +62,74 @@ func (c *EventConsumer) Start(ctx context.Context, queue string) {
 }
 
 // 🧩 Handler para user.created
-func (c *EventConsumer) handlePersonCreated(ctx context.Context, evt domain.Event) {
+func (c *EventConsumer) handlePersonCreated(ctx context.Context, evt domain.Event) error {
 	var payload domain.PersonCreatedPayload
-	data, _ := json.Marshal(evt.Payload)
-	if err := json.Unmarshal(data, &payload); err != nil {
-		fmt.Printf("⚠️ Invalid payload for user.created: %v\n", err)
-		return
+	if err := decodePayload(evt, &payload); err != nil {
+		return fmt.Errorf("%w: user.created: %v", domain.ErrEventoInvalido, err)
 	}
-	fmt.Println("calling from handleUserCreated")
-	fmt.Printf("DEBUG Payload type: %T\n", evt.Payload)
-	fmt.Printf("DEBUG Payload value: %+v\n", evt.Payload)
-
-	fmt.Printf("DEBUG payload value: %+v\n", payload)
-	if err := c.service.CreatePersonaAPI(ctx, payload); err != nil {
-		fmt.Printf("❌ Error creating user: %v\n", err)
-		return
+
+	if err := c.service.CreatePersonaAPI(ctx, evt, payload); err != nil {
+		return err
 	}
 
-	fmt.Printf("✅ User created successfully: %s\n", payload.ID)
+	fmt.Printf("✅ User created successfully: %d\n", payload.ID)
+	return nil
+}
+
+// 🧩 Handler para user.updated
+func (c *EventConsumer) handlePersonUpdated(ctx context.Context, evt domain.Event) error {
+	var payload domain.PersonUpdatedPayload
+	if err := decodePayload(evt, &payload); err != nil {
+		return fmt.Errorf("%w: user.updated: %v", domain.ErrEventoInvalido, err)
+	}
+
+	if err := c.service.UpdContactoPersonaAPI(ctx, evt, payload); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ User updated successfully: %d\n", payload.ID)
+	return nil
+}
+
+// 🧩 Handler para user.revoked
+func (c *EventConsumer) handlePersonRevoked(ctx context.Context, evt domain.Event) error {
+	var payload domain.PersonBajaPayload
+	if err := decodePayload(evt, &payload); err != nil {
+		return fmt.Errorf("%w: user.revoked: %v", domain.ErrEventoInvalido, err)
+	}
+
+	resultado, err := c.service.RevokePersonaAPI(ctx, evt, payload)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ User revoked: %d (%d feeds revocados, %d listas de espera dejadas)\n",
+		payload.ID, resultado.FeedsRevocados, resultado.ListasEsperaDejadas)
+	return nil
+}
+
+// 🧩 Handler para user.deleted
+func (c *EventConsumer) handlePersonDeleted(ctx context.Context, evt domain.Event) error {
+	var payload domain.PersonBajaPayload
+	if err := decodePayload(evt, &payload); err != nil {
+		return fmt.Errorf("%w: user.deleted: %v", domain.ErrEventoInvalido, err)
+	}
+
+	resultado, err := c.service.DeletePersonaAPI(ctx, evt, payload)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ User deleted: %d (anonimizada=%t, %d reservas canceladas, %d feeds revocados, %d listas de espera dejadas)\n",
+		payload.ID, resultado.Anonimizada, resultado.ReservasCanceladas, resultado.FeedsRevocados, resultado.ListasEsperaDejadas)
+	return nil
+}
+
+// decodePayload convierte el payload genérico del evento al struct del handler
+func decodePayload(evt domain.Event, v interface{}) error {
+	data, err := json.Marshal(evt.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
 }
@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/logger"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+	"github.com/lib/pq"
 )
 
 type AiReservesRepository struct {
@@ -65,12 +68,12 @@ func (hr *AiReservesRepository) GetDatabasesPing(ctx context.Context) ([]domain.
 	return databases, nil
 }
 
-func (hr *AiReservesRepository) CreatePersona(ctx context.Context, req domain.PersonCreatedPayload) error {
+func (hr *AiReservesRepository) CreatePersona(ctx context.Context, tx *sql.Tx, req domain.PersonCreatedPayload) error {
 
 	// Primero verificamos si la persona existe
 	var exists bool
 
-	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+	err := tx.QueryRowContext(ctx,
 		`SELECT EXISTS(SELECT 1 FROM ai_res.personas WHERE id = $1)`,
 		req.ID,
 	).Scan(&exists)
@@ -80,7 +83,7 @@ func (hr *AiReservesRepository) CreatePersona(ctx context.Context, req domain.Pe
 
 	if !exists {
 		// INSERT si la persona NO existe
-		_, err = hr.dbPost.GetDB().ExecContext(ctx,
+		_, err = tx.ExecContext(ctx,
 			`INSERT INTO ai_res.personas(id, email, telefono,created_at,created_by,updated_at)
 			 VALUES ($1, $2, $3,CURRENT_TIMESTAMP,'auth_security',null)`,
 			req.ID,
@@ -96,7 +99,7 @@ func (hr *AiReservesRepository) CreatePersona(ctx context.Context, req domain.Pe
 	}
 
 	// UPDATE si ya existe
-	_, err = hr.dbPost.GetDB().ExecContext(ctx,
+	_, err = tx.ExecContext(ctx,
 		`UPDATE ai_res.personas
 		    SET email = $2,
 		        telefono = $3,
@@ -115,6 +118,141 @@ func (hr *AiReservesRepository) CreatePersona(ctx context.Context, req domain.Pe
 	return nil
 }
 
+// UpdContactoPersona aplica user.updated: email y teléfono vacíos no pisan los actuales.
+// Si la persona todavía no llegó (eventos fuera de orden) la crea.
+func (hr *AiReservesRepository) UpdContactoPersona(ctx context.Context, tx *sql.Tx, req domain.PersonUpdatedPayload) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO ai_res.personas (id, email, telefono, created_at, created_by)
+		 VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), CURRENT_TIMESTAMP, 'auth_security')
+		 ON CONFLICT (id) DO UPDATE
+		    SET email = COALESCE(EXCLUDED.email, personas.email),
+		        telefono = COALESCE(EXCLUDED.telefono, personas.telefono),
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'auth_security'`,
+		req.ID,
+		req.Email,
+		req.TePersona,
+	)
+	if err != nil {
+		return fmt.Errorf("upd contacto persona %d: %w", req.ID, err)
+	}
+
+	return nil
+}
+
+// BajaPersona marca la persona como revocada (y eliminada si corresponde), bloqueándola para el resto
+// de la baja. Devuelve false si ai-reserves no la conoce. Repetir la baja conserva las fechas originales.
+func (hr *AiReservesRepository) BajaPersona(ctx context.Context, tx *sql.Tx, idPersona int, eliminada bool) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.personas
+		    SET revocada_at = COALESCE(revocada_at, CURRENT_TIMESTAMP),
+		        eliminada_at = CASE WHEN $2 THEN COALESCE(eliminada_at, CURRENT_TIMESTAMP) ELSE eliminada_at END,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'auth_security'
+		  WHERE id = $1`,
+		idPersona,
+		eliminada,
+	)
+	if err != nil {
+		return false, fmt.Errorf("baja persona %d: %w", idPersona, err)
+	}
+
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+// PersonaDadaDeBaja indica si la persona fue revocada o eliminada en auth-security
+func (hr *AiReservesRepository) PersonaDadaDeBaja(ctx context.Context, tx *sql.Tx, idPersona int) (bool, error) {
+	var baja bool
+
+	err := tx.QueryRowContext(ctx,
+		`SELECT revocada_at IS NOT NULL
+		   FROM ai_res.personas
+		  WHERE id = $1`,
+		idPersona,
+	).Scan(&baja)
+
+	// Una persona desconocida la rechaza la FK al grabar la reserva
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking baja persona %d: %w", idPersona, err)
+	}
+
+	return baja, nil
+}
+
+// AnonimizarPersona borra los datos personales; el id queda para no romper reservas ni historial
+func (hr *AiReservesRepository) AnonimizarPersona(ctx context.Context, tx *sql.Tx, idPersona int) error {
+
+	// 1️⃣ Datos de la persona
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.personas
+		    SET nombre = 'ANONIMIZADO',
+		        apellido_razon_social = 'ANONIMIZADO',
+		        tipo_doc_persona = NULL,
+		        nro_doc_persona = NULL,
+		        email = NULL,
+		        telefono = NULL,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'auth_security'
+		  WHERE id = $1`,
+		idPersona,
+	)
+	if err != nil {
+		return fmt.Errorf("anonimizar persona %d: %w", idPersona, err)
+	}
+
+	// 2️⃣ Texto libre de sus reservas, que puede tener datos personales
+	_, err = tx.ExecContext(ctx,
+		`UPDATE ai_res.reservas
+		    SET observaciones = NULL
+		  WHERE id_paciente = $1
+		    AND observaciones IS NOT NULL`,
+		idPersona,
+	)
+	if err != nil {
+		return fmt.Errorf("anonimizar reservas persona %d: %w", idPersona, err)
+	}
+
+	return nil
+}
+
+// GetReservasFuturasPaciente devuelve los ids de las reservas vigentes del paciente que todavía no empezaron
+func (hr *AiReservesRepository) GetReservasFuturasPaciente(ctx context.Context, tx *sql.Tx, idPaciente int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id
+		   FROM ai_res.reservas
+		  WHERE id_paciente = $1
+		    AND estado IN ($2, $3)
+		    AND inicio_utc > CURRENT_TIMESTAMP
+		  ORDER BY inicio_utc`,
+		idPaciente,
+		domain.ReservaPendiente,
+		domain.ReservaConfirmada,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query reservas futuras paciente %d: %w", idPaciente, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan reserva futura: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas futuras: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (hr *AiReservesRepository) UpdAtributoPersona(ctx context.Context, req domain.PersonaParcial) error {
 
 	// 1) Validar que la persona exista
@@ -274,12 +412,12 @@ func (hr *AiReservesRepository) InsertFullConfigPersona(ctx context.Context, req
 		INSERT INTO ai_res.conf_personal
 			(id_persona, hora_inicio, hora_fin,
 			 lunes, martes, miercoles, jueves, viernes, sabado, domingo,
-			 genera_feriados, modo_agenda,
+			 genera_feriados, modo_agenda, zona_horaria,
 			 created_by)
 		VALUES
 			($1, $2, $3,
 			 $4, $5, $6, $7, $8, $9, $10,
-			 $11, $12,
+			 $11, $12, $13,
 			 'ai_reserves')
 		RETURNING id;
 	`
@@ -299,6 +437,7 @@ func (hr *AiReservesRepository) InsertFullConfigPersona(ctx context.Context, req
 		req.Domingo,
 		req.GeneraFeriados,
 		req.ModoAgenda,
+		req.ZonaHoraria,
 	).Scan(&newID)
 
 	if err != nil {
@@ -337,6 +476,7 @@ func (hr *AiReservesRepository) UpsertConfigPersona(ctx context.Context, req dom
 		"sabado":          "boolean",
 		"domingo":         "boolean",
 		"genera_feriados": "boolean",
+		"zona_horaria":    "timezone",
 	}
 
 	// Validar columna
@@ -370,6 +510,12 @@ func (hr *AiReservesRepository) UpsertConfigPersona(ctx context.Context, req dom
 		}
 		castValue = t
 
+	case "timezone":
+		if _, errZona := zona.Cargar(req.Value); errZona != nil || req.Value == "" {
+			return fmt.Errorf("invalid time zone '%s', expected an IANA name like %s", req.Value, zona.Default)
+		}
+		castValue = req.Value
+
 	default:
 		return fmt.Errorf("unsupported type '%s' for column '%s'", colType, req.Atribute)
 	}
@@ -977,9 +1123,11 @@ func (hr *AiReservesRepository) UpdAtributeSubTipoUnidadReserva(ctx context.Cont
 	db := hr.dbPost.GetDB()
 
 	var validSubTipoFields = map[string]bool{
-		"nombre":                   true,
-		"descripcion":              true,
-		"duracion_reserva_minutos": true,
+		"nombre":                         true,
+		"descripcion":                    true,
+		"duracion_reserva_minutos":       true,
+		"anticipacion_cancelacion_horas": true,
+		"capacidad":                      true,
 	}
 
 	// 1️⃣ Validar atributo permitido
@@ -1067,473 +1215,1630 @@ func (hr *AiReservesRepository) UpdAtributeSubTipoUnidadReserva(ctx context.Cont
 	return nil
 }
 
-func (hr *AiReservesRepository) CreateReserve(ctx context.Context, req domain.Reserva) error {
-	return nil
-}
+func (hr *AiReservesRepository) CreateReserve(ctx context.Context, tx *sql.Tx, req domain.Reserva) (int, error) {
 
-func (hr *AiReservesRepository) CancelReserve(ctx context.Context, idReserva int) error {
-	return nil
-}
+	// 1️⃣ Bloquear el slot pedido (FOR UPDATE) para que dos reservas concurrentes no lo tomen
+	slot, err := hr.lockSlot(ctx, tx, req.IDAgenda, req.HoraInicio)
+	if err != nil {
+		return 0, err
+	}
+	idSlot, horaFin, fechaAgenda := slot.id, slot.horaFin, slot.fecha
 
-func (hr *AiReservesRepository) SearchReserve(ctx context.Context, req domain.SearchReserve) error {
-	return nil
+	if err := slot.tomable(req); err != nil {
+		return 0, err
+	}
+
+	if !req.Fecha.IsZero() && req.Fecha.Format("2006-01-02") != fechaAgenda.Format("2006-01-02") {
+		return 0, fmt.Errorf("fecha %s no corresponde a la agenda %d (%s)",
+			req.Fecha.Format("2006-01-02"), req.IDAgenda, fechaAgenda.Format("2006-01-02"))
+	}
+
+	// 2️⃣ Insertar la reserva (la fecha y la hora de fin salen del slot)
+	estado := req.Estado
+	if estado == "" {
+		estado = domain.ReservaPendiente
+	}
+
+	var newID int
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO ai_res.reservas
+			(id_agenda, fecha, hora_inicio, hora_fin, inicio_utc, fin_utc,
+			 id_sub_tipo_unidad_reserva, id_paciente, estado, observaciones,
+			 id_serie, created_by)
+		 VALUES ($1, $2, $3::time, $4::time, $5, $6, $7, $8, $9, $10, $11, 'ai_reserves')
+		 RETURNING id`,
+		req.IDAgenda,
+		fechaAgenda,
+		req.HoraInicio,
+		horaFin,
+		slot.inicio,
+		slot.fin,
+		req.IDSubTipoUnidadReserva,
+		req.IDPaciente,
+		estado,
+		req.Observaciones,
+		nullableInt(req.IDSerie),
+	).Scan(&newID)
+
+	if err != nil {
+		return 0, fmt.Errorf("insert reserva: %w", err)
+	}
+
+	// 3️⃣ Ocupar el slot con la reserva recién creada
+	if err := hr.claimSlot(ctx, tx, slot, newID, req.TokenRetencion); err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("📅 Reserva creada ID=%d (agenda=%d slot=%d)\n", newID, req.IDAgenda, idSlot)
+	return newID, nil
 }
 
-func (hr *AiReservesRepository) InitAgenda(ctx context.Context, req domain.Agenda) error {
-	return nil
+// slotBloqueado es el slot leído con FOR UPDATE antes de reservarlo o retenerlo
+type slotBloqueado struct {
+	id               int
+	estado           string
+	horaFin          string
+	fecha            time.Time
+	inicio           sql.NullTime
+	fin              sql.NullTime
+	token            string
+	retencionVencida bool // retención de checkout vencida que el barrido todavía no liberó
+	capacidad        int
+	ocupados         int
 }
 
-func (hr *AiReservesRepository) GetInfoPersona(ctx context.Context, idPersona int) (domain.Persona, error) {
-	var p domain.Persona
+// libres son los lugares del slot que no ocupa ninguna reserva
+func (s slotBloqueado) libres() int {
+	return s.capacidad - s.ocupados
+}
 
-	query := `
-		SELECT id, nombre, apellido_razon_social, persona_juridica,
-		       tipo_doc_persona, nro_doc_persona, email, telefono
-		FROM ai_res.personas
-		WHERE id = $1
-	`
+// compartido indica que una reserva sin token puede entrar en un slot retenido por otro checkout
+// o por una oferta de lista de espera: la retención guarda su lugar y la reserva toma otro
+func (s slotBloqueado) compartido(token string) bool {
+	return token == "" && s.estado == domain.SlotRetenido && !s.retencionVencida && s.libres() > 1
+}
 
-	row := hr.dbPost.GetDB().QueryRowContext(ctx, query, idPersona)
+// disponible indica si el slot se puede tomar: libre o con una retención de checkout ya vencida
+func (s slotBloqueado) disponible(idAgenda int, hora string) error {
+	switch {
+	case s.estado == domain.SlotLibre, s.estado == domain.SlotRetenido && s.retencionVencida:
+		return nil
+	case s.compartido(""):
+		return nil
+	case s.estado == domain.SlotRetenido:
+		return fmt.Errorf("%w: agenda %d hora %s", domain.ErrSlotRetenido, idAgenda, hora)
+	default:
+		return fmt.Errorf("%w: agenda %d hora %s (%s)", domain.ErrSlotAlreadyBooked, idAgenda, hora, s.estado)
+	}
+}
 
-	err := row.Scan(
-		&p.ID,
-		&p.Nombre,
-		&p.ApellidoRazonSocial,
-		&p.PersonaJuridia,
-		&p.TipoDocPersona,
-		&p.NroDocPersona,
-		&p.Email,
-		&p.TelPersona,
-	)
+// tomable valida que la reserva pueda ocupar el slot: con token tiene que confirmar la retención
+// de su checkout; sin token el slot tiene que estar disponible
+func (s slotBloqueado) tomable(req domain.Reserva) error {
+	if req.TokenRetencion == "" {
+		return s.disponible(req.IDAgenda, req.HoraInicio)
+	}
+	if s.estado != domain.SlotRetenido || s.token != req.TokenRetencion || s.retencionVencida {
+		return fmt.Errorf("%w: agenda %d hora %s", domain.ErrRetencionInvalida, req.IDAgenda, req.HoraInicio)
+	}
+	return nil
+}
 
+func (hr *AiReservesRepository) lockSlot(ctx context.Context, tx *sql.Tx, idAgenda int, hora string) (slotBloqueado, error) {
+	var s slotBloqueado
+
+	err := tx.QueryRowContext(ctx,
+		`SELECT s.id,
+		        COALESCE(s.estado, 'LIBRE'),
+		        to_char(s.hora_fin, 'HH24:MI'),
+		        a.fecha,
+		        s.inicio_utc,
+		        s.fin_utc,
+		        COALESCE(s.token_retencion, ''),
+		        (s.estado = $3 AND s.token_retencion IS NOT NULL AND s.retenido_hasta < CURRENT_TIMESTAMP),
+		        s.capacidad,
+		        s.ocupados
+		   FROM ai_res.agenda_slots s
+		   JOIN ai_res.agendas a ON a.id = s.id_agenda
+		  WHERE s.id_agenda = $1
+		    AND s.hora_inicio = $2::time
+		    AND COALESCE(a.activa, TRUE)
+		    FOR UPDATE OF s`,
+		idAgenda,
+		hora,
+		domain.SlotRetenido,
+	).Scan(&s.id, &s.estado, &s.horaFin, &s.fecha, &s.inicio, &s.fin, &s.token, &s.retencionVencida,
+		&s.capacidad, &s.ocupados)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return slotBloqueado{}, fmt.Errorf("%w: agenda %d hora %s", domain.ErrSlotNotFound, idAgenda, hora)
+	}
 	if err != nil {
-		return domain.Persona{}, err
+		return slotBloqueado{}, fmt.Errorf("locking agenda_slot: %w", err)
 	}
 
-	return p, nil
+	return s, nil
 }
 
-func (hr *AiReservesRepository) GetReservasPersona(ctx context.Context, req domain.GetReservaPersona) ([]domain.Reserva, error) {
-	db := hr.dbPost.GetDB()
-
-	// 1️⃣ Validar que el sub_tipo exista (buenas prácticas)
-	var exists bool
-	err := db.QueryRowContext(ctx,
-		`SELECT EXISTS(
-			SELECT 1 
-			  FROM ai_res.personas
-			 WHERE id = $1
-		)`,
-		req.IDPersona,
-	).Scan(&exists)
-
+// claimSlot ocupa un lugar del slot (ya bloqueado por la transacción) con la reserva; pasa a OCUPADO
+// cuando se llena. Si la reserva entra junto a una retención ajena, la retención se conserva con su lugar.
+// El estado con el que se leyó (LIBRE, o RETENIDO si se confirma una retención) y el control de
+// capacidad en el WHERE impiden la sobreventa aunque algo se haya colado entre la lectura y el UPDATE.
+func (hr *AiReservesRepository) claimSlot(ctx context.Context, tx *sql.Tx, slot slotBloqueado, idReserva int, token string) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET ocupados = ocupados + 1,
+		        estado = CASE WHEN $5 THEN estado
+		                      WHEN ocupados + 1 >= capacidad THEN $2
+		                      ELSE $6 END,
+		        id_reserva = CASE WHEN capacidad = 1 THEN $3::int END,
+		        token_retencion = CASE WHEN $5 THEN token_retencion END,
+		        retenido_hasta = CASE WHEN $5 THEN retenido_hasta END,
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		    AND COALESCE(estado, 'LIBRE') = $4
+		    AND ocupados + CASE WHEN $5 THEN 2 ELSE 1 END <= capacidad`,
+		slot.id,
+		domain.SlotOcupado,
+		idReserva,
+		slot.estado,
+		slot.compartido(token),
+		domain.SlotLibre,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("validating person: %w", err)
+		return fmt.Errorf("claiming agenda_slot %d: %w", slot.id, err)
 	}
 
-	if !exists {
-		return nil, fmt.Errorf("person doesn't exists")
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("%w: slot %d sin lugares", domain.ErrSlotAlreadyBooked, slot.id)
 	}
 
-	// 2️⃣ Query principal
-	rows, err := db.QueryContext(ctx,
-		`SELECT 
-			id,
-			id_agenda,
-			fecha,
-			hora_inicio,
-			hora_fin,
-			id_paciente,
-			estado,
-			observaciones,
-			id_sub_tipo_unidad_reserva
-		 FROM ai_res.reservas
-		 WHERE id_paciente = $1
-		   AND estado IN ('PENDIENTE', 'CONFIRMADA')
-		   AND (fecha > CURRENT_DATE
-		        OR (fecha = CURRENT_DATE AND hora_fin > CURRENT_TIME))
-		 ORDER BY fecha, hora_inicio`,
-		req.IDPersona,
-	)
+	return nil
+}
+
+func (hr *AiReservesRepository) CancelReserve(ctx context.Context, tx *sql.Tx, req domain.ReservaCancel) error {
 
+	// 1️⃣ Pasar la reserva a CANCELADA registrando quién y por qué
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.reservas
+		    SET estado = $2,
+		        cancelado_por = $3,
+		        motivo_cancelacion = $4,
+		        fecha_cancelacion = CURRENT_TIMESTAMP,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		req.IDReserva,
+		domain.ReservaCancelada,
+		nullableString(req.CanceladoPor),
+		nullableString(req.Motivo),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("query reservas: %w", err)
+		return fmt.Errorf("cancel reserva: %w", err)
 	}
-	defer rows.Close()
 
-	// 3️⃣ Mapear filas
-	var reservas []domain.Reserva
+	// 2️⃣ Liberar el slot que ocupaba
+	if err := hr.releaseSlots(ctx, tx, req.IDReserva); err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		var r domain.Reserva
+	fmt.Printf("🚫 Reserva cancelada ID=%d\n", req.IDReserva)
+	return nil
+}
 
-		err := rows.Scan(
-			&r.ID,
-			&r.IDAgenda,
-			&r.Fecha,
-			&r.HoraInicio,
-			&r.HoraFin,
-			&r.IDPaciente,
-			&r.Estado,
-			&r.Observaciones,
-			&r.IDSubTipoUnidadReserva,
-		)
+// releaseSlots devuelve el lugar que ocupaba la reserva en su slot: un slot completo vuelve a LIBRE
+// (o a BLOQUEADO si mientras tanto quedó dentro de un bloqueo de agenda); uno retenido sigue retenido.
+// El slot se ubica por agenda y hora porque en los slots compartidos id_reserva no identifica a todas.
+func (hr *AiReservesRepository) releaseSlots(ctx context.Context, tx *sql.Tx, idReserva int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots s
+		    SET ocupados = GREATEST(s.ocupados - 1, 0),
+		        estado = CASE WHEN s.estado <> $4 THEN s.estado
+		                      WHEN s.id_bloqueo IS NOT NULL THEN $3
+		                      ELSE $2 END,
+		        id_reserva = NULL,
+		        updated_at = CURRENT_TIMESTAMP
+		   FROM ai_res.reservas r
+		  WHERE r.id = $1
+		    AND s.id_agenda = r.id_agenda
+		    AND s.hora_inicio = r.hora_inicio
+		    AND s.ocupados > 0`,
+		idReserva,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+		domain.SlotOcupado,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing agenda_slots of reserva %d: %w", idReserva, err)
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("scan reservas: %w", err)
-		}
+	return hr.releaseRecursos(ctx, tx, idReserva)
+}
 
-		reservas = append(reservas, r)
+// releaseRecursos devuelve los lugares que la reserva ocupaba en las agendas de sus recursos
+// adicionales (los slots que se superponen con su horario) y borra el registro en agendas_reservas
+func (hr *AiReservesRepository) releaseRecursos(ctx context.Context, tx *sql.Tx, idReserva int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots s
+		    SET ocupados = GREATEST(s.ocupados - 1, 0),
+		        estado = CASE WHEN s.estado <> $4 THEN s.estado
+		                      WHEN s.id_bloqueo IS NOT NULL THEN $3
+		                      ELSE $2 END,
+		        id_reserva = CASE WHEN s.id_reserva = $1 THEN NULL ELSE s.id_reserva END,
+		        updated_at = CURRENT_TIMESTAMP
+		   FROM ai_res.agendas_reservas ar
+		   JOIN ai_res.reservas r ON r.id = ar.id_reserva
+		  WHERE ar.id_reserva = $1
+		    AND s.id_agenda = ar.id_agenda
+		    AND s.inicio_utc < r.fin_utc
+		    AND s.fin_utc > r.inicio_utc
+		    AND s.ocupados > 0`,
+		idReserva,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+		domain.SlotOcupado,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing recursos of reserva %d: %w", idReserva, err)
 	}
 
-	// 4️⃣ Manejar error de iteración
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating reservas: %w", err)
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM ai_res.agendas_reservas WHERE id_reserva = $1`,
+		idReserva,
+	)
+	if err != nil {
+		return fmt.Errorf("deleting agendas_reservas of reserva %d: %w", idReserva, err)
 	}
 
-	return reservas, nil
+	return nil
 }
 
-func (hr *AiReservesRepository) GetReservasUnidadReserva(ctx context.Context, req domain.GetReservaUnidadReserva) ([]domain.Reserva, error) {
+// GetReservaForUpdate lee la reserva bloqueándola hasta el fin de la transacción
+func (hr *AiReservesRepository) GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error) {
+	var r domain.Reserva
+	var zonaHoraria string
+	var inicio, fin sql.NullTime
+
+	err := tx.QueryRowContext(ctx,
+		`SELECT r.id,
+		        r.id_agenda,
+		        r.fecha,
+		        to_char(r.hora_inicio, 'HH24:MI'),
+		        to_char(r.hora_fin, 'HH24:MI'),
+		        r.id_paciente,
+		        COALESCE(r.estado, 'PENDIENTE'),
+		        r.observaciones,
+		        r.id_sub_tipo_unidad_reserva,
+		        a.zona_horaria,
+		        r.inicio_utc,
+		        r.fin_utc
+		   FROM ai_res.reservas r
+		   JOIN ai_res.agendas a ON a.id = r.id_agenda
+		  WHERE r.id = $1
+		    FOR UPDATE OF r`,
+		idReserva,
+	).Scan(
+		&r.ID,
+		&r.IDAgenda,
+		&r.Fecha,
+		&r.HoraInicio,
+		&r.HoraFin,
+		&r.IDPaciente,
+		&r.Estado,
+		&r.Observaciones,
+		&r.IDSubTipoUnidadReserva,
+		&zonaHoraria,
+		&inicio,
+		&fin,
+	)
 
-	db := hr.dbPost.GetDB()
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Reserva{}, fmt.Errorf("%w: id=%d", domain.ErrReservaNotFound, idReserva)
+	}
+	if err != nil {
+		return domain.Reserva{}, fmt.Errorf("get reserva for update: %w", err)
+	}
 
-	// 1️⃣ Validar que el sub_tipo exista (buenas prácticas)
-	var exists bool
-	err := db.QueryRowContext(ctx,
-		`SELECT EXISTS(
-			SELECT 1 
-			  FROM ai_res.sub_tipo_unidad_reserva
-			 WHERE id = $1
-		)`,
-		req.IDSubTipoUnidadReserva,
-	).Scan(&exists)
+	zonaReserva(&r, zonaHoraria, inicio, fin)
+	return r, nil
+}
+
+func (hr *AiReservesRepository) UpdEstadoReserva(ctx context.Context, tx *sql.Tx, req domain.ReservaTransicion) error {
+	actor := req.Actor
+	if actor == "" {
+		actor = "ai_reserves"
+	}
 
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.reservas
+		    SET estado = $2,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = $3
+		  WHERE id = $1`,
+		req.IDReserva,
+		req.Estado,
+		actor,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("validating sub_tipo_unidad_reserva: %w", err)
+		return fmt.Errorf("update estado reserva: %w", err)
 	}
 
-	if !exists {
-		return nil, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", req.IDSubTipoUnidadReserva)
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrReservaNotFound, req.IDReserva)
 	}
 
-	// 2️⃣ Query principal
-	rows, err := db.QueryContext(ctx,
-		`SELECT 
-			id,
-			id_agenda,
-			fecha,
-			hora_inicio,
-			hora_fin,
-			id_paciente,
-			estado,
-			observaciones,
-			id_sub_tipo_unidad_reserva
-		 FROM ai_res.reservas
-		 WHERE id_sub_tipo_unidad_reserva = $1
-		   AND estado IN ('PENDIENTE', 'CONFIRMADA')
-		   AND (fecha > CURRENT_DATE
-		        OR (fecha = CURRENT_DATE AND hora_fin > CURRENT_TIME))
-		 ORDER BY fecha, hora_inicio`,
-		req.IDSubTipoUnidadReserva,
+	fmt.Printf("🔁 Reserva ID=%d → %s\n", req.IDReserva, req.Estado)
+	return nil
+}
+
+func (hr *AiReservesRepository) InsertHistorialReserva(ctx context.Context, tx *sql.Tx, h domain.ReservaHistorial) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO ai_res.reservas_historial
+			(id_reserva, estado_anterior, estado_nuevo, actor, motivo)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		h.IDReserva,
+		h.EstadoAnterior,
+		h.EstadoNuevo,
+		h.Actor,
+		h.Motivo,
 	)
+	if err != nil {
+		return fmt.Errorf("insert reservas_historial: %w", err)
+	}
+
+	return nil
+}
 
+func (hr *AiReservesRepository) GetHistorialReserva(ctx context.Context, idReserva int) ([]domain.ReservaHistorial, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT id, id_reserva, estado_anterior, estado_nuevo, actor, motivo, created_at
+		   FROM ai_res.reservas_historial
+		  WHERE id_reserva = $1
+		  ORDER BY created_at, id`,
+		idReserva,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("query reservas: %w", err)
+		return nil, fmt.Errorf("querying reservas_historial: %w", err)
 	}
 	defer rows.Close()
 
-	// 3️⃣ Mapear filas
-	var reservas []domain.Reserva
-
+	historial := []domain.ReservaHistorial{}
 	for rows.Next() {
-		var r domain.Reserva
-
-		err := rows.Scan(
-			&r.ID,
-			&r.IDAgenda,
-			&r.Fecha,
-			&r.HoraInicio,
-			&r.HoraFin,
-			&r.IDPaciente,
-			&r.Estado,
-			&r.Observaciones,
-			&r.IDSubTipoUnidadReserva,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("scan reservas: %w", err)
+		var h domain.ReservaHistorial
+		if err := rows.Scan(&h.ID, &h.IDReserva, &h.EstadoAnterior, &h.EstadoNuevo, &h.Actor, &h.Motivo, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning reservas_historial: %w", err)
 		}
-
-		reservas = append(reservas, r)
+		historial = append(historial, h)
 	}
 
-	// 4️⃣ Manejar error de iteración
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating reservas: %w", err)
+		return nil, fmt.Errorf("iterating reservas_historial: %w", err)
 	}
 
-	return reservas, nil
+	return historial, nil
 }
 
-func (hr *AiReservesRepository) PushEventToQueue(ctx context.Context, tx *sql.Tx, event domain.Event) error {
-	query := `
-		INSERT INTO api_int.message_event (
-			id_event,
-			source_system,
-			destiny_system,
-			payload,
-			status,
-			fecha_recepcion,
-			actualizado_por
-		)
-		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, $6)
-		ON CONFLICT (id_event, source_system)
-		DO NOTHING;
-	`
+func (hr *AiReservesRepository) GetAnticipacionCancelacion(ctx context.Context, tx *sql.Tx, idSubTipo int) (int, error) {
+	var horas int
 
-	payloadJSON, err := json.Marshal(event.Payload)
+	err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(anticipacion_cancelacion_horas, 0)
+		   FROM ai_res.sub_tipo_unidad_reserva
+		  WHERE id = $1`,
+		idSubTipo,
+	).Scan(&horas)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", idSubTipo)
+	}
 	if err != nil {
-		return fmt.Errorf("error marshalling payload: %w", err)
+		return 0, fmt.Errorf("get anticipacion cancelacion: %w", err)
 	}
 
-	res, err := tx.ExecContext(ctx, query,
-		event.ID,
-		event.Origin,     // → source_system
-		event.RoutingKey, // → queue_name
-		payloadJSON,
-		"RECEIVED",
-		"SYSTEM",
+	return horas, nil
+}
+
+// GetReglasReserva devuelve las reglas del sub tipo con el override del profesional aplicado
+// (idConfPersonal 0: solo las del sub tipo, p. ej. para un espacio físico)
+func (hr *AiReservesRepository) GetReglasReserva(ctx context.Context, idSubTipo int, idConfPersonal int) (domain.ReglasReserva, error) {
+	reglas := domain.ReglasReserva{IDSubTipoUnidadReserva: idSubTipo, IDConfPersonal: idConfPersonal}
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT COALESCE(cps.anticipacion_minima_minutos, st.anticipacion_minima_minutos),
+		        COALESCE(cps.horizonte_dias, st.horizonte_dias),
+		        COALESCE(cps.max_reservas_dia, st.max_reservas_dia),
+		        COALESCE(cps.max_reservas_semana, st.max_reservas_semana),
+		        COALESCE(cps.buffer_minutos, st.buffer_minutos)
+		   FROM ai_res.sub_tipo_unidad_reserva st
+		   LEFT JOIN ai_res.conf_personal_sub_tipo_unidad_reserva cps
+		          ON cps.id_sub_tipo_unidad_reserva = st.id
+		         AND cps.id_conf_personal = $2
+		  WHERE st.id = $1`,
+		idSubTipo,
+		idConfPersonal,
+	).Scan(
+		&reglas.AnticipacionMinimaMinutos,
+		&reglas.HorizonteDias,
+		&reglas.MaxReservasDia,
+		&reglas.MaxReservasSemana,
+		&reglas.BufferMinutos,
 	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ReglasReserva{}, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", idSubTipo)
+	}
 	if err != nil {
-		return fmt.Errorf("error inserting event: %w", err)
+		return domain.ReglasReserva{}, fmt.Errorf("get reglas reserva: %w", err)
 	}
 
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return domain.ErrDuplicateEvent
+	return reglas, nil
+}
+
+// UpdReglasSubTipo actualiza las reglas del sub tipo; los campos nil quedan como están
+func (hr *AiReservesRepository) UpdReglasSubTipo(ctx context.Context, req domain.ReglasReservaConfig) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.sub_tipo_unidad_reserva
+		    SET anticipacion_minima_minutos = COALESCE($2::int, anticipacion_minima_minutos),
+		        horizonte_dias = COALESCE($3::int, horizonte_dias),
+		        max_reservas_dia = COALESCE($4::int, max_reservas_dia),
+		        max_reservas_semana = COALESCE($5::int, max_reservas_semana),
+		        buffer_minutos = COALESCE($6::int, buffer_minutos),
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		req.IDSubTipoUnidadReserva,
+		req.AnticipacionMinimaMinutos,
+		req.HorizonteDias,
+		req.MaxReservasDia,
+		req.MaxReservasSemana,
+		req.BufferMinutos,
+	)
+	if err != nil {
+		return fmt.Errorf("update reglas sub_tipo_unidad_reserva: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("sub_tipo_unidad_reserva %d no existe", req.IDSubTipoUnidadReserva)
 	}
 
+	fmt.Printf("📏 Reglas de reserva actualizadas para sub_tipo %d\n", req.IDSubTipoUnidadReserva)
 	return nil
 }
 
-func (hr *AiReservesRepository) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
-	tx, err := hr.dbPost.db.BeginTx(ctx, nil)
+// UpdReglasConfPersonal graba el override del profesional; los campos nil vuelven a la regla del sub tipo
+func (hr *AiReservesRepository) UpdReglasConfPersonal(ctx context.Context, idConfPersonal int, req domain.ReglasReservaConfig) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.conf_personal_sub_tipo_unidad_reserva
+		    SET anticipacion_minima_minutos = $3,
+		        horizonte_dias = $4,
+		        max_reservas_dia = $5,
+		        max_reservas_semana = $6,
+		        buffer_minutos = $7,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id_conf_personal = $1
+		    AND id_sub_tipo_unidad_reserva = $2`,
+		idConfPersonal,
+		req.IDSubTipoUnidadReserva,
+		req.AnticipacionMinimaMinutos,
+		req.HorizonteDias,
+		req.MaxReservasDia,
+		req.MaxReservasSemana,
+		req.BufferMinutos,
+	)
 	if err != nil {
-		return err
+		return fmt.Errorf("update reglas conf_personal_sub_tipo_unidad_reserva: %w", err)
 	}
 
-	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
-		return err
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("el profesional (conf_personal %d) no ofrece el sub_tipo_unidad_reserva %d",
+			idConfPersonal, req.IDSubTipoUnidadReserva)
 	}
 
-	return tx.Commit()
+	fmt.Printf("📏 Reglas de reserva actualizadas para conf_personal %d sub_tipo %d\n", idConfPersonal, req.IDSubTipoUnidadReserva)
+	return nil
 }
 
-func (hr *AiReservesRepository) InsertConfigPersonalSubTipo(ctx context.Context, config domain.ConfigPersonalSubTipo) error {
-	// The SQL insert statement
-	query := `
-		INSERT INTO ai_res.conf_personal_sub_tipo_unidad_reserva
-			(id_conf_personal, id_sub_tipo_unidad_reserva, duracion_reserva_minutos)
-		VALUES ($1, $2, $3)
-	`
+// LockPersona bloquea la persona hasta el fin de la transacción: serializa las reservas
+// concurrentes de un mismo paciente para contar sus topes
+func (hr *AiReservesRepository) LockPersona(ctx context.Context, tx *sql.Tx, idPersona int) error {
+	var id int
 
-	// Execute the query with the provided parameters
-	_, err := hr.dbPost.GetDB().ExecContext(ctx, query,
-		config.IDConfPersonal,
-		config.IDSubTipoUnidadReserva,
-		config.DuracionReservaMinutos,
-	)
+	err := tx.QueryRowContext(ctx,
+		`SELECT id
+		   FROM ai_res.personas
+		  WHERE id = $1
+		    FOR UPDATE`,
+		idPersona,
+	).Scan(&id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("persona %d no existe", idPersona)
+	}
 	if err != nil {
-		return fmt.Errorf("inserting config personal sub tipo: %w", err)
+		return fmt.Errorf("locking persona %d: %w", idPersona, err)
 	}
 
 	return nil
 }
 
-func (hr *AiReservesRepository) InsertOrUpdateConfEstablecimiento(ctx context.Context, req domain.ConfEstablecimiento) error {
+func (hr *AiReservesRepository) SearchReserve(ctx context.Context, req domain.SearchReserve) ([]domain.SlotDisponible, int, error) {
 
-	// 1) Validar existencia de persona
-	var personaExists bool
-	err := hr.dbPost.GetDB().QueryRowContext(ctx,
-		`SELECT EXISTS(SELECT 1 FROM ai_res.personas WHERE id = $1)`,
-		req.IDPersona,
-	).Scan(&personaExists)
-	if err != nil {
-		return fmt.Errorf("checking persona existence: %w", err)
-	}
-	if !personaExists {
-		return fmt.Errorf("persona id=%d does not exist", req.IDPersona)
+	// 1️⃣ Slots con lugar y futuros del rango con los filtros opcionales: LIBRE, o RETENIDO si es
+	//    compartido y además del lugar retenido queda otro. "cupos" son los lugares que quedan;
+	//    "nro" numera los slots de cada día para el filtro de primer disponible.
+	//    "rg" son las reglas de reserva del sub tipo buscado (o del único del espacio); si no se indica
+	//    sub tipo y el profesional ofrece varios, se toma la regla menos restrictiva de cada uno.
+	query := `
+		WITH libres AS (
+			SELECT s.id AS id_slot,
+			       s.id_agenda,
+			       COALESCE(a.id_conf_personal, 0) AS id_conf_personal,
+			       COALESCE(cp.id_persona, 0) AS id_profesional,
+			       COALESCE(a.id_conf_establecimiento, 0) AS id_conf_establecimiento,
+			       a.fecha,
+			       s.hora_inicio,
+			       s.hora_fin,
+			       a.zona_horaria,
+			       s.inicio_utc,
+			       s.fin_utc,
+			       s.capacidad,
+			       s.capacidad - s.ocupados - CASE WHEN s.estado = $14 THEN 1 ELSE 0 END AS cupos,
+			       ROW_NUMBER() OVER (PARTITION BY a.fecha ORDER BY s.hora_inicio, s.id) AS nro
+			  FROM ai_res.agenda_slots s
+			  JOIN ai_res.agendas a ON a.id = s.id_agenda
+			  LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+			  LEFT JOIN ai_res.conf_establecimiento ce ON ce.id = a.id_conf_establecimiento
+			  CROSS JOIN LATERAL (
+			        SELECT COALESCE(MIN(COALESCE(cps.anticipacion_minima_minutos, st.anticipacion_minima_minutos)), 0) AS anticipacion_minima_minutos,
+			               CASE WHEN bool_or(COALESCE(cps.horizonte_dias, st.horizonte_dias) = 0) IS NOT FALSE THEN 0
+			                    ELSE MAX(COALESCE(cps.horizonte_dias, st.horizonte_dias)) END AS horizonte_dias,
+			               CASE WHEN bool_or(COALESCE(cps.max_reservas_dia, st.max_reservas_dia) = 0) IS NOT FALSE THEN 0
+			                    ELSE MAX(COALESCE(cps.max_reservas_dia, st.max_reservas_dia)) END AS max_reservas_dia,
+			               CASE WHEN bool_or(COALESCE(cps.max_reservas_semana, st.max_reservas_semana) = 0) IS NOT FALSE THEN 0
+			                    ELSE MAX(COALESCE(cps.max_reservas_semana, st.max_reservas_semana)) END AS max_reservas_semana,
+			               COALESCE(MIN(COALESCE(cps.buffer_minutos, st.buffer_minutos)), 0) AS buffer_minutos,
+			               array_agg(st.id) AS sub_tipos
+			          FROM ai_res.sub_tipo_unidad_reserva st
+			          LEFT JOIN ai_res.conf_personal_sub_tipo_unidad_reserva cps
+			                 ON cps.id_sub_tipo_unidad_reserva = st.id
+			                AND cps.id_conf_personal = a.id_conf_personal
+			         WHERE st.id = COALESCE($6::int, ce.id_sub_tipo_unidad_reserva)
+			            OR ($6::int IS NULL AND cps.id IS NOT NULL)
+			  ) rg
+			 WHERE (COALESCE(s.estado, 'LIBRE') = $1
+			        OR (s.estado = $14 AND s.capacidad - s.ocupados > 1))
+			   AND COALESCE(a.activa, TRUE)
+			   AND a.fecha BETWEEN $2::date AND $3::date
+			   AND s.inicio_utc > CURRENT_TIMESTAMP
+			   AND ($4::int IS NULL OR cp.id_persona = $4)
+			   AND ($5::int IS NULL OR a.id_conf_establecimiento = $5)
+			   AND ($6::int IS NULL
+			        OR ce.id_sub_tipo_unidad_reserva = $6
+			        OR EXISTS (SELECT 1
+			                     FROM ai_res.conf_personal_sub_tipo_unidad_reserva cps
+			                    WHERE cps.id_conf_personal = a.id_conf_personal
+			                      AND cps.id_sub_tipo_unidad_reserva = $6))
+			   AND ($7::time IS NULL OR s.hora_inicio >= $7::time)
+			   AND ($8::time IS NULL OR s.hora_fin <= $8::time)
+			   AND (cardinality($9::int[]) = 0 OR EXTRACT(DOW FROM a.fecha)::int = ANY($9::int[]))
+			   -- Reglas de reserva: anticipación mínima, horizonte y margen con otros turnos
+			   AND s.inicio_utc >= CURRENT_TIMESTAMP + make_interval(mins => rg.anticipacion_minima_minutos)
+			   AND (rg.horizonte_dias = 0
+			        OR s.inicio_utc <= CURRENT_TIMESTAMP + make_interval(days => rg.horizonte_dias))
+			   AND (rg.buffer_minutos = 0 OR NOT EXISTS (
+			        SELECT 1
+			          FROM ai_res.reservas r
+			          JOIN ai_res.agendas ra ON ra.id = r.id_agenda
+			         WHERE (ra.id_conf_personal = a.id_conf_personal OR ra.id_conf_establecimiento = a.id_conf_establecimiento)
+			           AND COALESCE(r.estado, 'PENDIENTE') IN ('PENDIENTE', 'CONFIRMADA')
+			           AND NOT (r.id_agenda = s.id_agenda AND r.hora_inicio = s.hora_inicio)
+			           AND r.inicio_utc < s.fin_utc + make_interval(mins => rg.buffer_minutos)
+			           AND r.fin_utc > s.inicio_utc - make_interval(mins => rg.buffer_minutos)))
+			   -- Topes del paciente (si se indica): sus reservas vigentes del sub tipo en el día y la semana
+			   AND ($13::int IS NULL OR rg.max_reservas_dia = 0 OR (
+			        SELECT COUNT(*)
+			          FROM ai_res.reservas r
+			         WHERE r.id_paciente = $13
+			           AND COALESCE(r.estado, 'PENDIENTE') IN ('PENDIENTE', 'CONFIRMADA')
+			           AND r.id_sub_tipo_unidad_reserva = ANY(rg.sub_tipos)
+			           AND r.fecha = a.fecha) < rg.max_reservas_dia)
+			   AND ($13::int IS NULL OR rg.max_reservas_semana = 0 OR (
+			        SELECT COUNT(*)
+			          FROM ai_res.reservas r
+			         WHERE r.id_paciente = $13
+			           AND COALESCE(r.estado, 'PENDIENTE') IN ('PENDIENTE', 'CONFIRMADA')
+			           AND r.id_sub_tipo_unidad_reserva = ANY(rg.sub_tipos)
+			           AND date_trunc('week', r.fecha) = date_trunc('week', a.fecha)) < rg.max_reservas_semana)
+			   -- Recursos adicionales: cada uno tiene que tener el horario del slot cubierto por slots con lugar
+			   AND NOT EXISTS (
+			        SELECT 1
+			          FROM unnest($15::int[], $16::int[]) AS rec(id_profesional, id_conf_establecimiento)
+			         WHERE NOT COALESCE((
+			               SELECT bool_and(COALESCE(rs.estado, 'LIBRE') = $1
+			                               OR (rs.estado = $14 AND rs.capacidad - rs.ocupados > 1))
+			                      AND MIN(rs.inicio_utc) <= s.inicio_utc
+			                      AND MAX(rs.fin_utc) >= s.fin_utc
+			                      AND SUM(EXTRACT(EPOCH FROM LEAST(rs.fin_utc, s.fin_utc) - GREATEST(rs.inicio_utc, s.inicio_utc)))
+			                          >= EXTRACT(EPOCH FROM s.fin_utc - s.inicio_utc)
+			                 FROM ai_res.agenda_slots rs
+			                 JOIN ai_res.agendas ra ON ra.id = rs.id_agenda
+			                 LEFT JOIN ai_res.conf_personal rcp ON rcp.id = ra.id_conf_personal
+			                WHERE (rcp.id_persona = rec.id_profesional OR ra.id_conf_establecimiento = rec.id_conf_establecimiento)
+			                  AND COALESCE(ra.activa, TRUE)
+			                  AND rs.id_agenda <> s.id_agenda
+			                  AND rs.inicio_utc < s.fin_utc
+			                  AND rs.fin_utc > s.inicio_utc), FALSE))
+		)
+		SELECT id_slot, id_agenda, id_conf_personal, id_profesional, id_conf_establecimiento,
+		       fecha, to_char(hora_inicio, 'HH24:MI'), to_char(hora_fin, 'HH24:MI'),
+		       zona_horaria, inicio_utc, fin_utc, capacidad, cupos,
+		       COUNT(*) OVER () AS total
+		  FROM libres
+		 WHERE (NOT $10 OR nro = 1)
+		 ORDER BY fecha, hora_inicio, id_slot
+		 LIMIT $11 OFFSET $12`
+
+	diasSemana := req.DiasSemana
+	if diasSemana == nil {
+		diasSemana = []int{}
 	}
 
-	// 2) Validar existence of sub_tipo_unidad_reserva
-	var subTipoExists bool
-	err = hr.dbPost.GetDB().QueryRowContext(ctx,
-		`SELECT EXISTS(SELECT 1 FROM ai_res.sub_tipo_unidad_reserva WHERE id = $1)`,
-		req.IDSubTipoUnidadReserva,
-	).Scan(&subTipoExists)
-	if err != nil {
-		return fmt.Errorf("checking sub_tipo_unidad_reserva: %w", err)
-	}
-	if !subTipoExists {
-		return fmt.Errorf("sub_tipo_unidad_reserva id=%d does not exist", req.IDSubTipoUnidadReserva)
+	// Recursos adicionales como pares (profesional, establecimiento) para el unnest
+	var recursosProfesional, recursosEstablecimiento []int
+	for _, r := range req.Recursos() {
+		recursosProfesional = append(recursosProfesional, r.IDProfesional)
+		recursosEstablecimiento = append(recursosEstablecimiento, r.IDConfEstablecimiento)
 	}
 
-	// 3) Ver si ya existe el registro para (id_persona + id_sub_tipo)
-	var exists bool
-	err = hr.dbPost.GetDB().QueryRowContext(ctx,
-		`SELECT EXISTS(
-			SELECT 1 
-			  FROM ai_res.conf_establecimiento
-			 WHERE id_persona = $1
-			   AND id_sub_tipo_unidad_reserva = $2
-		)`,
-		req.IDPersona, req.IDSubTipoUnidadReserva,
-	).Scan(&exists)
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx, query,
+		domain.SlotLibre,
+		req.FechaDesde.Format("2006-01-02"),
+		req.FechaHasta.Format("2006-01-02"),
+		nullableInt(req.IDProfesional),
+		nullableInt(req.IDConfEstablecimiento),
+		nullableInt(req.IDSubTipoUnidadReserva),
+		nullableString(req.HoraDesde),
+		nullableString(req.HoraHasta),
+		pq.Array(diasSemana),
+		req.PrimeroDisponible,
+		req.TamanioPagina,
+		(req.Pagina-1)*req.TamanioPagina,
+		nullableInt(req.IDPaciente),
+		domain.SlotRetenido,
+		pq.Array(recursosProfesional),
+		pq.Array(recursosEstablecimiento),
+	)
 	if err != nil {
-		return fmt.Errorf("checking existing conf_establecimiento: %w", err)
+		return nil, 0, fmt.Errorf("query disponibilidad: %w", err)
 	}
+	defer rows.Close()
 
-	// 4) UPDATE COMPLETO si ya existe
-	if exists {
-		queryUpdate := `
-			UPDATE ai_res.conf_establecimiento
-			   SET nombre = $1,
-				   hora_inicio = $2,
-				   hora_fin = $3,
-				   lunes = $4,
-				   martes = $5,
-				   miercoles = $6,
-				   jueves = $7,
-				   viernes = $8,
-				   sabado = $9,
-				   domingo = $10,
-				   genera_feriados = $11,
-				   updated_at = CURRENT_TIMESTAMP,
-				   updated_by = 'auth_security'
-			 WHERE id_persona = $12
-			   AND id_sub_tipo_unidad_reserva = $13
-		`
+	// 2️⃣ Mapear filas
+	var slots []domain.SlotDisponible
+	total := 0
 
-		_, err = hr.dbPost.GetDB().ExecContext(ctx, queryUpdate,
-			req.Nombre,
-			req.HoraInicio,
-			req.HoraFin,
-			req.Lunes,
-			req.Martes,
-			req.Miercoles,
-			req.Jueves,
-			req.Viernes,
-			req.Sabado,
-			req.Domingo,
-			req.GeneraFeriados,
-			req.IDPersona,
-			req.IDSubTipoUnidadReserva,
+	for rows.Next() {
+		var sd domain.SlotDisponible
+		var inicio, fin sql.NullTime
+
+		err := rows.Scan(
+			&sd.IDSlot,
+			&sd.IDAgenda,
+			&sd.IDConfPersonal,
+			&sd.IDProfesional,
+			&sd.IDConfEstablecimiento,
+			&sd.Fecha,
+			&sd.HoraInicio,
+			&sd.HoraFin,
+			&sd.ZonaHoraria,
+			&inicio,
+			&fin,
+			&sd.Capacidad,
+			&sd.Cupos,
+			&total,
 		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan disponibilidad: %w", err)
+		}
+		sd.Inicio = enZona(inicio, sd.ZonaHoraria)
+		sd.Fin = enZona(fin, sd.ZonaHoraria)
+
+		slots = append(slots, sd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating disponibilidad: %w", err)
+	}
 
+	return slots, total, nil
+}
+
+func (hr *AiReservesRepository) InitAgenda(ctx context.Context, tx *sql.Tx, dias []domain.AgendaDia) (domain.AgendaResumen, error) {
+
+	var resumen domain.AgendaResumen
+
+	for _, dia := range dias {
+
+		// 1️⃣ Agenda del día: los UNIQUE(..., fecha) hacen que un día ya generado se omita
+		var idAgenda int
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO ai_res.agendas
+				(id_conf_personal, id_conf_establecimiento, fecha, zona_horaria, activa, created_by)
+			 VALUES ($1, $2, $3, $4, TRUE, 'ai_reserves')
+			 ON CONFLICT DO NOTHING
+			 RETURNING id`,
+			nullableInt(dia.IDConfPersonal),
+			nullableInt(dia.IDConfEstablecimiento),
+			dia.Fecha,
+			dia.ZonaHoraria,
+		).Scan(&idAgenda)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			resumen.DiasOmitidos++
+			resumen.SlotsOmitidos += len(dia.Slots)
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("updating conf_establecimiento: %w", err)
+			return domain.AgendaResumen{}, fmt.Errorf("insert agenda %s: %w", dia.Fecha.Format("2006-01-02"), err)
 		}
+		resumen.DiasCreados++
 
-		fmt.Printf("🔄 Updated conf_establecimiento for persona=%d + sub_tipo=%d\n",
-			req.IDPersona, req.IDSubTipoUnidadReserva)
+		if len(dia.Slots) == 0 {
+			continue
+		}
 
-		return nil
+		// 2️⃣ Slots del día en un solo INSERT (hora de reloj local + instantes UTC + capacidad)
+		inicios := make([]string, 0, len(dia.Slots))
+		fines := make([]string, 0, len(dia.Slots))
+		iniciosUTC := make([]string, 0, len(dia.Slots))
+		finesUTC := make([]string, 0, len(dia.Slots))
+		for _, slot := range dia.Slots {
+			inicios = append(inicios, slot.HoraInicio)
+			fines = append(fines, slot.HoraFin)
+			iniciosUTC = append(iniciosUTC, slot.Inicio.UTC().Format(time.RFC3339))
+			finesUTC = append(finesUTC, slot.Fin.UTC().Format(time.RFC3339))
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO ai_res.agenda_slots (id_agenda, hora_inicio, hora_fin, inicio_utc, fin_utc, estado, capacidad)
+			 SELECT $1, s.hora_inicio, s.hora_fin, s.inicio_utc, s.fin_utc, $6, $7
+			   FROM unnest($2::time[], $3::time[], $4::timestamptz[], $5::timestamptz[])
+			        AS s(hora_inicio, hora_fin, inicio_utc, fin_utc)
+			 ON CONFLICT (id_agenda, hora_inicio) DO NOTHING`,
+			idAgenda,
+			pq.Array(inicios),
+			pq.Array(fines),
+			pq.Array(iniciosUTC),
+			pq.Array(finesUTC),
+			domain.SlotLibre,
+			max(dia.Capacidad, 1),
+		)
+		if err != nil {
+			return domain.AgendaResumen{}, fmt.Errorf("insert agenda_slots agenda %d: %w", idAgenda, err)
+		}
+
+		creados, _ := res.RowsAffected()
+		resumen.SlotsCreados += int(creados)
+		resumen.SlotsOmitidos += len(dia.Slots) - int(creados)
 	}
 
-	// 5) INSERT COMPLETO si NO existe
-	queryInsert := `
-		INSERT INTO ai_res.conf_establecimiento
-			(id_persona, nombre, id_sub_tipo_unidad_reserva,
-			 hora_inicio, hora_fin,
-			 lunes, martes, miercoles, jueves, viernes, sabado, domingo,
-			 genera_feriados, created_by)
-		VALUES
-			($1, $2, $3,
-			 $4, $5,
-			 $6, $7, $8, $9, $10, $11, $12,
-			 $13, 'auth_security')
-		RETURNING id;
-	`
+	fmt.Printf("🗓️ Agendas generadas: %d días creados, %d omitidos, %d slots creados\n",
+		resumen.DiasCreados, resumen.DiasOmitidos, resumen.SlotsCreados)
 
-	var newID int
-	err = hr.dbPost.GetDB().QueryRowContext(ctx, queryInsert,
-		req.IDPersona,
-		req.Nombre,
-		req.IDSubTipoUnidadReserva,
-		req.HoraInicio,
-		req.HoraFin,
-		req.Lunes,
-		req.Martes,
-		req.Miercoles,
-		req.Jueves,
-		req.Viernes,
-		req.Sabado,
-		req.Domingo,
-		req.GeneraFeriados,
-	).Scan(&newID)
+	return resumen, nil
+}
+
+func (hr *AiReservesRepository) GetConfigPersonaFull(ctx context.Context, idPersona int) (domain.ConfigPersonaFull, error) {
+	var c domain.ConfigPersonaFull
 
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT id, id_persona, hora_inicio, hora_fin,
+		        COALESCE(lunes, FALSE), COALESCE(martes, FALSE), COALESCE(miercoles, FALSE),
+		        COALESCE(jueves, FALSE), COALESCE(viernes, FALSE), COALESCE(sabado, FALSE),
+		        COALESCE(domingo, FALSE), COALESCE(genera_feriados, FALSE), modo_agenda, zona_horaria
+		   FROM ai_res.conf_personal
+		  WHERE id_persona = $1`,
+		idPersona,
+	).Scan(
+		&c.ID,
+		&c.IDPersona,
+		&c.HoraInicio,
+		&c.HoraFin,
+		&c.Lunes,
+		&c.Martes,
+		&c.Miercoles,
+		&c.Jueves,
+		&c.Viernes,
+		&c.Sabado,
+		&c.Domingo,
+		&c.GeneraFeriados,
+		&c.ModoAgenda,
+		&c.ZonaHoraria,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ConfigPersonaFull{}, fmt.Errorf("config for persona id=%d does not exist", idPersona)
+	}
 	if err != nil {
-		return fmt.Errorf("insert conf_establecimiento: %w", err)
+		return domain.ConfigPersonaFull{}, fmt.Errorf("get conf_personal: %w", err)
 	}
 
-	fmt.Printf("🆕 Created conf_establecimiento id=%d for persona=%d + sub_tipo=%d\n",
-		newID, req.IDPersona, req.IDSubTipoUnidadReserva)
-
-	return nil
+	return c, nil
 }
 
-func (hr *AiReservesRepository) UpdateConfEstablecimientoField(ctx context.Context, req domain.ConfigEstablecimiento) error {
+// GetSubTiposConfPersonal devuelve los sub tipos del profesional con la duración efectiva
+// (override de conf_personal_sub_tipo_unidad_reserva o la estándar del sub tipo)
+func (hr *AiReservesRepository) GetSubTiposConfPersonal(ctx context.Context, idConfPersonal int) ([]domain.ConfigPersonalSubTipo, error) {
+
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT cps.id_conf_personal,
+		        cps.id_sub_tipo_unidad_reserva,
+		        COALESCE(NULLIF(cps.duracion_reserva_minutos, 0), st.duracion_reserva_minutos)
+		   FROM ai_res.conf_personal_sub_tipo_unidad_reserva cps
+		   JOIN ai_res.sub_tipo_unidad_reserva st ON st.id = cps.id_sub_tipo_unidad_reserva
+		  WHERE cps.id_conf_personal = $1
+		  ORDER BY cps.id_sub_tipo_unidad_reserva`,
+		idConfPersonal,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query sub tipos conf_personal: %w", err)
+	}
+	defer rows.Close()
 
-	// 1) Whitelist de columnas permitidas
-	validCols := map[string]string{
-		"nombre":          "string",
-		"hora_inicio":     "time",
-		"hora_fin":        "time",
-		"lunes":           "boolean",
-		"martes":          "boolean",
-		"miercoles":       "boolean",
-		"jueves":          "boolean",
-		"viernes":         "boolean",
-		"sabado":          "boolean",
-		"domingo":         "boolean",
-		"genera_feriados": "boolean",
+	var subTipos []domain.ConfigPersonalSubTipo
+	for rows.Next() {
+		var st domain.ConfigPersonalSubTipo
+		if err := rows.Scan(&st.IDConfPersonal, &st.IDSubTipoUnidadReserva, &st.DuracionReservaMinutos); err != nil {
+			return nil, fmt.Errorf("scan sub tipos conf_personal: %w", err)
+		}
+		subTipos = append(subTipos, st)
 	}
 
-	colType, ok := validCols[req.Atribute]
-	if !ok {
-		return fmt.Errorf("attribute '%s' cannot be updated", req.Atribute)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating sub tipos conf_personal: %w", err)
 	}
 
-	// 2) Validar existencia del registro
-	var exists bool
+	return subTipos, nil
+}
+
+func (hr *AiReservesRepository) GetConfEstablecimiento(ctx context.Context, idConfEstablecimiento int) (domain.ConfEstablecimiento, error) {
+	var c domain.ConfEstablecimiento
+
 	err := hr.dbPost.GetDB().QueryRowContext(ctx,
-		`SELECT EXISTS(
-			SELECT 1 FROM ai_res.conf_establecimiento
-			 WHERE id_persona = $1
-			   AND id_sub_tipo_unidad_reserva = $2
-		)`,
-		req.IDPersona,
-		req.IDSubTipoUnidadReserva,
-	).Scan(&exists)
+		`SELECT id, id_persona, nombre, id_sub_tipo_unidad_reserva, hora_inicio, hora_fin,
+		        COALESCE(lunes, FALSE), COALESCE(martes, FALSE), COALESCE(miercoles, FALSE),
+		        COALESCE(jueves, FALSE), COALESCE(viernes, FALSE), COALESCE(sabado, FALSE),
+		        COALESCE(domingo, FALSE), COALESCE(genera_feriados, FALSE), zona_horaria
+		   FROM ai_res.conf_establecimiento
+		  WHERE id = $1`,
+		idConfEstablecimiento,
+	).Scan(
+		&c.ID,
+		&c.IDPersona,
+		&c.Nombre,
+		&c.IDSubTipoUnidadReserva,
+		&c.HoraInicio,
+		&c.HoraFin,
+		&c.Lunes,
+		&c.Martes,
+		&c.Miercoles,
+		&c.Jueves,
+		&c.Viernes,
+		&c.Sabado,
+		&c.Domingo,
+		&c.GeneraFeriados,
+		&c.ZonaHoraria,
+	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ConfEstablecimiento{}, fmt.Errorf("conf_establecimiento id=%d does not exist", idConfEstablecimiento)
+	}
 	if err != nil {
-		return fmt.Errorf("checking conf_establecimiento existence: %w", err)
+		return domain.ConfEstablecimiento{}, fmt.Errorf("get conf_establecimiento: %w", err)
 	}
 
-	if !exists {
-		return fmt.Errorf("no conf_establecimiento found for persona=%d + sub_tipo=%d",
-			req.IDPersona, req.IDSubTipoUnidadReserva)
+	return c, nil
+}
+
+func (hr *AiReservesRepository) GetDuracionSubTipo(ctx context.Context, idSubTipo int) (int, error) {
+	var minutos int
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT duracion_reserva_minutos
+		   FROM ai_res.sub_tipo_unidad_reserva
+		  WHERE id = $1`,
+		idSubTipo,
+	).Scan(&minutos)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", idSubTipo)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get duracion sub_tipo_unidad_reserva: %w", err)
 	}
 
-	// 3) Convertir valor según tipo
-	var castValue interface{}
+	return minutos, nil
+}
 
-	switch colType {
+// GetCapacidadSubTipo devuelve cuántas reservas entran en un mismo slot del sub tipo
+func (hr *AiReservesRepository) GetCapacidadSubTipo(ctx context.Context, idSubTipo int) (int, error) {
+	var capacidad int
 
-	case "boolean":
-		if req.Value == "true" || req.Value == "1" {
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT capacidad
+		   FROM ai_res.sub_tipo_unidad_reserva
+		  WHERE id = $1`,
+		idSubTipo,
+	).Scan(&capacidad)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", idSubTipo)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get capacidad sub_tipo_unidad_reserva: %w", err)
+	}
+
+	return capacidad, nil
+}
+
+// GetIDAgenda resuelve la agenda activa de un profesional (conf_personal) o de un establecimiento para una fecha
+func (hr *AiReservesRepository) GetIDAgenda(ctx context.Context, idConfPersonal int, idConfEstablecimiento int, fecha time.Time) (int, error) {
+	var idAgenda int
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT id
+		   FROM ai_res.agendas
+		  WHERE fecha = $3::date
+		    AND COALESCE(activa, TRUE)
+		    AND (($1::int IS NOT NULL AND id_conf_personal = $1)
+		      OR ($2::int IS NOT NULL AND id_conf_establecimiento = $2))`,
+		nullableInt(idConfPersonal),
+		nullableInt(idConfEstablecimiento),
+		fecha.Format("2006-01-02"),
+	).Scan(&idAgenda)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: fecha %s", domain.ErrAgendaNotFound, fecha.Format("2006-01-02"))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get agenda: %w", err)
+	}
+
+	return idAgenda, nil
+}
+
+// nullableInt traduce un id opcional (0 = sin valor) a NULL
+func nullableInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+// nullableString traduce un valor opcional ("" = sin valor) a NULL
+func nullableString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+// enZona expresa un instante leído de la base (en UTC) en la zona de su agenda
+func enZona(t sql.NullTime, zonaHoraria string) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	loc, err := zona.Cargar(zonaHoraria)
+	if err != nil {
+		return t.Time
+	}
+	return t.Time.In(loc)
+}
+
+// zonaReserva completa la zona de la agenda y los instantes de una reserva leída de la base
+func zonaReserva(r *domain.Reserva, zonaHoraria string, inicio, fin sql.NullTime) {
+	r.ZonaHoraria = zonaHoraria
+	r.Inicio = enZona(inicio, zonaHoraria)
+	r.Fin = enZona(fin, zonaHoraria)
+}
+
+func (hr *AiReservesRepository) GetInfoPersona(ctx context.Context, idPersona int) (domain.Persona, error) {
+	var p domain.Persona
+
+	query := `
+		SELECT id, nombre, apellido_razon_social, persona_juridica,
+		       tipo_doc_persona, nro_doc_persona, email, telefono
+		FROM ai_res.personas
+		WHERE id = $1
+	`
+
+	row := hr.dbPost.GetDB().QueryRowContext(ctx, query, idPersona)
+
+	err := row.Scan(
+		&p.ID,
+		&p.Nombre,
+		&p.ApellidoRazonSocial,
+		&p.PersonaJuridia,
+		&p.TipoDocPersona,
+		&p.NroDocPersona,
+		&p.Email,
+		&p.TelPersona,
+	)
+
+	if err != nil {
+		return domain.Persona{}, err
+	}
+
+	return p, nil
+}
+
+func (hr *AiReservesRepository) GetReservasPersona(ctx context.Context, req domain.GetReservaPersona) ([]domain.Reserva, error) {
+	db := hr.dbPost.GetDB()
+
+	// 1️⃣ Validar que el sub_tipo exista (buenas prácticas)
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 
+			  FROM ai_res.personas
+			 WHERE id = $1
+		)`,
+		req.IDPersona,
+	).Scan(&exists)
+
+	if err != nil {
+		return nil, fmt.Errorf("validating person: %w", err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("person doesn't exists")
+	}
+
+	// 2️⃣ Query principal
+	rows, err := db.QueryContext(ctx,
+		`SELECT 
+			r.id,
+			r.id_agenda,
+			r.fecha,
+			r.hora_inicio,
+			r.hora_fin,
+			r.id_paciente,
+			r.estado,
+			r.observaciones,
+			r.id_sub_tipo_unidad_reserva,
+			a.zona_horaria,
+			r.inicio_utc,
+			r.fin_utc
+		 FROM ai_res.reservas r
+		 JOIN ai_res.agendas a ON a.id = r.id_agenda
+		 WHERE r.id_paciente = $1
+		   AND r.estado IN ('PENDIENTE', 'CONFIRMADA')
+		   AND r.fin_utc > CURRENT_TIMESTAMP
+		 ORDER BY r.inicio_utc`,
+		req.IDPersona,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("query reservas: %w", err)
+	}
+	defer rows.Close()
+
+	// 3️⃣ Mapear filas
+	var reservas []domain.Reserva
+
+	for rows.Next() {
+		var r domain.Reserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+
+		err := rows.Scan(
+			&r.ID,
+			&r.IDAgenda,
+			&r.Fecha,
+			&r.HoraInicio,
+			&r.HoraFin,
+			&r.IDPaciente,
+			&r.Estado,
+			&r.Observaciones,
+			&r.IDSubTipoUnidadReserva,
+			&zonaHoraria,
+			&inicio,
+			&fin,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("scan reservas: %w", err)
+		}
+
+		zonaReserva(&r, zonaHoraria, inicio, fin)
+		reservas = append(reservas, r)
+	}
+
+	// 4️⃣ Manejar error de iteración
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas: %w", err)
+	}
+
+	return reservas, nil
+}
+
+func (hr *AiReservesRepository) GetReservasUnidadReserva(ctx context.Context, req domain.GetReservaUnidadReserva) ([]domain.Reserva, error) {
+
+	db := hr.dbPost.GetDB()
+
+	// 1️⃣ Validar que el sub_tipo exista (buenas prácticas)
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 
+			  FROM ai_res.sub_tipo_unidad_reserva
+			 WHERE id = $1
+		)`,
+		req.IDSubTipoUnidadReserva,
+	).Scan(&exists)
+
+	if err != nil {
+		return nil, fmt.Errorf("validating sub_tipo_unidad_reserva: %w", err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", req.IDSubTipoUnidadReserva)
+	}
+
+	// 2️⃣ Query principal
+	rows, err := db.QueryContext(ctx,
+		`SELECT 
+			r.id,
+			r.id_agenda,
+			r.fecha,
+			r.hora_inicio,
+			r.hora_fin,
+			r.id_paciente,
+			r.estado,
+			r.observaciones,
+			r.id_sub_tipo_unidad_reserva,
+			a.zona_horaria,
+			r.inicio_utc,
+			r.fin_utc
+		 FROM ai_res.reservas r
+		 JOIN ai_res.agendas a ON a.id = r.id_agenda
+		 WHERE r.id_sub_tipo_unidad_reserva = $1
+		   AND r.estado IN ('PENDIENTE', 'CONFIRMADA')
+		   AND r.fin_utc > CURRENT_TIMESTAMP
+		 ORDER BY r.inicio_utc`,
+		req.IDSubTipoUnidadReserva,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("query reservas: %w", err)
+	}
+	defer rows.Close()
+
+	// 3️⃣ Mapear filas
+	var reservas []domain.Reserva
+
+	for rows.Next() {
+		var r domain.Reserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+
+		err := rows.Scan(
+			&r.ID,
+			&r.IDAgenda,
+			&r.Fecha,
+			&r.HoraInicio,
+			&r.HoraFin,
+			&r.IDPaciente,
+			&r.Estado,
+			&r.Observaciones,
+			&r.IDSubTipoUnidadReserva,
+			&zonaHoraria,
+			&inicio,
+			&fin,
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("scan reservas: %w", err)
+		}
+
+		zonaReserva(&r, zonaHoraria, inicio, fin)
+		reservas = append(reservas, r)
+	}
+
+	// 4️⃣ Manejar error de iteración
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas: %w", err)
+	}
+
+	return reservas, nil
+}
+
+// PushEventToQueue graba el evento en el outbox dentro de la transacción del cambio que lo origina;
+// el relay lo publica después. Un id_event repetido no se vuelve a grabar.
+func (hr *AiReservesRepository) PushEventToQueue(ctx context.Context, tx *sql.Tx, event domain.Event) error {
+	query := `
+		INSERT INTO ai_res.outbox_events (
+			id_event,
+			tipo,
+			routing_key,
+			origen,
+			payload,
+			ocurrido_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id_event)
+		DO NOTHING;
+	`
+
+	payloadJSON, err := json.Marshal(event.Payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling payload: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, query,
+		event.ID,
+		event.Type,
+		event.RoutingKey,
+		event.Origin,
+		payloadJSON,
+		event.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting outbox event: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return domain.ErrDuplicateEvent
+	}
+
+	return nil
+}
+
+// ClaimOutboxPendientes reclama los eventos pendientes cuyo reintento ya venció, y los que quedaron en
+// PROCESSING con el lease vencido, pasándolos a PROCESSING hasta locked_until. Es un único UPDATE:
+// los locks duran lo que la sentencia y SKIP LOCKED reparte el trabajo entre réplicas.
+func (hr *AiReservesRepository) ClaimOutboxPendientes(ctx context.Context, limite int, lease time.Duration) ([]domain.OutboxEvent, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`WITH reclamados AS (
+		     UPDATE ai_res.outbox_events
+		        SET estado = $1,
+		            locked_until = CURRENT_TIMESTAMP + make_interval(mins => $4)
+		      WHERE id IN (
+		            SELECT id
+		              FROM ai_res.outbox_events
+		             WHERE (estado = $2 AND proximo_intento <= CURRENT_TIMESTAMP)
+		                OR (estado = $1 AND locked_until <= CURRENT_TIMESTAMP)
+		             ORDER BY id
+		             LIMIT $3
+		               FOR UPDATE SKIP LOCKED)
+		  RETURNING id, id_event, tipo, routing_key, origen, payload, ocurrido_at, intentos
+		 )
+		 SELECT id, id_event, tipo, routing_key, origen, payload, ocurrido_at, intentos
+		   FROM reclamados
+		  ORDER BY id`,
+		domain.OutboxProcesando,
+		domain.OutboxPendiente,
+		limite,
+		int(lease.Minutes()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query outbox pendientes: %w", err)
+	}
+	defer rows.Close()
+
+	var eventos []domain.OutboxEvent
+	for rows.Next() {
+		var ev domain.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Event.ID, &ev.Event.Type, &ev.Event.RoutingKey, &ev.Event.Origin,
+			&payload, &ev.Event.Timestamp, &ev.Intentos); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		// El payload viaja tal como se grabó
+		ev.Event.Payload = json.RawMessage(payload)
+		eventos = append(eventos, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating outbox events: %w", err)
+	}
+
+	return eventos, nil
+}
+
+// MarkOutboxEnviado cierra el evento reclamado; si el lease venció y otra réplica ya lo marcó, no lo toca
+func (hr *AiReservesRepository) MarkOutboxEnviado(ctx context.Context, id int64) error {
+	_, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.outbox_events
+		    SET estado = $2,
+		        intentos = intentos + 1,
+		        ultimo_error = NULL,
+		        locked_until = NULL,
+		        enviado_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		    AND estado = $3`,
+		id,
+		domain.OutboxEnviado,
+		domain.OutboxProcesando,
+	)
+	if err != nil {
+		return fmt.Errorf("mark outbox event %d enviado: %w", id, err)
+	}
+
+	return nil
+}
+
+// MarkOutboxError registra el intento fallido: queda PENDING hasta proximoIntento o FAILED si agotó los reintentos
+func (hr *AiReservesRepository) MarkOutboxError(ctx context.Context, id int64, estado string, proximoIntento time.Time, causa string) error {
+	_, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.outbox_events
+		    SET estado = $2,
+		        intentos = intentos + 1,
+		        proximo_intento = $3,
+		        ultimo_error = $4,
+		        locked_until = NULL
+		  WHERE id = $1
+		    AND estado = $5`,
+		id,
+		estado,
+		proximoIntento,
+		causa,
+		domain.OutboxProcesando,
+	)
+	if err != nil {
+		return fmt.Errorf("mark outbox event %d error: %w", id, err)
+	}
+
+	return nil
+}
+
+// RegistrarEventoProcesado deja constancia del evento recibido en la transacción que lo aplica.
+// Devuelve false si ya estaba registrado: una entrega paralela del mismo evento espera acá a que
+// la primera termine y, si confirmó, no lo vuelve a aplicar.
+func (hr *AiReservesRepository) RegistrarEventoProcesado(ctx context.Context, tx *sql.Tx, event domain.Event) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO ai_res.eventos_procesados (id_event, routing_key, origen)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (id_event) DO NOTHING`,
+		event.ID,
+		event.RoutingKey,
+		nullableString(event.Origin),
+	)
+	if err != nil {
+		return false, fmt.Errorf("registrar evento procesado %s: %w", event.ID, err)
+	}
+
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+func (hr *AiReservesRepository) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := hr.dbPost.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return conflictoConcurrente(err)
+	}
+
+	return conflictoConcurrente(tx.Commit())
+}
+
+// conflictoConcurrente marca como reintentables el deadlock (40P01) y la falla de serialización (40001)
+func conflictoConcurrente(err error) error {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) && (pgErr.Code == "40P01" || pgErr.Code == "40001") {
+		return fmt.Errorf("%w: %w", domain.ErrConflictoConcurrente, err)
+	}
+	return err
+}
+
+func (hr *AiReservesRepository) InsertConfigPersonalSubTipo(ctx context.Context, config domain.ConfigPersonalSubTipo) error {
+	// The SQL insert statement
+	query := `
+		INSERT INTO ai_res.conf_personal_sub_tipo_unidad_reserva
+			(id_conf_personal, id_sub_tipo_unidad_reserva, duracion_reserva_minutos)
+		VALUES ($1, $2, $3)
+	`
+
+	// Execute the query with the provided parameters
+	_, err := hr.dbPost.GetDB().ExecContext(ctx, query,
+		config.IDConfPersonal,
+		config.IDSubTipoUnidadReserva,
+		config.DuracionReservaMinutos,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting config personal sub tipo: %w", err)
+	}
+
+	return nil
+}
+
+func (hr *AiReservesRepository) InsertOrUpdateConfEstablecimiento(ctx context.Context, req domain.ConfEstablecimiento) error {
+
+	// 1) Validar existencia de persona
+	var personaExists bool
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM ai_res.personas WHERE id = $1)`,
+		req.IDPersona,
+	).Scan(&personaExists)
+	if err != nil {
+		return fmt.Errorf("checking persona existence: %w", err)
+	}
+	if !personaExists {
+		return fmt.Errorf("persona id=%d does not exist", req.IDPersona)
+	}
+
+	// 2) Validar existence of sub_tipo_unidad_reserva
+	var subTipoExists bool
+	err = hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM ai_res.sub_tipo_unidad_reserva WHERE id = $1)`,
+		req.IDSubTipoUnidadReserva,
+	).Scan(&subTipoExists)
+	if err != nil {
+		return fmt.Errorf("checking sub_tipo_unidad_reserva: %w", err)
+	}
+	if !subTipoExists {
+		return fmt.Errorf("sub_tipo_unidad_reserva id=%d does not exist", req.IDSubTipoUnidadReserva)
+	}
+
+	// 3) Ver si ya existe el registro para (id_persona + id_sub_tipo)
+	var exists bool
+	err = hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 
+			  FROM ai_res.conf_establecimiento
+			 WHERE id_persona = $1
+			   AND id_sub_tipo_unidad_reserva = $2
+		)`,
+		req.IDPersona, req.IDSubTipoUnidadReserva,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking existing conf_establecimiento: %w", err)
+	}
+
+	// 4) UPDATE COMPLETO si ya existe
+	if exists {
+		queryUpdate := `
+			UPDATE ai_res.conf_establecimiento
+			   SET nombre = $1,
+				   hora_inicio = $2,
+				   hora_fin = $3,
+				   lunes = $4,
+				   martes = $5,
+				   miercoles = $6,
+				   jueves = $7,
+				   viernes = $8,
+				   sabado = $9,
+				   domingo = $10,
+				   genera_feriados = $11,
+				   zona_horaria = $14,
+				   updated_at = CURRENT_TIMESTAMP,
+				   updated_by = 'auth_security'
+			 WHERE id_persona = $12
+			   AND id_sub_tipo_unidad_reserva = $13
+		`
+
+		_, err = hr.dbPost.GetDB().ExecContext(ctx, queryUpdate,
+			req.Nombre,
+			req.HoraInicio,
+			req.HoraFin,
+			req.Lunes,
+			req.Martes,
+			req.Miercoles,
+			req.Jueves,
+			req.Viernes,
+			req.Sabado,
+			req.Domingo,
+			req.GeneraFeriados,
+			req.IDPersona,
+			req.IDSubTipoUnidadReserva,
+			req.ZonaHoraria,
+		)
+
+		if err != nil {
+			return fmt.Errorf("updating conf_establecimiento: %w", err)
+		}
+
+		fmt.Printf("🔄 Updated conf_establecimiento for persona=%d + sub_tipo=%d\n",
+			req.IDPersona, req.IDSubTipoUnidadReserva)
+
+		return nil
+	}
+
+	// 5) INSERT COMPLETO si NO existe
+	queryInsert := `
+		INSERT INTO ai_res.conf_establecimiento
+			(id_persona, nombre, id_sub_tipo_unidad_reserva,
+			 hora_inicio, hora_fin,
+			 lunes, martes, miercoles, jueves, viernes, sabado, domingo,
+			 genera_feriados, zona_horaria, created_by)
+		VALUES
+			($1, $2, $3,
+			 $4, $5,
+			 $6, $7, $8, $9, $10, $11, $12,
+			 $13, $14, 'auth_security')
+		RETURNING id;
+	`
+
+	var newID int
+	err = hr.dbPost.GetDB().QueryRowContext(ctx, queryInsert,
+		req.IDPersona,
+		req.Nombre,
+		req.IDSubTipoUnidadReserva,
+		req.HoraInicio,
+		req.HoraFin,
+		req.Lunes,
+		req.Martes,
+		req.Miercoles,
+		req.Jueves,
+		req.Viernes,
+		req.Sabado,
+		req.Domingo,
+		req.GeneraFeriados,
+		req.ZonaHoraria,
+	).Scan(&newID)
+
+	if err != nil {
+		return fmt.Errorf("insert conf_establecimiento: %w", err)
+	}
+
+	fmt.Printf("🆕 Created conf_establecimiento id=%d for persona=%d + sub_tipo=%d\n",
+		newID, req.IDPersona, req.IDSubTipoUnidadReserva)
+
+	return nil
+}
+
+func (hr *AiReservesRepository) UpdateConfEstablecimientoField(ctx context.Context, req domain.ConfigEstablecimiento) error {
+
+	// 1) Whitelist de columnas permitidas
+	validCols := map[string]string{
+		"nombre":          "string",
+		"hora_inicio":     "time",
+		"hora_fin":        "time",
+		"lunes":           "boolean",
+		"martes":          "boolean",
+		"miercoles":       "boolean",
+		"jueves":          "boolean",
+		"viernes":         "boolean",
+		"sabado":          "boolean",
+		"domingo":         "boolean",
+		"genera_feriados": "boolean",
+		"zona_horaria":    "timezone",
+	}
+
+	colType, ok := validCols[req.Atribute]
+	if !ok {
+		return fmt.Errorf("attribute '%s' cannot be updated", req.Atribute)
+	}
+
+	// 2) Validar existencia del registro
+	var exists bool
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM ai_res.conf_establecimiento
+			 WHERE id_persona = $1
+			   AND id_sub_tipo_unidad_reserva = $2
+		)`,
+		req.IDPersona,
+		req.IDSubTipoUnidadReserva,
+	).Scan(&exists)
+
+	if err != nil {
+		return fmt.Errorf("checking conf_establecimiento existence: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("no conf_establecimiento found for persona=%d + sub_tipo=%d",
+			req.IDPersona, req.IDSubTipoUnidadReserva)
+	}
+
+	// 3) Convertir valor según tipo
+	var castValue interface{}
+
+	switch colType {
+
+	case "boolean":
+		if req.Value == "true" || req.Value == "1" {
 			castValue = true
 		} else if req.Value == "false" || req.Value == "0" {
 			castValue = false
@@ -1541,45 +2846,1846 @@ func (hr *AiReservesRepository) UpdateConfEstablecimientoField(ctx context.Conte
 			return fmt.Errorf("invalid boolean value '%s'", req.Value)
 		}
 
-	case "time":
-		t, errConv := time.Parse("15:04", req.Value)
-		if errConv != nil {
-			t, errConv = time.Parse("15:04:05", req.Value)
-			if errConv != nil {
-				return fmt.Errorf("invalid time '%s', expected HH:mm or HH:mm:ss", req.Value)
-			}
+	case "time":
+		t, errConv := time.Parse("15:04", req.Value)
+		if errConv != nil {
+			t, errConv = time.Parse("15:04:05", req.Value)
+			if errConv != nil {
+				return fmt.Errorf("invalid time '%s', expected HH:mm or HH:mm:ss", req.Value)
+			}
+		}
+		castValue = t
+
+	case "string":
+		castValue = req.Value
+
+	case "timezone":
+		if _, errZona := zona.Cargar(req.Value); errZona != nil || req.Value == "" {
+			return fmt.Errorf("invalid time zone '%s', expected an IANA name like %s", req.Value, zona.Default)
+		}
+		castValue = req.Value
+
+	default:
+		return fmt.Errorf("unsupported type '%s' for field '%s'", colType, req.Atribute)
+	}
+
+	// 4) UPDATE dinámico seguro
+	queryUpdate := fmt.Sprintf(`
+		UPDATE ai_res.conf_establecimiento
+		   SET %s = $1,
+		       updated_at = CURRENT_TIMESTAMP,
+		       updated_by = 'auth_security'
+		 WHERE id_persona = $2
+		   AND id_sub_tipo_unidad_reserva = $3
+	`, req.Atribute)
+
+	_, err = hr.dbPost.GetDB().ExecContext(ctx, queryUpdate,
+		castValue,
+		req.IDPersona,
+		req.IDSubTipoUnidadReserva,
+	)
+
+	if err != nil {
+		return fmt.Errorf("update conf_establecimiento: %w", err)
+	}
+
+	fmt.Printf("🔄 Updated conf_establecimiento field %s for persona=%d + sub_tipo=%d\n",
+		req.Atribute, req.IDPersona, req.IDSubTipoUnidadReserva)
+
+	return nil
+}
+
+func (hr *AiReservesRepository) CreateFeriado(ctx context.Context, req domain.Feriado) (int, error) {
+	var id int
+
+	// Si ya existe el feriado para ese día y alcance, se actualiza la descripción
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`INSERT INTO ai_res.feriados
+			(fecha, descripcion, id_conf_establecimiento, origen, created_by)
+		 VALUES ($1, $2, $3, $4, 'ai_reserves')
+		 ON CONFLICT (fecha, (COALESCE(id_conf_establecimiento, 0)))
+		 DO UPDATE SET descripcion = EXCLUDED.descripcion,
+		               updated_at = CURRENT_TIMESTAMP,
+		               updated_by = 'ai_reserves'
+		 RETURNING id`,
+		req.Fecha,
+		nullableString(req.Descripcion),
+		req.IDConfEstablecimiento,
+		req.Origen,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert feriado: %w", err)
+	}
+
+	fmt.Printf("🎌 Feriado %s guardado ID=%d\n", req.Fecha.Format("2006-01-02"), id)
+	return id, nil
+}
+
+func (hr *AiReservesRepository) UpdFeriado(ctx context.Context, req domain.Feriado) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.feriados
+		    SET fecha = $2,
+		        descripcion = $3,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		req.ID,
+		req.Fecha,
+		nullableString(req.Descripcion),
+	)
+	if err != nil {
+		return fmt.Errorf("update feriado: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrFeriadoNotFound, req.ID)
+	}
+
+	return nil
+}
+
+func (hr *AiReservesRepository) DeleteFeriado(ctx context.Context, idFeriado int) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`DELETE FROM ai_res.feriados WHERE id = $1`,
+		idFeriado,
+	)
+	if err != nil {
+		return fmt.Errorf("delete feriado: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrFeriadoNotFound, idFeriado)
+	}
+
+	return nil
+}
+
+// GetFeriados devuelve los feriados nacionales del rango y, si se indica establecimiento, también sus cierres
+func (hr *AiReservesRepository) GetFeriados(ctx context.Context, req domain.FeriadoFiltro) ([]domain.Feriado, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT id, fecha, COALESCE(descripcion, ''), id_conf_establecimiento, origen
+		   FROM ai_res.feriados
+		  WHERE fecha BETWEEN $1 AND $2
+		    AND (id_conf_establecimiento IS NULL OR id_conf_establecimiento = $3)
+		  ORDER BY fecha, id_conf_establecimiento NULLS FIRST`,
+		req.FechaDesde,
+		req.FechaHasta,
+		nullableInt(req.IDConfEstablecimiento),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying feriados: %w", err)
+	}
+	defer rows.Close()
+
+	feriados := []domain.Feriado{}
+	for rows.Next() {
+		var f domain.Feriado
+		if err := rows.Scan(&f.ID, &f.Fecha, &f.Descripcion, &f.IDConfEstablecimiento, &f.Origen); err != nil {
+			return nil, fmt.Errorf("scanning feriado: %w", err)
+		}
+		feriados = append(feriados, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating feriados: %w", err)
+	}
+
+	return feriados, nil
+}
+
+// InsertFeriados carga feriados importados; los días ya cargados para el mismo alcance se omiten
+func (hr *AiReservesRepository) InsertFeriados(ctx context.Context, tx *sql.Tx, feriados []domain.Feriado) (domain.FeriadoImportResumen, error) {
+	var resumen domain.FeriadoImportResumen
+
+	for _, f := range feriados {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO ai_res.feriados
+				(fecha, descripcion, id_conf_establecimiento, origen, created_by)
+			 VALUES ($1, $2, $3, $4, 'ai_reserves')
+			 ON CONFLICT (fecha, (COALESCE(id_conf_establecimiento, 0))) DO NOTHING`,
+			f.Fecha,
+			nullableString(f.Descripcion),
+			f.IDConfEstablecimiento,
+			f.Origen,
+		)
+		if err != nil {
+			return resumen, fmt.Errorf("insert feriado %s: %w", f.Fecha.Format("2006-01-02"), err)
+		}
+
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			resumen.Omitidos++
+			continue
+		}
+		resumen.Creados++
+	}
+
+	fmt.Printf("🎌 Feriados importados: %d creados, %d omitidos\n", resumen.Creados, resumen.Omitidos)
+	return resumen, nil
+}
+
+func (hr *AiReservesRepository) CreateBloqueo(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) (int, error) {
+	var id int
+
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO ai_res.agenda_bloqueos
+			(id_conf_personal, id_conf_establecimiento, inicio, fin, motivo, created_by, uid_ical, ocurrencia_ical)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id`,
+		nullableInt(req.IDConfPersonal),
+		nullableInt(req.IDConfEstablecimiento),
+		req.Inicio,
+		req.Fin,
+		nullableString(req.Motivo),
+		req.CreatedBy,
+		nullableString(req.UID),
+		sql.NullTime{Time: req.Ocurrencia, Valid: req.UID != ""},
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert agenda_bloqueo: %w", err)
+	}
+
+	fmt.Printf("⛔ Bloqueo de agenda creado ID=%d (%s → %s)\n", id,
+		req.Inicio.Format("2006-01-02 15:04"), req.Fin.Format("2006-01-02 15:04"))
+	return id, nil
+}
+
+// BloquearSlots asocia al bloqueo los slots que se superponen con su rango.
+// Los libres pasan a BLOQUEADO; los ocupados conservan la reserva hasta que se reprograme o cancele.
+func (hr *AiReservesRepository) BloquearSlots(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) (int, error) {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots s
+		    SET id_bloqueo = $1,
+		        estado = CASE WHEN COALESCE(s.estado, 'LIBRE') = $6 THEN $7 ELSE s.estado END,
+		        updated_at = CURRENT_TIMESTAMP
+		   FROM ai_res.agendas a
+		  WHERE a.id = s.id_agenda
+		    AND (a.id_conf_personal = $2 OR a.id_conf_establecimiento = $3)
+		    AND s.inicio_utc < $5
+		    AND s.fin_utc > $4
+		    AND s.id_bloqueo IS NULL`,
+		req.ID,
+		nullableInt(req.IDConfPersonal),
+		nullableInt(req.IDConfEstablecimiento),
+		req.Inicio,
+		req.Fin,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("blocking agenda_slots for bloqueo %d: %w", req.ID, err)
+	}
+
+	bloqueados, _ := res.RowsAffected()
+	return int(bloqueados), nil
+}
+
+// GetReservasEnBloqueo devuelve las reservas vigentes que se superponen con el bloqueo
+func (hr *AiReservesRepository) GetReservasEnBloqueo(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) ([]domain.Reserva, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT r.id,
+		        r.id_agenda,
+		        r.fecha,
+		        to_char(r.hora_inicio, 'HH24:MI'),
+		        to_char(r.hora_fin, 'HH24:MI'),
+		        r.id_paciente,
+		        COALESCE(r.estado, 'PENDIENTE'),
+		        r.observaciones,
+		        r.id_sub_tipo_unidad_reserva,
+		        COALESCE(a.id_conf_establecimiento, 0),
+		        a.zona_horaria,
+		        r.inicio_utc,
+		        r.fin_utc
+		   FROM ai_res.reservas r
+		   JOIN ai_res.agendas a ON a.id = r.id_agenda
+		  WHERE (a.id_conf_personal = $1 OR a.id_conf_establecimiento = $2)
+		    AND COALESCE(r.estado, 'PENDIENTE') IN ($5, $6)
+		    AND r.inicio_utc < $4
+		    AND r.fin_utc > $3
+		  ORDER BY r.inicio_utc`,
+		nullableInt(req.IDConfPersonal),
+		nullableInt(req.IDConfEstablecimiento),
+		req.Inicio,
+		req.Fin,
+		domain.ReservaPendiente,
+		domain.ReservaConfirmada,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas en bloqueo: %w", err)
+	}
+	defer rows.Close()
+
+	reservas := []domain.Reserva{}
+	for rows.Next() {
+		var r domain.Reserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+		if err := rows.Scan(&r.ID, &r.IDAgenda, &r.Fecha, &r.HoraInicio, &r.HoraFin, &r.IDPaciente,
+			&r.Estado, &r.Observaciones, &r.IDSubTipoUnidadReserva, &r.IDConfEstablecimiento,
+			&zonaHoraria, &inicio, &fin); err != nil {
+			return nil, fmt.Errorf("scanning reserva en bloqueo: %w", err)
+		}
+		zonaReserva(&r, zonaHoraria, inicio, fin)
+		reservas = append(reservas, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas en bloqueo: %w", err)
+	}
+
+	return reservas, nil
+}
+
+// DeleteBloqueo elimina el bloqueo y libera sus slots; devuelve el bloqueo borrado
+// para poder reaplicar otros bloqueos que se superpongan
+func (hr *AiReservesRepository) DeleteBloqueo(ctx context.Context, tx *sql.Tx, idBloqueo int) (domain.AgendaBloqueo, error) {
+	var b domain.AgendaBloqueo
+	var idConfPersonal, idConfEstablecimiento sql.NullInt64
+	var motivo sql.NullString
+
+	err := tx.QueryRowContext(ctx,
+		`DELETE FROM ai_res.agenda_bloqueos
+		  WHERE id = $1
+		 RETURNING id, id_conf_personal, id_conf_establecimiento, inicio, fin, motivo`,
+		idBloqueo,
+	).Scan(&b.ID, &idConfPersonal, &idConfEstablecimiento, &b.Inicio, &b.Fin, &motivo)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.AgendaBloqueo{}, fmt.Errorf("%w: id=%d", domain.ErrBloqueoNotFound, idBloqueo)
+	}
+	if err != nil {
+		return domain.AgendaBloqueo{}, fmt.Errorf("delete agenda_bloqueo: %w", err)
+	}
+
+	b.IDConfPersonal = int(idConfPersonal.Int64)
+	b.IDConfEstablecimiento = int(idConfEstablecimiento.Int64)
+	b.Motivo = motivo.String
+
+	// El ON DELETE SET NULL ya soltó id_bloqueo: los slots que seguían bloqueados vuelven a LIBRE
+	_, err = tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots s
+		    SET estado = $3,
+		        updated_at = CURRENT_TIMESTAMP
+		   FROM ai_res.agendas a
+		  WHERE a.id = s.id_agenda
+		    AND (a.id_conf_personal = $1 OR a.id_conf_establecimiento = $2)
+		    AND s.estado = $4
+		    AND s.id_bloqueo IS NULL`,
+		nullableInt(b.IDConfPersonal),
+		nullableInt(b.IDConfEstablecimiento),
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+	)
+	if err != nil {
+		return domain.AgendaBloqueo{}, fmt.Errorf("releasing agenda_slots of bloqueo %d: %w", idBloqueo, err)
+	}
+
+	fmt.Printf("✅ Bloqueo de agenda eliminado ID=%d\n", idBloqueo)
+	return b, nil
+}
+
+// GetBloqueos devuelve los bloqueos del profesional o establecimiento que se superponen con el rango
+func (hr *AiReservesRepository) GetBloqueos(ctx context.Context, req domain.BloqueoFiltro) ([]domain.AgendaBloqueo, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT id,
+		        COALESCE(id_conf_personal, 0),
+		        COALESCE(id_conf_establecimiento, 0),
+		        inicio,
+		        fin,
+		        COALESCE(motivo, ''),
+		        COALESCE(created_by, ''),
+		        COALESCE(uid_ical, ''),
+		        ocurrencia_ical
+		   FROM ai_res.agenda_bloqueos
+		  WHERE (id_conf_personal = $1 OR id_conf_establecimiento = $2)
+		    AND inicio < $4
+		    AND fin > $3
+		  ORDER BY inicio, id`,
+		nullableInt(req.IDConfPersonal),
+		nullableInt(req.IDConfEstablecimiento),
+		req.FechaDesde,
+		req.FechaHasta,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying agenda_bloqueos: %w", err)
+	}
+
+	return scanBloqueos(rows)
+}
+
+// GetBloqueosICal bloquea y devuelve los bloqueos importados del profesional para los UID indicados
+// que todavía no terminaron al momento desde
+func (hr *AiReservesRepository) GetBloqueosICal(ctx context.Context, tx *sql.Tx, idConfPersonal int, uids []string, desde time.Time) ([]domain.AgendaBloqueo, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id,
+		        COALESCE(id_conf_personal, 0),
+		        COALESCE(id_conf_establecimiento, 0),
+		        inicio,
+		        fin,
+		        COALESCE(motivo, ''),
+		        COALESCE(created_by, ''),
+		        COALESCE(uid_ical, ''),
+		        ocurrencia_ical
+		   FROM ai_res.agenda_bloqueos
+		  WHERE id_conf_personal = $1
+		    AND uid_ical = ANY($2)
+		    AND fin > $3
+		  ORDER BY inicio, id
+		    FOR UPDATE`,
+		idConfPersonal,
+		pq.Array(uids),
+		desde,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying agenda_bloqueos ical: %w", err)
+	}
+
+	return scanBloqueos(rows)
+}
+
+// GetZonaConfPersonal devuelve la zona horaria de la configuración del profesional
+func (hr *AiReservesRepository) GetZonaConfPersonal(ctx context.Context, idConfPersonal int) (string, error) {
+	var zonaHoraria string
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT zona_horaria
+		   FROM ai_res.conf_personal
+		  WHERE id = $1`,
+		idConfPersonal,
+	).Scan(&zonaHoraria)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("conf_personal %d no existe", idConfPersonal)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get zona conf_personal %d: %w", idConfPersonal, err)
+	}
+
+	return zonaHoraria, nil
+}
+
+func scanBloqueos(rows *sql.Rows) ([]domain.AgendaBloqueo, error) {
+	defer rows.Close()
+
+	bloqueos := []domain.AgendaBloqueo{}
+	for rows.Next() {
+		var b domain.AgendaBloqueo
+		var ocurrencia sql.NullTime
+		if err := rows.Scan(&b.ID, &b.IDConfPersonal, &b.IDConfEstablecimiento, &b.Inicio, &b.Fin, &b.Motivo,
+			&b.CreatedBy, &b.UID, &ocurrencia); err != nil {
+			return nil, fmt.Errorf("scanning agenda_bloqueo: %w", err)
+		}
+		b.Ocurrencia = ocurrencia.Time
+		bloqueos = append(bloqueos, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating agenda_bloqueos: %w", err)
+	}
+
+	return bloqueos, nil
+}
+
+// reservasVigentesSelect lista las reservas que ocupan agenda (pendientes o confirmadas)
+const reservasVigentesSelect = `
+	SELECT r.id,
+	       r.id_agenda,
+	       r.fecha,
+	       to_char(r.hora_inicio, 'HH24:MI'),
+	       to_char(r.hora_fin, 'HH24:MI'),
+	       r.id_paciente,
+	       COALESCE(r.estado, 'PENDIENTE'),
+	       r.observaciones,
+	       r.id_sub_tipo_unidad_reserva,
+	       a.zona_horaria,
+	       r.inicio_utc,
+	       r.fin_utc
+	  FROM ai_res.reservas r
+	  JOIN ai_res.agendas a ON a.id = r.id_agenda
+	 WHERE COALESCE(r.estado, 'PENDIENTE') IN ('PENDIENTE', 'CONFIRMADA')`
+
+func scanReservas(rows *sql.Rows) ([]domain.Reserva, error) {
+	defer rows.Close()
+
+	reservas := []domain.Reserva{}
+	for rows.Next() {
+		var r domain.Reserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+		if err := rows.Scan(&r.ID, &r.IDAgenda, &r.Fecha, &r.HoraInicio, &r.HoraFin, &r.IDPaciente,
+			&r.Estado, &r.Observaciones, &r.IDSubTipoUnidadReserva, &zonaHoraria, &inicio, &fin); err != nil {
+			return nil, fmt.Errorf("scanning reserva: %w", err)
+		}
+		zonaReserva(&r, zonaHoraria, inicio, fin)
+		reservas = append(reservas, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas: %w", err)
+	}
+
+	return reservas, nil
+}
+
+// GetReservasProfesional devuelve las reservas vigentes de un profesional entre dos fechas, también
+// las de otras agendas que lo ocupan como recurso adicional
+func (hr *AiReservesRepository) GetReservasProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.Reserva, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		reservasVigentesSelect+`
+		   AND (a.id_conf_personal = $1
+		        OR EXISTS (SELECT 1
+		                     FROM ai_res.agendas_reservas ar
+		                     JOIN ai_res.agendas ra ON ra.id = ar.id_agenda
+		                    WHERE ar.id_reserva = r.id
+		                      AND ra.id_conf_personal = $1))
+		   AND r.fecha BETWEEN $2::date AND $3::date
+		 ORDER BY r.fecha, r.hora_inicio`,
+		idConfPersonal,
+		desde.Format("2006-01-02"),
+		hasta.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas profesional: %w", err)
+	}
+
+	return scanReservas(rows)
+}
+
+// GetReservasAgenda devuelve las reservas vigentes de una agenda diaria, también las que la ocupan
+// como recurso adicional
+func (hr *AiReservesRepository) GetReservasAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.Reserva, error) {
+	rows, err := tx.QueryContext(ctx,
+		reservasVigentesSelect+`
+		   AND (r.id_agenda = $1
+		        OR r.id IN (SELECT ar.id_reserva FROM ai_res.agendas_reservas ar WHERE ar.id_agenda = $1))
+		 ORDER BY r.hora_inicio`,
+		idAgenda,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas agenda %d: %w", idAgenda, err)
+	}
+
+	return scanReservas(rows)
+}
+
+// GetReservasPaciente devuelve las reservas vigentes de un paciente para un sub tipo entre dos fechas.
+// Sin transacción lee lo confirmado (búsqueda de disponibilidad).
+func (hr *AiReservesRepository) GetReservasPaciente(ctx context.Context, tx *sql.Tx, idPaciente int, idSubTipo int, desde, hasta time.Time) ([]domain.Reserva, error) {
+	query := reservasVigentesSelect + `
+		   AND r.id_paciente = $1
+		   AND r.id_sub_tipo_unidad_reserva = $2
+		   AND r.fecha BETWEEN $3::date AND $4::date
+		 ORDER BY r.fecha, r.hora_inicio`
+	args := []any{idPaciente, idSubTipo, desde.Format("2006-01-02"), hasta.Format("2006-01-02")}
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = hr.dbPost.GetDB().QueryContext(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas paciente %d: %w", idPaciente, err)
+	}
+
+	return scanReservas(rows)
+}
+
+// GetReservasVecinas devuelve las reservas vigentes del mismo profesional o espacio que quedan a menos
+// de margen de la reserva indicada (sin contarla a ella)
+func (hr *AiReservesRepository) GetReservasVecinas(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, margen time.Duration) ([]domain.Reserva, error) {
+	rows, err := tx.QueryContext(ctx,
+		reservasVigentesSelect+`
+		   AND r.id <> $1
+		   AND NOT (r.id_agenda = $2 AND r.hora_inicio = $5::time)
+		   AND EXISTS (SELECT 1
+		                 FROM ai_res.agendas propia
+		                WHERE propia.id = $2
+		                  AND (propia.id_conf_personal = a.id_conf_personal
+		                       OR propia.id_conf_establecimiento = a.id_conf_establecimiento))
+		   AND r.inicio_utc < $4
+		   AND r.fin_utc > $3
+		 ORDER BY r.inicio_utc`,
+		reserva.ID,
+		reserva.IDAgenda,
+		reserva.Inicio.Add(-margen),
+		reserva.Fin.Add(margen),
+		reserva.HoraInicio,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas vecinas de %d: %w", reserva.ID, err)
+	}
+
+	return scanReservas(rows)
+}
+
+// LockAgendaDia asegura la agenda del día de un profesional MULTIAGENDA (sin slots)
+// y la bloquea hasta el fin de la transacción
+func (hr *AiReservesRepository) LockAgendaDia(ctx context.Context, tx *sql.Tx, idConfPersonal int, fecha time.Time) (int, error) {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO ai_res.agendas (id_conf_personal, fecha, activa, created_by, zona_horaria)
+		 SELECT cp.id, $2, TRUE, 'ai_reserves', cp.zona_horaria
+		   FROM ai_res.conf_personal cp
+		  WHERE cp.id = $1
+		 ON CONFLICT DO NOTHING`,
+		idConfPersonal,
+		fecha,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert agenda %s: %w", fecha.Format("2006-01-02"), err)
+	}
+
+	var idAgenda int
+	err = tx.QueryRowContext(ctx,
+		`SELECT id
+		   FROM ai_res.agendas
+		  WHERE id_conf_personal = $1
+		    AND fecha = $2
+		    AND COALESCE(activa, TRUE)
+		    FOR UPDATE`,
+		idConfPersonal,
+		fecha,
+	).Scan(&idAgenda)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: conf_personal=%d fecha=%s", domain.ErrAgendaNotFound, idConfPersonal, fecha.Format("2006-01-02"))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("locking agenda: %w", err)
+	}
+
+	return idAgenda, nil
+}
+
+// CreateReservaSinSlot inserta una reserva de agenda MULTIAGENDA; la hora de fin ya viene calculada
+func (hr *AiReservesRepository) CreateReservaSinSlot(ctx context.Context, tx *sql.Tx, req domain.Reserva) (int, error) {
+	estado := req.Estado
+	if estado == "" {
+		estado = domain.ReservaPendiente
+	}
+
+	var newID int
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO ai_res.reservas
+			(id_agenda, fecha, hora_inicio, hora_fin, inicio_utc, fin_utc,
+			 id_sub_tipo_unidad_reserva, id_paciente, estado, observaciones,
+			 id_serie, created_by)
+		 VALUES ($1, $2, $3::time, $4::time, $5, $6, $7, $8, $9, $10, $11, 'ai_reserves')
+		 RETURNING id`,
+		req.IDAgenda,
+		req.Fecha,
+		req.HoraInicio,
+		req.HoraFin,
+		req.Inicio,
+		req.Fin,
+		req.IDSubTipoUnidadReserva,
+		req.IDPaciente,
+		estado,
+		req.Observaciones,
+		nullableInt(req.IDSerie),
+	).Scan(&newID)
+	if err != nil {
+		return 0, fmt.Errorf("insert reserva: %w", err)
+	}
+
+	fmt.Printf("📅 Reserva creada ID=%d (agenda=%d %s-%s)\n", newID, req.IDAgenda, req.HoraInicio, req.HoraFin)
+	return newID, nil
+}
+
+func (hr *AiReservesRepository) CreateSerie(ctx context.Context, tx *sql.Tx, req domain.ReservaSerie) (int, error) {
+	var id int
+
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO ai_res.reservas_series
+			(rrule, fecha_inicio, hora_inicio, id_sub_tipo_unidad_reserva, id_paciente,
+			 id_profesional, id_conf_establecimiento, estado, observaciones, created_by)
+		 VALUES ($1, $2, $3::time, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id`,
+		req.RRule,
+		req.FechaInicio,
+		req.Reserva.HoraInicio,
+		req.Reserva.IDSubTipoUnidadReserva,
+		req.Reserva.IDPaciente,
+		nullableInt(req.Reserva.IDProfesional),
+		nullableInt(req.Reserva.IDConfEstablecimiento),
+		domain.SerieActiva,
+		req.Reserva.Observaciones,
+		req.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert reservas_series: %w", err)
+	}
+
+	fmt.Printf("🔂 Serie de reservas creada ID=%d (%s)\n", id, req.RRule)
+	return id, nil
+}
+
+func (hr *AiReservesRepository) UpdEstadoSerie(ctx context.Context, tx *sql.Tx, idSerie int, estado string) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.reservas_series
+		    SET estado = $2,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		idSerie,
+		estado,
+	)
+	if err != nil {
+		return fmt.Errorf("update estado serie: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrSerieNotFound, idSerie)
+	}
+
+	return nil
+}
+
+// GetReservasSerie devuelve las reservas vigentes de la serie desde una fecha (y hasta otra, si no es cero)
+func (hr *AiReservesRepository) GetReservasSerie(ctx context.Context, tx *sql.Tx, idSerie int, desde, hasta time.Time) ([]domain.Reserva, error) {
+	var hastaParam sql.NullString
+	if !hasta.IsZero() {
+		hastaParam = nullableString(hasta.Format("2006-01-02"))
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		reservasVigentesSelect+`
+		   AND r.id_serie = $1
+		   AND r.fecha >= $2::date
+		   AND ($3::date IS NULL OR r.fecha <= $3::date)
+		 ORDER BY r.fecha, r.hora_inicio`,
+		idSerie,
+		desde.Format("2006-01-02"),
+		hastaParam,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas serie %d: %w", idSerie, err)
+	}
+
+	return scanReservas(rows)
+}
+
+func (hr *AiReservesRepository) CreateListaEspera(ctx context.Context, req domain.ListaEspera) (int, error) {
+	var id int
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`INSERT INTO ai_res.lista_espera
+			(id_persona, id_conf_personal, id_conf_establecimiento, id_sub_tipo_unidad_reserva,
+			 fecha_desde, fecha_hasta, hora_desde, hora_hasta, estado, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7::time, $8::time, $9, 'ai_reserves')
+		 RETURNING id`,
+		req.IDPersona,
+		nullableInt(req.IDConfPersonal),
+		nullableInt(req.IDConfEstablecimiento),
+		req.IDSubTipoUnidadReserva,
+		req.FechaDesde,
+		req.FechaHasta,
+		nullableString(req.HoraDesde),
+		nullableString(req.HoraHasta),
+		domain.ListaEsperaEsperando,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert lista_espera: %w", err)
+	}
+
+	fmt.Printf("⏳ Persona %d anotada en lista de espera ID=%d\n", req.IDPersona, id)
+	return id, nil
+}
+
+const listaEsperaSelect = `
+	SELECT le.id,
+	       le.id_persona,
+	       COALESCE(cp.id_persona, 0),
+	       COALESCE(le.id_conf_personal, 0),
+	       COALESCE(le.id_conf_establecimiento, 0),
+	       le.id_sub_tipo_unidad_reserva,
+	       le.fecha_desde,
+	       le.fecha_hasta,
+	       COALESCE(to_char(le.hora_desde, 'HH24:MI'), ''),
+	       COALESCE(to_char(le.hora_hasta, 'HH24:MI'), ''),
+	       le.estado,
+	       le.created_at
+	  FROM ai_res.lista_espera le
+	  LEFT JOIN ai_res.conf_personal cp ON cp.id = le.id_conf_personal`
+
+func scanListaEspera(row interface{ Scan(...any) error }) (domain.ListaEspera, error) {
+	var le domain.ListaEspera
+	err := row.Scan(&le.ID, &le.IDPersona, &le.IDProfesional, &le.IDConfPersonal, &le.IDConfEstablecimiento,
+		&le.IDSubTipoUnidadReserva, &le.FechaDesde, &le.FechaHasta, &le.HoraDesde, &le.HoraHasta,
+		&le.Estado, &le.CreatedAt)
+	return le, err
+}
+
+func (hr *AiReservesRepository) GetListaEspera(ctx context.Context, req domain.ListaEsperaFiltro) ([]domain.ListaEspera, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		listaEsperaSelect+`
+		 WHERE ($1 = 0 OR le.id_conf_personal = $1)
+		   AND ($2 = 0 OR le.id_conf_establecimiento = $2)
+		   AND ($3 = 0 OR le.id_sub_tipo_unidad_reserva = $3)
+		   AND ($4 = '' OR le.estado = $4)
+		   AND ($5 = 0 OR le.id_persona = $5)
+		 ORDER BY le.created_at, le.id`,
+		req.IDConfPersonal,
+		req.IDConfEstablecimiento,
+		req.IDSubTipoUnidadReserva,
+		req.Estado,
+		req.IDPersona,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying lista_espera: %w", err)
+	}
+	defer rows.Close()
+
+	lista := []domain.ListaEspera{}
+	for rows.Next() {
+		le, err := scanListaEspera(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning lista_espera: %w", err)
+		}
+		lista = append(lista, le)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lista_espera: %w", err)
+	}
+
+	return lista, nil
+}
+
+// GetListaEsperaForUpdate lee la entrada bloqueándola hasta el fin de la transacción
+func (hr *AiReservesRepository) GetListaEsperaForUpdate(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.ListaEspera, error) {
+	le, err := scanListaEspera(tx.QueryRowContext(ctx,
+		listaEsperaSelect+`
+		 WHERE le.id = $1
+		   FOR UPDATE OF le`,
+		idListaEspera,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ListaEspera{}, fmt.Errorf("%w: id=%d", domain.ErrListaEsperaNotFound, idListaEspera)
+	}
+	if err != nil {
+		return domain.ListaEspera{}, fmt.Errorf("get lista_espera for update: %w", err)
+	}
+
+	return le, nil
+}
+
+// GetListaEsperaPersona bloquea las entradas vigentes de la persona: primero las que están en espera,
+// así al soltar sus ofertas el turno no vuelve a caer en otra entrada de la misma persona
+func (hr *AiReservesRepository) GetListaEsperaPersona(ctx context.Context, tx *sql.Tx, idPersona int) ([]domain.ListaEspera, error) {
+	rows, err := tx.QueryContext(ctx,
+		listaEsperaSelect+`
+		 WHERE le.id_persona = $1
+		   AND le.estado IN ($2, $3)
+		 ORDER BY le.estado = $3, le.id
+		   FOR UPDATE OF le`,
+		idPersona,
+		domain.ListaEsperaEsperando,
+		domain.ListaEsperaOfrecido,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query lista_espera persona %d: %w", idPersona, err)
+	}
+	defer rows.Close()
+
+	var lista []domain.ListaEspera
+	for rows.Next() {
+		le, err := scanListaEspera(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan lista_espera: %w", err)
+		}
+		lista = append(lista, le)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lista_espera: %w", err)
+	}
+
+	return lista, nil
+}
+
+// NextListaEspera toma (y bloquea) la primera entrada en espera que acepta el turno liberado
+// y a la que todavía no se le ofreció ese mismo turno
+func (hr *AiReservesRepository) NextListaEspera(ctx context.Context, tx *sql.Tx, turno domain.TurnoLiberado) (domain.ListaEspera, error) {
+	le, err := scanListaEspera(tx.QueryRowContext(ctx,
+		listaEsperaSelect+`
+		 WHERE le.estado = $1
+		   AND le.id_sub_tipo_unidad_reserva = $2
+		   AND (le.id_conf_personal IS NULL OR le.id_conf_personal = $3)
+		   AND (le.id_conf_establecimiento IS NULL OR le.id_conf_establecimiento = $4)
+		   AND $5::date BETWEEN le.fecha_desde AND le.fecha_hasta
+		   AND (le.hora_desde IS NULL OR $6::time >= le.hora_desde)
+		   AND (le.hora_hasta IS NULL OR $7::time <= le.hora_hasta)
+		   AND NOT EXISTS (
+		        SELECT 1
+		          FROM ai_res.lista_espera_ofertas o
+		         WHERE o.id_lista_espera = le.id
+		           AND o.id_agenda = $8
+		           AND o.hora_inicio = $6::time)
+		 ORDER BY le.created_at, le.id
+		 LIMIT 1
+		   FOR UPDATE OF le SKIP LOCKED`,
+		domain.ListaEsperaEsperando,
+		turno.IDSubTipoUnidadReserva,
+		nullableInt(turno.IDConfPersonal),
+		nullableInt(turno.IDConfEstablecimiento),
+		turno.Fecha.Format("2006-01-02"),
+		turno.HoraInicio,
+		turno.HoraFin,
+		turno.IDAgenda,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ListaEspera{}, domain.ErrListaEsperaNotFound
+	}
+	if err != nil {
+		return domain.ListaEspera{}, fmt.Errorf("next lista_espera: %w", err)
+	}
+
+	return le, nil
+}
+
+func (hr *AiReservesRepository) UpdEstadoListaEspera(ctx context.Context, tx *sql.Tx, idListaEspera int, estado string) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.lista_espera
+		    SET estado = $2,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		idListaEspera,
+		estado,
+	)
+	if err != nil {
+		return fmt.Errorf("update estado lista_espera: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrListaEsperaNotFound, idListaEspera)
+	}
+
+	return nil
+}
+
+// GetTurnoReserva arma el turno que deja libre una reserva: agenda, slot (si la agenda los tiene) y dueño de la agenda
+func (hr *AiReservesRepository) GetTurnoReserva(ctx context.Context, tx *sql.Tx, reserva domain.Reserva) (domain.TurnoLiberado, error) {
+	turno := domain.TurnoLiberado{
+		IDAgenda:               reserva.IDAgenda,
+		IDSubTipoUnidadReserva: reserva.IDSubTipoUnidadReserva,
+		Fecha:                  reserva.Fecha,
+		HoraInicio:             reserva.HoraInicio,
+		HoraFin:                reserva.HoraFin,
+	}
+
+	err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(s.id, 0),
+		        COALESCE(cp.id_persona, 0),
+		        COALESCE(a.id_conf_personal, 0),
+		        COALESCE(a.id_conf_establecimiento, 0),
+		        a.zona_horaria
+		   FROM ai_res.agendas a
+		   LEFT JOIN ai_res.agenda_slots s ON s.id_agenda = a.id AND s.hora_inicio = $2::time
+		   LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		  WHERE a.id = $1`,
+		reserva.IDAgenda,
+		reserva.HoraInicio,
+	).Scan(&turno.IDSlot, &turno.IDProfesional, &turno.IDConfPersonal, &turno.IDConfEstablecimiento, &turno.ZonaHoraria)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.TurnoLiberado{}, fmt.Errorf("%w: id=%d", domain.ErrAgendaNotFound, reserva.IDAgenda)
+	}
+	if err != nil {
+		return domain.TurnoLiberado{}, fmt.Errorf("get turno reserva %d: %w", reserva.ID, err)
+	}
+
+	return turno, nil
+}
+
+// RetenerSlot reserva el slot para una oferta de lista de espera; devuelve false si el slot
+// ya no está LIBRE (p. ej. quedó dentro de un bloqueo)
+func (hr *AiReservesRepository) RetenerSlot(ctx context.Context, tx *sql.Tx, idSlot int, hasta time.Time) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET estado = $2,
+		        retenido_hasta = $3,
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		    AND COALESCE(estado, 'LIBRE') = $4`,
+		idSlot,
+		domain.SlotRetenido,
+		hasta,
+		domain.SlotLibre,
+	)
+	if err != nil {
+		return false, fmt.Errorf("retaining agenda_slot %d: %w", idSlot, err)
+	}
+
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+// LiberarSlotRetenido devuelve a LIBRE (o a BLOQUEADO) un slot retenido por una oferta
+func (hr *AiReservesRepository) LiberarSlotRetenido(ctx context.Context, tx *sql.Tx, idSlot int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET estado = CASE WHEN id_bloqueo IS NOT NULL THEN $3 ELSE $2 END,
+		        retenido_hasta = NULL,
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		    AND estado = $4`,
+		idSlot,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+		domain.SlotRetenido,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing retained agenda_slot %d: %w", idSlot, err)
+	}
+
+	return nil
+}
+
+func (hr *AiReservesRepository) CreateOferta(ctx context.Context, tx *sql.Tx, req domain.OfertaListaEspera) (int, error) {
+	var id int
+
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO ai_res.lista_espera_ofertas
+			(id_lista_espera, id_agenda, id_slot, id_conf_personal, id_conf_establecimiento,
+			 id_sub_tipo_unidad_reserva, fecha, hora_inicio, hora_fin, estado, vence_en)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8::time, $9::time, $10, $11)
+		 RETURNING id`,
+		req.IDListaEspera,
+		req.Turno.IDAgenda,
+		nullableInt(req.Turno.IDSlot),
+		nullableInt(req.Turno.IDConfPersonal),
+		nullableInt(req.Turno.IDConfEstablecimiento),
+		req.Turno.IDSubTipoUnidadReserva,
+		req.Turno.Fecha,
+		req.Turno.HoraInicio,
+		req.Turno.HoraFin,
+		domain.OfertaPendiente,
+		req.VenceEn,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert lista_espera_ofertas: %w", err)
+	}
+
+	fmt.Printf("🎟️ Oferta ID=%d a lista de espera %d (agenda=%d %s)\n", id, req.IDListaEspera, req.Turno.IDAgenda, req.Turno.HoraInicio)
+	return id, nil
+}
+
+const ofertaSelect = `
+	SELECT o.id,
+	       o.id_lista_espera,
+	       le.id_persona,
+	       o.id_agenda,
+	       COALESCE(o.id_slot, 0),
+	       COALESCE(cp.id_persona, 0),
+	       COALESCE(o.id_conf_personal, 0),
+	       COALESCE(o.id_conf_establecimiento, 0),
+	       o.id_sub_tipo_unidad_reserva,
+	       o.fecha,
+	       to_char(o.hora_inicio, 'HH24:MI'),
+	       to_char(o.hora_fin, 'HH24:MI'),
+	       o.estado,
+	       o.vence_en,
+	       a.zona_horaria
+	  FROM ai_res.lista_espera_ofertas o
+	  JOIN ai_res.lista_espera le ON le.id = o.id_lista_espera
+	  JOIN ai_res.agendas a ON a.id = o.id_agenda
+	  LEFT JOIN ai_res.conf_personal cp ON cp.id = o.id_conf_personal`
+
+func scanOferta(row interface{ Scan(...any) error }) (domain.OfertaListaEspera, error) {
+	var o domain.OfertaListaEspera
+	err := row.Scan(&o.ID, &o.IDListaEspera, &o.IDPersona, &o.Turno.IDAgenda, &o.Turno.IDSlot,
+		&o.Turno.IDProfesional, &o.Turno.IDConfPersonal, &o.Turno.IDConfEstablecimiento,
+		&o.Turno.IDSubTipoUnidadReserva, &o.Turno.Fecha, &o.Turno.HoraInicio, &o.Turno.HoraFin,
+		&o.Estado, &o.VenceEn, &o.Turno.ZonaHoraria)
+	return o, err
+}
+
+func scanOfertas(rows *sql.Rows) ([]domain.OfertaListaEspera, error) {
+	defer rows.Close()
+
+	ofertas := []domain.OfertaListaEspera{}
+	for rows.Next() {
+		o, err := scanOferta(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning lista_espera_oferta: %w", err)
+		}
+		ofertas = append(ofertas, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lista_espera_ofertas: %w", err)
+	}
+
+	return ofertas, nil
+}
+
+// GetOfertaForUpdate lee la oferta bloqueándola hasta el fin de la transacción
+func (hr *AiReservesRepository) GetOfertaForUpdate(ctx context.Context, tx *sql.Tx, idOferta int) (domain.OfertaListaEspera, error) {
+	o, err := scanOferta(tx.QueryRowContext(ctx,
+		ofertaSelect+`
+		 WHERE o.id = $1
+		   FOR UPDATE OF o`,
+		idOferta,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.OfertaListaEspera{}, fmt.Errorf("%w: id=%d", domain.ErrOfertaNotFound, idOferta)
+	}
+	if err != nil {
+		return domain.OfertaListaEspera{}, fmt.Errorf("get oferta for update: %w", err)
+	}
+
+	return o, nil
+}
+
+// GetOfertaPendiente devuelve la oferta sin responder de una entrada de la lista de espera
+func (hr *AiReservesRepository) GetOfertaPendiente(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.OfertaListaEspera, error) {
+	o, err := scanOferta(tx.QueryRowContext(ctx,
+		ofertaSelect+`
+		 WHERE o.id_lista_espera = $1
+		   AND o.estado = $2
+		 ORDER BY o.id DESC
+		 LIMIT 1
+		   FOR UPDATE OF o`,
+		idListaEspera,
+		domain.OfertaPendiente,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.OfertaListaEspera{}, fmt.Errorf("%w: lista_espera=%d", domain.ErrOfertaNotFound, idListaEspera)
+	}
+	if err != nil {
+		return domain.OfertaListaEspera{}, fmt.Errorf("get oferta pendiente: %w", err)
+	}
+
+	return o, nil
+}
+
+func (hr *AiReservesRepository) UpdEstadoOferta(ctx context.Context, tx *sql.Tx, idOferta int, estado string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.lista_espera_ofertas
+		    SET estado = $2,
+		        respondido_en = CURRENT_TIMESTAMP
+		  WHERE id = $1`,
+		idOferta,
+		estado,
+	)
+	if err != nil {
+		return fmt.Errorf("update estado oferta: %w", err)
+	}
+
+	return nil
+}
+
+// GetOfertasVencidas toma un lote de ofertas pendientes cuyo plazo ya pasó; SKIP LOCKED
+// permite que varias instancias barran en paralelo sin pisarse
+func (hr *AiReservesRepository) GetOfertasVencidas(ctx context.Context, tx *sql.Tx, limite int) ([]domain.OfertaListaEspera, error) {
+	rows, err := tx.QueryContext(ctx,
+		ofertaSelect+`
+		 WHERE o.estado = $1
+		   AND o.vence_en < CURRENT_TIMESTAMP
+		 ORDER BY o.vence_en
+		 LIMIT $2
+		   FOR UPDATE OF o SKIP LOCKED`,
+		domain.OfertaPendiente,
+		limite,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying ofertas vencidas: %w", err)
+	}
+
+	return scanOfertas(rows)
+}
+
+// GetOfertasVigentesProfesional devuelve las ofertas pendientes sobre agendas MULTIAGENDA del profesional:
+// esos turnos están retenidos aunque no tengan slot
+func (hr *AiReservesRepository) GetOfertasVigentesProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.OfertaListaEspera, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		ofertaSelect+`
+		 WHERE o.estado = $1
+		   AND o.id_slot IS NULL
+		   AND o.id_conf_personal = $2
+		   AND o.fecha BETWEEN $3::date AND $4::date
+		 ORDER BY o.fecha, o.hora_inicio`,
+		domain.OfertaPendiente,
+		idConfPersonal,
+		desde.Format("2006-01-02"),
+		hasta.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying ofertas vigentes profesional: %w", err)
+	}
+
+	return scanOfertas(rows)
+}
+
+// GetOfertasVigentesAgenda devuelve las ofertas pendientes sin slot de una agenda diaria
+func (hr *AiReservesRepository) GetOfertasVigentesAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.OfertaListaEspera, error) {
+	rows, err := tx.QueryContext(ctx,
+		ofertaSelect+`
+		 WHERE o.estado = $1
+		   AND o.id_slot IS NULL
+		   AND o.id_agenda = $2
+		 ORDER BY o.hora_inicio`,
+		domain.OfertaPendiente,
+		idAgenda,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying ofertas vigentes agenda %d: %w", idAgenda, err)
+	}
+
+	return scanOfertas(rows)
+}
+
+// HoldSlot retiene un slot libre para un checkout: queda RETENIDO con el token hasta que
+// se confirma la reserva, se libera o vence
+func (hr *AiReservesRepository) HoldSlot(ctx context.Context, tx *sql.Tx, req domain.RetencionSlot) (domain.RetencionSlot, error) {
+	slot, err := hr.lockSlot(ctx, tx, req.IDAgenda, req.HoraInicio)
+	if err != nil {
+		return domain.RetencionSlot{}, err
+	}
+
+	if err := slot.disponible(req.IDAgenda, req.HoraInicio); err != nil {
+		return domain.RetencionSlot{}, err
+	}
+	// Un slot compartido admite reservas junto a una retención, pero una sola retención a la vez
+	if slot.compartido("") {
+		return domain.RetencionSlot{}, fmt.Errorf("%w: agenda %d hora %s", domain.ErrSlotRetenido, req.IDAgenda, req.HoraInicio)
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET estado = $2,
+		        token_retencion = $3,
+		        retenido_hasta = CURRENT_TIMESTAMP + make_interval(mins => $4),
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		 RETURNING retenido_hasta`,
+		slot.id,
+		domain.SlotRetenido,
+		req.Token,
+		req.Minutos,
+	).Scan(&req.RetenidoHasta)
+	if err != nil {
+		return domain.RetencionSlot{}, fmt.Errorf("holding agenda_slot %d: %w", slot.id, err)
+	}
+
+	req.IDSlot = slot.id
+	fmt.Printf("🔒 Slot %d retenido hasta %s\n", slot.id, req.RetenidoHasta.Format("15:04:05"))
+	return req, nil
+}
+
+// ReleaseSlotHold libera la retención de un checkout abandonado
+func (hr *AiReservesRepository) ReleaseSlotHold(ctx context.Context, token string) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET estado = CASE WHEN id_bloqueo IS NOT NULL THEN $3 ELSE $2 END,
+		        token_retencion = NULL,
+		        retenido_hasta = NULL,
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE token_retencion = $1
+		    AND estado = $4`,
+		token,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+		domain.SlotRetenido,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing slot hold: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return domain.ErrRetencionInvalida
+	}
+
+	return nil
+}
+
+// ReleaseExpiredSlotHolds libera las retenciones de checkout vencidas; las de la lista de espera
+// (sin token) las maneja el vencimiento de la oferta
+func (hr *AiReservesRepository) ReleaseExpiredSlotHolds(ctx context.Context) (int, error) {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.agenda_slots
+		    SET estado = CASE WHEN id_bloqueo IS NOT NULL THEN $2 ELSE $1 END,
+		        token_retencion = NULL,
+		        retenido_hasta = NULL,
+		        updated_at = CURRENT_TIMESTAMP
+		  WHERE estado = $3
+		    AND token_retencion IS NOT NULL
+		    AND retenido_hasta < CURRENT_TIMESTAMP`,
+		domain.SlotLibre,
+		domain.SlotBloqueado,
+		domain.SlotRetenido,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("releasing expired slot holds: %w", err)
+	}
+
+	rows, _ := res.RowsAffected()
+	return int(rows), nil
+}
+
+// UpdRecordatoriosSubTipo reemplaza las horas de recordatorio del sub tipo (ordenadas, sin repetir)
+func (hr *AiReservesRepository) UpdRecordatoriosSubTipo(ctx context.Context, req domain.RecordatoriosConfig) (domain.RecordatoriosConfig, error) {
+	var horas pq.Int64Array
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`UPDATE ai_res.sub_tipo_unidad_reserva
+		    SET recordatorios_horas = ARRAY(SELECT DISTINCT h FROM unnest($2::int[]) AS h ORDER BY h DESC),
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1
+		 RETURNING recordatorios_horas`,
+		req.IDSubTipoUnidadReserva,
+		pq.Array(req.HorasAntes),
+	).Scan(&horas)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.RecordatoriosConfig{}, fmt.Errorf("sub_tipo_unidad_reserva %d no existe", req.IDSubTipoUnidadReserva)
+	}
+	if err != nil {
+		return domain.RecordatoriosConfig{}, fmt.Errorf("update recordatorios sub_tipo_unidad_reserva: %w", err)
+	}
+
+	resp := domain.RecordatoriosConfig{IDSubTipoUnidadReserva: req.IDSubTipoUnidadReserva, HorasAntes: []int{}}
+	for _, h := range horas {
+		resp.HorasAntes = append(resp.HorasAntes, int(h))
+	}
+	return resp, nil
+}
+
+// ClaimRecordatorios registra como enviados los recordatorios que ya tocan (reservas confirmadas que
+// empiezan dentro de las horas configuradas en su sub tipo) y devuelve esas reservas. El UNIQUE
+// (id_reserva, horas_antes) hace que cada recordatorio lo tome una sola instancia del scheduler.
+func (hr *AiReservesRepository) ClaimRecordatorios(ctx context.Context, tx *sql.Tx, limite int) ([]domain.RecordatorioReserva, error) {
+	rows, err := tx.QueryContext(ctx,
+		`WITH pendientes AS (
+			SELECT r.id AS id_reserva, h.horas
+			  FROM ai_res.reservas r
+			  JOIN ai_res.sub_tipo_unidad_reserva st ON st.id = r.id_sub_tipo_unidad_reserva
+			 CROSS JOIN LATERAL unnest(st.recordatorios_horas) AS h(horas)
+			 WHERE r.estado = $1
+			   AND r.inicio_utc > CURRENT_TIMESTAMP
+			   AND r.inicio_utc <= CURRENT_TIMESTAMP + make_interval(hours => h.horas)
+			   AND NOT EXISTS (SELECT 1
+			                     FROM ai_res.reservas_recordatorios rr
+			                    WHERE rr.id_reserva = r.id
+			                      AND rr.horas_antes = h.horas)
+			 ORDER BY r.inicio_utc
+			 LIMIT $2
+		), enviados AS (
+			INSERT INTO ai_res.reservas_recordatorios (id_reserva, horas_antes)
+			SELECT id_reserva, horas
+			  FROM pendientes
+			    ON CONFLICT (id_reserva, horas_antes) DO NOTHING
+			RETURNING id_reserva, horas_antes
+		)
+		SELECT r.id,
+		       r.id_agenda,
+		       r.fecha,
+		       to_char(r.hora_inicio, 'HH24:MI'),
+		       to_char(r.hora_fin, 'HH24:MI'),
+		       r.id_paciente,
+		       COALESCE(r.estado, 'PENDIENTE'),
+		       r.id_sub_tipo_unidad_reserva,
+		       COALESCE(cp.id_persona, 0),
+		       COALESCE(a.id_conf_establecimiento, 0),
+		       a.zona_horaria,
+		       r.inicio_utc,
+		       r.fin_utc,
+		       MIN(e.horas_antes)
+		  FROM enviados e
+		  JOIN ai_res.reservas r ON r.id = e.id_reserva
+		  JOIN ai_res.agendas a ON a.id = r.id_agenda
+		  LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		 GROUP BY r.id, a.id, cp.id
+		 ORDER BY r.inicio_utc`,
+		domain.ReservaConfirmada,
+		limite,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming recordatorios: %w", err)
+	}
+	defer rows.Close()
+
+	var recordatorios []domain.RecordatorioReserva
+	for rows.Next() {
+		var rec domain.RecordatorioReserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+		r := &rec.Reserva
+		if err := rows.Scan(&r.ID, &r.IDAgenda, &r.Fecha, &r.HoraInicio, &r.HoraFin, &r.IDPaciente, &r.Estado,
+			&r.IDSubTipoUnidadReserva, &r.IDProfesional, &r.IDConfEstablecimiento,
+			&zonaHoraria, &inicio, &fin, &rec.HorasAntes); err != nil {
+			return nil, fmt.Errorf("scanning recordatorio: %w", err)
+		}
+		zonaReserva(r, zonaHoraria, inicio, fin)
+		recordatorios = append(recordatorios, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating recordatorios: %w", err)
+	}
+
+	return recordatorios, nil
+}
+
+// ResetRecordatorios olvida los recordatorios enviados de la reserva (p. ej. al reprogramarla)
+func (hr *AiReservesRepository) ResetRecordatorios(ctx context.Context, tx *sql.Tx, idReserva int) error {
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM ai_res.reservas_recordatorios WHERE id_reserva = $1`,
+		idReserva,
+	)
+	if err != nil {
+		return fmt.Errorf("reset recordatorios reserva %d: %w", idReserva, err)
+	}
+
+	return nil
+}
+
+// MoveReserve mueve la reserva a un slot de agenda pregenerada: libera el slot anterior, ocupa
+// el nuevo y actualiza agenda, fecha y horario conservando el id y el resto de los datos.
+// Devuelve el destino con la fecha y la hora de fin del slot.
+func (hr *AiReservesRepository) MoveReserve(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) (domain.Reserva, error) {
+
+	// 1️⃣ Liberar el slot que ocupaba
+	if err := hr.releaseSlots(ctx, tx, idReserva); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	// 2️⃣ Bloquear y validar el slot destino
+	slot, err := hr.lockSlot(ctx, tx, destino.IDAgenda, destino.HoraInicio)
+	if err != nil {
+		return domain.Reserva{}, err
+	}
+	if err := slot.tomable(destino); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	if !destino.Fecha.IsZero() && destino.Fecha.Format("2006-01-02") != slot.fecha.Format("2006-01-02") {
+		return domain.Reserva{}, fmt.Errorf("fecha %s no corresponde a la agenda %d (%s)",
+			destino.Fecha.Format("2006-01-02"), destino.IDAgenda, slot.fecha.Format("2006-01-02"))
+	}
+	destino.Fecha = slot.fecha
+	destino.HoraFin = slot.horaFin
+	destino.Inicio = slot.inicio.Time
+	destino.Fin = slot.fin.Time
+
+	// 3️⃣ Ocupar el slot nuevo con la misma reserva
+	if err := hr.claimSlot(ctx, tx, slot, idReserva, destino.TokenRetencion); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	if err := hr.updTurnoReserva(ctx, tx, idReserva, destino); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	fmt.Printf("🔀 Reserva ID=%d movida a agenda=%d slot=%d\n", idReserva, destino.IDAgenda, slot.id)
+	return destino, nil
+}
+
+// MoveReservaSinSlot mueve la reserva a un turno MULTIAGENDA ya validado (la hora de fin viene calculada)
+func (hr *AiReservesRepository) MoveReservaSinSlot(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) error {
+	if err := hr.releaseSlots(ctx, tx, idReserva); err != nil {
+		return err
+	}
+
+	if err := hr.updTurnoReserva(ctx, tx, idReserva, destino); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔀 Reserva ID=%d movida a agenda=%d %s-%s\n", idReserva, destino.IDAgenda, destino.HoraInicio, destino.HoraFin)
+	return nil
+}
+
+func (hr *AiReservesRepository) updTurnoReserva(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) error {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.reservas
+		    SET id_agenda = $2,
+		        fecha = $3,
+		        hora_inicio = $4::time,
+		        hora_fin = $5::time,
+		        inicio_utc = $6,
+		        fin_utc = $7,
+		        updated_at = CURRENT_TIMESTAMP,
+		        updated_by = 'ai_reserves'
+		  WHERE id = $1`,
+		idReserva,
+		destino.IDAgenda,
+		destino.Fecha,
+		destino.HoraInicio,
+		destino.HoraFin,
+		destino.Inicio,
+		destino.Fin,
+	)
+	if err != nil {
+		return fmt.Errorf("update turno reserva: %w", err)
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: id=%d", domain.ErrReservaNotFound, idReserva)
+	}
+
+	return nil
+}
+
+// OcuparRecurso toma, para la reserva ya grabada, todos los slots del recurso (otro profesional o un
+// espacio) que se superponen con su horario. Los slots se bloquean en orden de inicio; tienen que cubrir
+// el turno completo y estar disponibles, si no la transacción entera vuelve atrás.
+func (hr *AiReservesRepository) OcuparRecurso(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, recurso domain.RecursoReserva) error {
+
+	// 1️⃣ Bloquear los slots del recurso dentro del horario de la reserva, siempre por id: dos reservas
+	// que se cruzan recursos los toman en el mismo orden y no se traban entre sí
+	rows, err := tx.QueryContext(ctx,
+		`SELECT s.id,
+		        s.id_agenda,
+		        COALESCE(s.estado, 'LIBRE'),
+		        s.inicio_utc,
+		        s.fin_utc,
+		        COALESCE(s.token_retencion, ''),
+		        (s.estado = $6 AND s.token_retencion IS NOT NULL AND s.retenido_hasta < CURRENT_TIMESTAMP),
+		        s.capacidad,
+		        s.ocupados
+		   FROM ai_res.agenda_slots s
+		   JOIN ai_res.agendas a ON a.id = s.id_agenda
+		   LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		  WHERE (cp.id_persona = $1 OR a.id_conf_establecimiento = $2)
+		    AND COALESCE(a.activa, TRUE)
+		    AND s.id_agenda <> $5
+		    AND s.inicio_utc < $4
+		    AND s.fin_utc > $3
+		  ORDER BY s.id
+		    FOR UPDATE OF s`,
+		recurso.IDProfesional,
+		recurso.IDConfEstablecimiento,
+		reserva.Inicio,
+		reserva.Fin,
+		reserva.IDAgenda,
+		domain.SlotRetenido,
+	)
+	if err != nil {
+		return fmt.Errorf("locking slots del recurso: %w", err)
+	}
+
+	var slots []slotBloqueado
+	var agendas []int
+	for rows.Next() {
+		var sl slotBloqueado
+		var idAgenda int
+		if err := rows.Scan(&sl.id, &idAgenda, &sl.estado, &sl.inicio, &sl.fin, &sl.token,
+			&sl.retencionVencida, &sl.capacidad, &sl.ocupados); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning slot del recurso: %w", err)
 		}
-		castValue = t
+		slots = append(slots, sl)
+		agendas = appendAgenda(agendas, idAgenda)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating slots del recurso: %w", err)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].inicio.Time.Before(slots[j].inicio.Time) })
 
-	case "string":
-		castValue = req.Value
+	// 2️⃣ Los slots tienen que cubrir el turno sin huecos
+	cubierto := reserva.Inicio
+	for _, sl := range slots {
+		if sl.inicio.Time.After(cubierto) {
+			break
+		}
+		if sl.fin.Time.After(cubierto) {
+			cubierto = sl.fin.Time
+		}
+	}
+	if len(slots) == 0 || cubierto.Before(reserva.Fin) {
+		return fmt.Errorf("%w: el recurso %s no tiene agenda para todo el turno %s %s-%s",
+			domain.ErrSlotNotFound, descRecurso(recurso), reserva.Fecha.Format("2006-01-02"), reserva.HoraInicio, reserva.HoraFin)
+	}
 
-	default:
-		return fmt.Errorf("unsupported type '%s' for field '%s'", colType, req.Atribute)
+	// 3️⃣ Ocupar un lugar de cada slot
+	for _, sl := range slots {
+		if err := sl.disponible(reserva.IDAgenda, reserva.HoraInicio); err != nil {
+			return fmt.Errorf("recurso %s: %w", descRecurso(recurso), err)
+		}
+		if err := hr.claimSlot(ctx, tx, sl, reserva.ID, ""); err != nil {
+			return fmt.Errorf("recurso %s: %w", descRecurso(recurso), err)
+		}
 	}
 
-	// 4) UPDATE dinámico seguro
-	queryUpdate := fmt.Sprintf(`
-		UPDATE ai_res.conf_establecimiento
-		   SET %s = $1,
-		       updated_at = CURRENT_TIMESTAMP,
-		       updated_by = 'auth_security'
-		 WHERE id_persona = $2
-		   AND id_sub_tipo_unidad_reserva = $3
-	`, req.Atribute)
+	// 4️⃣ Registrar las agendas del recurso que usa la reserva
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO ai_res.agendas_reservas (id_agenda, id_reserva, created_by)
+		 SELECT unnest($1::int[]), $2, 'ai_reserves'
+		 ON CONFLICT (id_agenda, id_reserva) DO NOTHING`,
+		pq.Array(agendas),
+		reserva.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("insert agendas_reservas reserva %d: %w", reserva.ID, err)
+	}
 
-	_, err = hr.dbPost.GetDB().ExecContext(ctx, queryUpdate,
-		castValue,
+	fmt.Printf("🧩 Reserva ID=%d ocupa %d slots del recurso %s\n", reserva.ID, len(slots), descRecurso(recurso))
+	return nil
+}
+
+func appendAgenda(agendas []int, idAgenda int) []int {
+	for _, id := range agendas {
+		if id == idAgenda {
+			return agendas
+		}
+	}
+	return append(agendas, idAgenda)
+}
+
+// RegistrarAgendaRecurso anota que la reserva ocupa la agenda MULTIAGENDA de un recurso adicional:
+// sin slots que marcar, la ocupación del recurso sale de agendas_reservas
+func (hr *AiReservesRepository) RegistrarAgendaRecurso(ctx context.Context, tx *sql.Tx, idAgenda, idReserva int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO ai_res.agendas_reservas (id_agenda, id_reserva, created_by)
+		 VALUES ($1, $2, 'ai_reserves')
+		 ON CONFLICT (id_agenda, id_reserva) DO NOTHING`,
+		idAgenda,
+		idReserva,
+	)
+	if err != nil {
+		return fmt.Errorf("insert agendas_reservas reserva %d: %w", idReserva, err)
+	}
+
+	return nil
+}
+
+func descRecurso(r domain.RecursoReserva) string {
+	if r.IDConfEstablecimiento != 0 {
+		return fmt.Sprintf("conf_establecimiento %d", r.IDConfEstablecimiento)
+	}
+	return fmt.Sprintf("profesional %d", r.IDProfesional)
+}
+
+// GetRecursosReserva devuelve los recursos adicionales que ocupa la reserva
+func (hr *AiReservesRepository) GetRecursosReserva(ctx context.Context, tx *sql.Tx, idReserva int) ([]domain.RecursoReserva, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT DISTINCT COALESCE(cp.id_persona, 0),
+		        COALESCE(a.id_conf_establecimiento, 0)
+		   FROM ai_res.agendas_reservas ar
+		   JOIN ai_res.agendas a ON a.id = ar.id_agenda
+		   LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		  WHERE ar.id_reserva = $1`,
+		idReserva,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying recursos de reserva %d: %w", idReserva, err)
+	}
+	defer rows.Close()
+
+	var recursos []domain.RecursoReserva
+	for rows.Next() {
+		var r domain.RecursoReserva
+		if err := rows.Scan(&r.IDProfesional, &r.IDConfEstablecimiento); err != nil {
+			return nil, fmt.Errorf("scanning recurso de reserva: %w", err)
+		}
+		recursos = append(recursos, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating recursos de reserva: %w", err)
+	}
+
+	return recursos, nil
+}
+
+// GetSlotsLibresRecurso devuelve los slots con lugar del recurso que empiezan en [desde, hasta)
+func (hr *AiReservesRepository) GetSlotsLibresRecurso(ctx context.Context, recurso domain.RecursoReserva, desde, hasta time.Time) ([]domain.AgendaSlot, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT s.id,
+		        s.id_agenda,
+		        to_char(s.hora_inicio, 'HH24:MI'),
+		        to_char(s.hora_fin, 'HH24:MI'),
+		        s.inicio_utc,
+		        s.fin_utc,
+		        COALESCE(s.estado, 'LIBRE')
+		   FROM ai_res.agenda_slots s
+		   JOIN ai_res.agendas a ON a.id = s.id_agenda
+		   LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		  WHERE (cp.id_persona = $1 OR a.id_conf_establecimiento = $2)
+		    AND COALESCE(a.activa, TRUE)
+		    AND s.inicio_utc >= $3
+		    AND s.inicio_utc < $4
+		    AND (COALESCE(s.estado, 'LIBRE') = $5
+		         OR (s.estado = $6 AND s.capacidad - s.ocupados > 1))
+		  ORDER BY s.inicio_utc`,
+		recurso.IDProfesional,
+		recurso.IDConfEstablecimiento,
+		desde,
+		hasta,
+		domain.SlotLibre,
+		domain.SlotRetenido,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying slots libres del recurso %s: %w", descRecurso(recurso), err)
+	}
+	defer rows.Close()
+
+	var slots []domain.AgendaSlot
+	for rows.Next() {
+		var sl domain.AgendaSlot
+		if err := rows.Scan(&sl.ID, &sl.IDAgenda, &sl.HoraInicio, &sl.HoraFin, &sl.Inicio, &sl.Fin, &sl.Estado); err != nil {
+			return nil, fmt.Errorf("scanning slot libre del recurso: %w", err)
+		}
+		slots = append(slots, sl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating slots libres del recurso: %w", err)
+	}
+
+	return slots, nil
+}
+
+// GetReservasCalendario devuelve las reservas (en cualquier estado) a exportar como calendario:
+// las de la persona como paciente o las de las agendas de las que es dueña
+func (hr *AiReservesRepository) GetReservasCalendario(ctx context.Context, req domain.CalendarioFiltro) ([]domain.ReservaCalendario, error) {
+	rows, err := hr.dbPost.GetDB().QueryContext(ctx,
+		`SELECT r.id,
+		        r.id_agenda,
+		        r.fecha,
+		        to_char(r.hora_inicio, 'HH24:MI'),
+		        to_char(r.hora_fin, 'HH24:MI'),
+		        r.id_paciente,
+		        COALESCE(r.estado, 'PENDIENTE'),
+		        r.observaciones,
+		        r.id_sub_tipo_unidad_reserva,
+		        st.nombre,
+		        COALESCE(TRIM(pac.nombre || ' ' || pac.apellido_razon_social), ''),
+		        COALESCE(TRIM(prof.nombre || ' ' || prof.apellido_razon_social), ''),
+		        COALESCE(ce.nombre, ''),
+		        (SELECT GREATEST(COUNT(*) - 1, 0)
+		           FROM ai_res.reservas_historial h
+		          WHERE h.id_reserva = r.id),
+		        COALESCE(r.updated_at, r.created_at, CURRENT_TIMESTAMP),
+		        a.zona_horaria,
+		        r.inicio_utc,
+		        r.fin_utc
+		   FROM ai_res.reservas r
+		   JOIN ai_res.agendas a ON a.id = r.id_agenda
+		   JOIN ai_res.sub_tipo_unidad_reserva st ON st.id = r.id_sub_tipo_unidad_reserva
+		   LEFT JOIN ai_res.personas pac ON pac.id = r.id_paciente
+		   LEFT JOIN ai_res.conf_personal cp ON cp.id = a.id_conf_personal
+		   LEFT JOIN ai_res.personas prof ON prof.id = cp.id_persona
+		   LEFT JOIN ai_res.conf_establecimiento ce ON ce.id = a.id_conf_establecimiento
+		  WHERE r.fecha BETWEEN $2::date AND $3::date
+		    AND (($4 = $5 AND r.id_paciente = $1)
+		      OR ($4 = $6 AND (cp.id_persona = $1 OR ce.id_persona = $1)))
+		  ORDER BY r.fecha, r.hora_inicio`,
 		req.IDPersona,
-		req.IDSubTipoUnidadReserva,
+		req.FechaDesde.Format("2006-01-02"),
+		req.FechaHasta.Format("2006-01-02"),
+		req.Rol,
+		domain.CalendarioRolPaciente,
+		domain.CalendarioRolProfesional,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying reservas calendario: %w", err)
+	}
+	defer rows.Close()
+
+	reservas := []domain.ReservaCalendario{}
+	for rows.Next() {
+		var rc domain.ReservaCalendario
+		r := &rc.Reserva
+		var zonaHoraria string
+		var inicio, fin sql.NullTime
+		if err := rows.Scan(&r.ID, &r.IDAgenda, &r.Fecha, &r.HoraInicio, &r.HoraFin, &r.IDPaciente,
+			&r.Estado, &r.Observaciones, &r.IDSubTipoUnidadReserva,
+			&rc.SubTipo, &rc.Paciente, &rc.Profesional, &rc.Establecimiento, &rc.Secuencia, &rc.UpdatedAt,
+			&zonaHoraria, &inicio, &fin); err != nil {
+			return nil, fmt.Errorf("scanning reserva calendario: %w", err)
+		}
+		zonaReserva(r, zonaHoraria, inicio, fin)
+		reservas = append(reservas, rc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating reservas calendario: %w", err)
+	}
+
+	return reservas, nil
+}
+
+func (hr *AiReservesRepository) CreateFeedCalendario(ctx context.Context, req domain.FeedCalendario) (domain.FeedCalendario, error) {
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`INSERT INTO ai_res.calendario_feeds (token, id_persona, rol, created_by)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		req.Token,
+		req.IDPersona,
+		req.Rol,
+		req.CreatedBy,
+	).Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		return domain.FeedCalendario{}, fmt.Errorf("insert calendario_feeds: %w", err)
+	}
+
+	fmt.Printf("📆 Feed de calendario creado ID=%d (persona=%d %s)\n", req.ID, req.IDPersona, req.Rol)
+	return req, nil
+}
+
+// GetFeedCalendario busca un feed activo por su token
+func (hr *AiReservesRepository) GetFeedCalendario(ctx context.Context, token string) (domain.FeedCalendario, error) {
+	var f domain.FeedCalendario
+
+	err := hr.dbPost.GetDB().QueryRowContext(ctx,
+		`SELECT id, token, id_persona, rol, COALESCE(created_by, ''), created_at
+		   FROM ai_res.calendario_feeds
+		  WHERE token = $1
+		    AND activo`,
+		token,
+	).Scan(&f.ID, &f.Token, &f.IDPersona, &f.Rol, &f.CreatedBy, &f.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.FeedCalendario{}, domain.ErrFeedNotFound
+	}
+	if err != nil {
+		return domain.FeedCalendario{}, fmt.Errorf("get calendario_feed: %w", err)
+	}
+
+	return f, nil
+}
+
+// RevokeFeedsPersona revoca todos los feeds activos de la persona (baja del usuario)
+func (hr *AiReservesRepository) RevokeFeedsPersona(ctx context.Context, tx *sql.Tx, idPersona int) (int, error) {
+	res, err := tx.ExecContext(ctx,
+		`UPDATE ai_res.calendario_feeds
+		    SET activo = FALSE,
+		        revocado_at = CURRENT_TIMESTAMP
+		  WHERE id_persona = $1
+		    AND activo`,
+		idPersona,
 	)
+	if err != nil {
+		return 0, fmt.Errorf("revoke calendario_feeds persona %d: %w", idPersona, err)
+	}
 
+	rows, _ := res.RowsAffected()
+	return int(rows), nil
+}
+
+// RevokeFeedCalendario desactiva el feed de la persona; un feed ajeno se informa como inexistente
+func (hr *AiReservesRepository) RevokeFeedCalendario(ctx context.Context, token string, idPersona int) error {
+	res, err := hr.dbPost.GetDB().ExecContext(ctx,
+		`UPDATE ai_res.calendario_feeds
+		    SET activo = FALSE,
+		        revocado_at = CURRENT_TIMESTAMP
+		  WHERE token = $1
+		    AND id_persona = $2
+		    AND activo`,
+		token, idPersona,
+	)
 	if err != nil {
-		return fmt.Errorf("update conf_establecimiento: %w", err)
+		return fmt.Errorf("revoke calendario_feed: %w", err)
 	}
 
-	fmt.Printf("🔄 Updated conf_establecimiento field %s for persona=%d + sub_tipo=%d\n",
-		req.Atribute, req.IDPersona, req.IDSubTipoUnidadReserva)
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return domain.ErrFeedNotFound
+	}
 
 	return nil
 }
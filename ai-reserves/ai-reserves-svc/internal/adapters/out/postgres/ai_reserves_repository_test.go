@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+func TestSlotBloqueadoDisponible(t *testing.T) {
+	tests := []struct {
+		name string
+		slot slotBloqueado
+		err  error // nil si el slot se puede tomar
+	}{
+		{"libre", slotBloqueado{estado: domain.SlotLibre, capacidad: 1}, nil},
+		{"libre con lugares de un grupo", slotBloqueado{estado: domain.SlotLibre, capacidad: 4, ocupados: 3}, nil},
+		{"ocupado", slotBloqueado{estado: domain.SlotOcupado, capacidad: 1, ocupados: 1}, domain.ErrSlotAlreadyBooked},
+		{"grupo completo", slotBloqueado{estado: domain.SlotOcupado, capacidad: 4, ocupados: 4}, domain.ErrSlotAlreadyBooked},
+		{"bloqueado", slotBloqueado{estado: domain.SlotBloqueado, capacidad: 1}, domain.ErrSlotAlreadyBooked},
+		{"retenido por otro checkout", slotBloqueado{estado: domain.SlotRetenido, token: "t1", capacidad: 1}, domain.ErrSlotRetenido},
+		{"retención vencida sin barrer", slotBloqueado{estado: domain.SlotRetenido, token: "t1", retencionVencida: true, capacidad: 1}, nil},
+		{"retenido con otro lugar libre", slotBloqueado{estado: domain.SlotRetenido, token: "t1", capacidad: 3, ocupados: 1}, nil},
+		{"retenido con el último lugar", slotBloqueado{estado: domain.SlotRetenido, token: "t1", capacidad: 3, ocupados: 2}, domain.ErrSlotRetenido},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.slot.disponible(7, "10:00")
+			if tt.err == nil && err != nil {
+				t.Fatalf("disponible = %v, se esperaba nil", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("disponible = %v, se esperaba %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSlotBloqueadoTomable(t *testing.T) {
+	retenido := slotBloqueado{estado: domain.SlotRetenido, token: "t1"}
+	vencido := slotBloqueado{estado: domain.SlotRetenido, token: "t1", retencionVencida: true}
+	libre := slotBloqueado{estado: domain.SlotLibre}
+
+	tests := []struct {
+		name  string
+		slot  slotBloqueado
+		token string
+		err   error
+	}{
+		{"sin token sobre un slot libre", libre, "", nil},
+		{"sin token sobre una retención ajena", retenido, "", domain.ErrSlotRetenido},
+		{"confirma su retención", retenido, "t1", nil},
+		{"token ajeno", retenido, "t2", domain.ErrRetencionInvalida},
+		{"su retención venció", vencido, "t1", domain.ErrRetencionInvalida},
+		{"token sobre un slot libre", libre, "t1", domain.ErrRetencionInvalida},
+	}
+
+	for _, tt := range tests {
+		err := tt.slot.tomable(domain.Reserva{IDAgenda: 7, HoraInicio: "10:00", TokenRetencion: tt.token})
+		if (tt.err == nil) != (err == nil) || (tt.err != nil && !errors.Is(err, tt.err)) {
+			t.Errorf("%s: tomable = %v, se esperaba %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSlotBloqueadoCompartido(t *testing.T) {
+	tests := []struct {
+		name  string
+		slot  slotBloqueado
+		token string
+		want  bool
+	}{
+		{"retenido con lugar para otro", slotBloqueado{estado: domain.SlotRetenido, capacidad: 2}, "", true},
+		{"retenido sin lugar para otro", slotBloqueado{estado: domain.SlotRetenido, capacidad: 1}, "", false},
+		{"la reserva que confirma la retención no comparte", slotBloqueado{estado: domain.SlotRetenido, capacidad: 2}, "t1", false},
+		{"retención vencida", slotBloqueado{estado: domain.SlotRetenido, retencionVencida: true, capacidad: 2}, "", false},
+		{"libre", slotBloqueado{estado: domain.SlotLibre, capacidad: 2}, "", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.slot.compartido(tt.token); got != tt.want {
+			t.Errorf("%s: compartido(%q) = %t, se esperaba %t", tt.name, tt.token, got, tt.want)
+		}
+	}
+}
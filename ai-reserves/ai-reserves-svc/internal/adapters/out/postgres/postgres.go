@@ -92,7 +92,7 @@ func (p *PostgresDB) MapPostgresError(err error) error {
 			return ErrServerShutdown
 		case "42601":
 			return ErrSyntaxError
-		case "40001":
+		case "40001", "40P01":
 			return ErrDeadlockDetected
 		default:
 			return ErrInternalServer
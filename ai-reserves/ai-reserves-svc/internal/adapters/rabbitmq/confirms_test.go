@@ -0,0 +1,150 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/streadway/amqp"
+)
+
+// canalFalso hace de broker: a cada publish le contesta lo que indique responder, por los mismos
+// canales de confirmaciones y devoluciones que escucha el confirmador
+type canalFalso struct {
+	confirms  chan amqp.Confirmation
+	returns   chan amqp.Return
+	responder func(c *canalFalso, tag uint64, msg amqp.Publishing)
+	destinos  []string // exchange/routing key de cada publish
+}
+
+func (c *canalFalso) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	tag, err := strconv.ParseUint(msg.CorrelationId, 10, 64)
+	if err != nil {
+		return err
+	}
+	c.destinos = append(c.destinos, exchange+"/"+key)
+	if c.responder != nil {
+		c.responder(c, tag, msg)
+	}
+	return nil
+}
+
+func confirmar(ack bool) func(c *canalFalso, tag uint64, msg amqp.Publishing) {
+	return func(c *canalFalso, tag uint64, msg amqp.Publishing) {
+		c.confirms <- amqp.Confirmation{DeliveryTag: tag, Ack: ack}
+	}
+}
+
+func devolver(c *canalFalso, tag uint64, msg amqp.Publishing) {
+	// Sin cola para la routing key el broker devuelve el mensaje y después lo confirma
+	c.returns <- amqp.Return{ReplyCode: 312, ReplyText: "NO_ROUTE", Exchange: "app_events", RoutingKey: "reserve.created", CorrelationId: msg.CorrelationId}
+	c.confirms <- amqp.Confirmation{DeliveryTag: tag, Ack: true}
+}
+
+// escuchando arma un confirmador sobre el canal falso con su escucha corriendo
+func escuchando(responder func(c *canalFalso, tag uint64, msg amqp.Publishing), timeout time.Duration) (*confirmador, *canalFalso) {
+	ch := &canalFalso{
+		confirms:  make(chan amqp.Confirmation, 10),
+		returns:   make(chan amqp.Return, 10),
+		responder: responder,
+	}
+	c := &confirmador{ch: ch, pendientes: map[uint64]chan error{}, timeout: timeout}
+	go c.escuchar(ch.confirms, ch.returns)
+	return c, ch
+}
+
+func TestConfirmadorEscuchar(t *testing.T) {
+	tests := []struct {
+		name      string
+		responder func(c *canalFalso, tag uint64, msg amqp.Publishing)
+		ok        bool
+		err       error // error esperado, si es uno conocido
+	}{
+		{"ack", confirmar(true), true, nil},
+		{"nack", confirmar(false), false, nil},
+		{"devuelto sin ruta", devolver, false, domain.ErrEventoNoRuteado},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := escuchando(tt.responder, time.Second)
+
+			err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{})
+			if (err == nil) != tt.ok {
+				t.Fatalf("publish = %v, se esperaba ok=%t", err, tt.ok)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("publish = %v, se esperaba %v", err, tt.err)
+			}
+			if !tt.ok && tt.err == nil && errors.Is(err, domain.ErrEventoNoRuteado) {
+				t.Fatalf("publish = %v, un nack no es un mensaje sin ruta", err)
+			}
+		})
+	}
+}
+
+func TestConfirmadorDeliveryTags(t *testing.T) {
+	// El segundo mensaje se devuelve: solo ese publish falla aunque las confirmaciones sigan llegando
+	c, _ := escuchando(func(c *canalFalso, tag uint64, msg amqp.Publishing) {
+		if tag == 2 {
+			devolver(c, tag, msg)
+			return
+		}
+		confirmar(true)(c, tag, msg)
+	}, time.Second)
+
+	for tag, noRuteado := range []bool{false, true, false} {
+		err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{})
+		if errors.Is(err, domain.ErrEventoNoRuteado) != noRuteado || (!noRuteado && err != nil) {
+			t.Fatalf("publish %d = %v, se esperaba devuelto=%t", tag+1, err, noRuteado)
+		}
+	}
+}
+
+func TestConfirmadorTimeout(t *testing.T) {
+	c, ch := escuchando(nil, 20*time.Millisecond)
+
+	if err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{}); err == nil {
+		t.Fatalf("publish sin confirmación = nil, se esperaba error")
+	}
+	c.mu.Lock()
+	pendientes := len(c.pendientes)
+	c.mu.Unlock()
+	if pendientes != 0 {
+		t.Fatalf("quedaron %d publish pendientes después del timeout", pendientes)
+	}
+
+	// La confirmación que llega tarde se descarta y el confirmador sigue atendiendo
+	ch.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	ch.responder = confirmar(true)
+	if err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{}); err != nil {
+		t.Fatalf("publish después de un timeout = %v, se esperaba nil", err)
+	}
+}
+
+func TestConfirmadorContextoCancelado(t *testing.T) {
+	c, _ := escuchando(nil, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.publish(ctx, "app_events", "reserve.created", amqp.Publishing{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("publish con el contexto cancelado = %v, se esperaba %v", err, context.Canceled)
+	}
+}
+
+func TestConfirmadorCerrar(t *testing.T) {
+	// El canal se cae con el publish esperando su confirmación
+	c, _ := escuchando(func(c *canalFalso, tag uint64, msg amqp.Publishing) {
+		close(c.confirms)
+	}, time.Second)
+
+	if err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{}); !errors.Is(err, domain.ErrBrokerDesconectado) {
+		t.Fatalf("publish pendiente al cerrarse el canal = %v, se esperaba %v", err, domain.ErrBrokerDesconectado)
+	}
+	if err := c.publish(context.Background(), "app_events", "reserve.created", amqp.Publishing{}); !errors.Is(err, domain.ErrBrokerDesconectado) {
+		t.Fatalf("publish con el confirmador cerrado = %v, se esperaba %v", err, domain.ErrBrokerDesconectado)
+	}
+}
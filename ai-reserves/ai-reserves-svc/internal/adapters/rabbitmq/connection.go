@@ -0,0 +1,247 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/streadway/amqp"
+)
+
+// Conexión supervisada: si el broker cierra la conexión (o alguno de los canales) el adapter
+// reconecta con espera creciente, reabre los canales y vuelve a registrar los consumers.
+// Mientras está desconectado Publish falla enseguida con domain.ErrBrokerDesconectado: el outbox
+// deja el evento pendiente y el relay lo reintenta.
+
+// Espera entre intentos de reconexión: reconnectMinDelay, el doble... hasta reconnectMaxDelay
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// consumer registrado con Consume; se vuelve a suscribir en cada reconexión mientras su ctx siga vivo
+type consumer struct {
+	ctx     context.Context
+	queue   string
+	handler func(domain.Event) error
+}
+
+// connect abre la conexión y los canales de publish / consume y los deja activos en el adapter
+func (r *RabbitMQAdapter) connect() error {
+	conn, err := amqp.Dial(r.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	pubCh, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open publish channel: %w", err)
+	}
+	conCh, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open consume channel: %w", err)
+	}
+	// (Optional) QoS to avoid overwhelming consumers
+	if err := conCh.Qos(50, 0, false); err != nil {
+		conn.Close()
+		return fmt.Errorf("qos: %w", err)
+	}
+	publicador, err := newConfirmador(pubCh)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isClosed() {
+		conn.Close()
+		return domain.ErrBrokerDesconectado
+	}
+	r.conn, r.pubCh, r.conCh = conn, pubCh, conCh
+	r.publicador = publicador
+	r.conectado = true
+	r.ultimoCambio = time.Now()
+	r.ultimoError = ""
+
+	return nil
+}
+
+// supervise espera el cierre de la conexión o de un canal y reconecta hasta que se llame a Close.
+// Si después de reconectar algún consumer no se pudo suscribir, fuerza otra reconexión esperando el doble.
+func (r *RabbitMQAdapter) supervise() {
+	espera := reconnectMinDelay
+	for {
+		r.mu.RLock()
+		conn, pubCh, conCh := r.conn, r.pubCh, r.conCh
+		r.mu.RUnlock()
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		pubClosed := pubCh.NotifyClose(make(chan *amqp.Error, 1))
+		conClosed := conCh.NotifyClose(make(chan *amqp.Error, 1))
+
+		var cause *amqp.Error
+		select {
+		case <-r.done:
+			return
+		case cause = <-connClosed:
+		case cause = <-pubClosed:
+		case cause = <-conClosed:
+		}
+		if r.isClosed() {
+			return
+		}
+
+		// Un canal cerrado por el broker también se recupera reconectando todo
+		_ = conn.Close()
+		r.setDesconectado(cause)
+
+		if !r.reconnect(espera) {
+			return
+		}
+		if r.resubscribe() {
+			espera = reconnectMinDelay
+		} else {
+			espera = min(espera*2, reconnectMaxDelay)
+			r.reintentarConsumers()
+		}
+	}
+}
+
+func (r *RabbitMQAdapter) setDesconectado(cause *amqp.Error) {
+	motivo := "connection closed"
+	if cause != nil {
+		motivo = cause.Error()
+	}
+	fmt.Printf("⚠️ RabbitMQ disconnected: %s\n", motivo)
+
+	r.mu.Lock()
+	r.conectado = false
+	r.ultimoCambio = time.Now()
+	r.ultimoError = motivo
+	r.mu.Unlock()
+}
+
+// reconnect reintenta la conexión con espera creciente a partir de espera; devuelve false si el adapter se cerró
+func (r *RabbitMQAdapter) reconnect(espera time.Duration) bool {
+	for intento := 1; ; intento++ {
+		select {
+		case <-r.done:
+			return false
+		case <-time.After(espera):
+		}
+
+		if err := r.connect(); err != nil {
+			if r.isClosed() {
+				return false
+			}
+			fmt.Printf("❌ RabbitMQ reconnect attempt %d failed, next in %s: %v\n", intento, min(espera*2, reconnectMaxDelay), err)
+			r.mu.Lock()
+			r.ultimoError = err.Error()
+			r.mu.Unlock()
+			espera = min(espera*2, reconnectMaxDelay)
+			continue
+		}
+
+		r.mu.Lock()
+		r.reconexiones++
+		r.mu.Unlock()
+		fmt.Printf("✅ RabbitMQ reconnected after %d attempts\n", intento)
+		return true
+	}
+}
+
+// resubscribe vuelve a registrar en el canal nuevo los consumers cuyo contexto sigue vivo;
+// devuelve false si alguno no se pudo suscribir
+func (r *RabbitMQAdapter) resubscribe() bool {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.mu.Lock()
+	vigentes := r.consumers[:0]
+	for _, c := range r.consumers {
+		if c.ctx.Err() == nil {
+			vigentes = append(vigentes, c)
+		}
+	}
+	r.consumers = vigentes
+	consumers := append([]consumer(nil), vigentes...)
+	r.mu.Unlock()
+
+	suscriptos := true
+	for _, c := range consumers {
+		if err := r.subscribe(c); err != nil {
+			// Sigue en la lista: la próxima reconexión lo vuelve a intentar
+			fmt.Printf("❌ Error re-registering consumer on %s: %v\n", c.queue, err)
+			suscriptos = false
+			continue
+		}
+		fmt.Printf("🎧 Consumer re-registered on %s\n", c.queue)
+	}
+	return suscriptos
+}
+
+// reintentarConsumers cierra el canal de consumo para que supervise reconecte y vuelva a suscribir
+// los consumers que quedaron sin suscripción. Sin conexión no hace nada: la reconexión ya está en curso.
+func (r *RabbitMQAdapter) reintentarConsumers() {
+	r.mu.RLock()
+	conCh, conectado := r.conCh, r.conectado
+	r.mu.RUnlock()
+
+	if conectado && conCh != nil {
+		_ = conCh.Close()
+	}
+}
+
+// publisher devuelve el publicador del canal activo o ErrBrokerDesconectado si no hay conexión
+func (r *RabbitMQAdapter) publisher() (*confirmador, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.conectado {
+		return nil, r.errDesconectado()
+	}
+	return r.publicador, nil
+}
+
+// channels devuelve los canales activos o ErrBrokerDesconectado si no hay conexión
+func (r *RabbitMQAdapter) channels() (pubCh, conCh *amqp.Channel, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.conectado {
+		return nil, nil, r.errDesconectado()
+	}
+	return r.pubCh, r.conCh, nil
+}
+
+// errDesconectado arma ErrBrokerDesconectado con el motivo de la caída; se llama con r.mu tomado
+func (r *RabbitMQAdapter) errDesconectado() error {
+	if r.ultimoError != "" {
+		return fmt.Errorf("%w: %s", domain.ErrBrokerDesconectado, r.ultimoError)
+	}
+	return domain.ErrBrokerDesconectado
+}
+
+func (r *RabbitMQAdapter) isClosed() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status informa el estado de la conexión con el broker para el healthcheck
+func (r *RabbitMQAdapter) Status() domain.Broker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return domain.Broker{
+		Broker:                "rabbitmq",
+		Conectado:             r.conectado,
+		FechaHoraUltimoCambio: r.ultimoCambio.Format(time.RFC3339),
+		Reconexiones:          r.reconexiones,
+		UltimoError:           r.ultimoError,
+	}
+}
@@ -3,8 +3,11 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
@@ -12,47 +15,76 @@ import (
 )
 
 type RabbitMQAdapter struct {
-	conn  *amqp.Connection
-	pubCh *amqp.Channel
-	conCh *amqp.Channel
+	url string
+
+	// conexión y canales vigentes; los reemplaza la reconexión (ver connection.go)
+	mu           sync.RWMutex
+	conn         *amqp.Connection
+	pubCh        *amqp.Channel
+	conCh        *amqp.Channel
+	publicador   *confirmador
+	conectado    bool
+	ultimoCambio time.Time
+	ultimoError  string
+	reconexiones int
+	consumers    []consumer
+	subMu        sync.Mutex // Consume y resubscribe no se intercalan: ningún consumer se pierde en una reconexión
+	done         chan struct{}
+	closeOnce    sync.Once
+
 	// topology
 	exchange string // e.g. "app_events"
+	// reintentos del consumer: maxRetries intentos más, esperando retryBase, 2*retryBase, 4*retryBase...
+	maxRetries int
+	retryBase  time.Duration
 }
 
+// Headers con los que el consumer lleva la cuenta de los reintentos de un mensaje
+const (
+	headerRetryCount = "x-retry-count"
+	headerLastError  = "x-last-error"
+)
+
 func NewRabbitMQAdapter(amqpURL, exchange string) (*RabbitMQAdapter, error) {
-	conn, err := amqp.Dial(amqpURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	maxRetries := 5
+	if v, err := strconv.Atoi(os.Getenv("RABBITMQ_MAX_RETRIES")); err == nil && v >= 0 {
+		maxRetries = v
 	}
-	pubCh, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open publish channel: %w", err)
+
+	retryBase := 5 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("RABBITMQ_RETRY_BASE_SECONDS")); err == nil && v > 0 {
+		retryBase = time.Duration(v) * time.Second
 	}
-	conCh, err := conn.Channel()
-	if err != nil {
-		pubCh.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to open consume channel: %w", err)
+
+	r := &RabbitMQAdapter{
+		url:        amqpURL,
+		done:       make(chan struct{}),
+		exchange:   os.Getenv("RABBITMQ_QUEUE_EXCHANGE"),
+		maxRetries: maxRetries,
+		retryBase:  retryBase,
+	}
+	// La primera conexión tiene que funcionar; las caídas posteriores las recupera supervise
+	if err := r.connect(); err != nil {
+		return nil, err
 	}
+	go r.supervise()
 
-	return &RabbitMQAdapter{
-		conn:     conn,
-		pubCh:    pubCh,
-		conCh:    conCh,
-		exchange: os.Getenv("RABBITMQ_QUEUE_EXCHANGE"),
-	}, nil
+	return r, nil
 }
 
-// Publish with routing key (e.g., "config.updated", "user.created")
+// Publish with routing key (e.g., "config.updated", "user.created").
+// Devuelve nil recién cuando el broker confirmó el mensaje (ver confirms.go).
 func (r *RabbitMQAdapter) Publish(ctx context.Context, event domain.Event) error {
 	body, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
-	return r.pubCh.Publish(
+	publicador, err := r.publisher()
+	if err != nil {
+		return err
+	}
+	return publicador.publish(ctx,
 		r.exchange, event.RoutingKey,
-		false, false,
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        body,
@@ -61,13 +93,41 @@ func (r *RabbitMQAdapter) Publish(ctx context.Context, event domain.Event) error
 	)
 }
 
-// Consume from a named queue that has already been bound to routing keys
-func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler func(domain.Event)) error {
-	// (Optional) QoS to avoid overwhelming consumers
-	if err := r.conCh.Qos(50, 0, false); err != nil {
-		return fmt.Errorf("qos: %w", err)
+// Consume from a named queue that has already been bound to routing keys.
+// El mensaje se confirma solo si el handler no devuelve error; si falla se reintenta con espera
+// creciente y, agotados los reintentos (o si el evento es inválido), pasa a la dead-letter queue.
+// El consumer queda registrado antes de suscribirse y se vuelve a suscribir después de cada
+// reconexión: si la primera suscripción falla la reintenta la reconexión, con la misma espera creciente.
+func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler func(domain.Event) error) error {
+	c := consumer{ctx: ctx, queue: queue, handler: handler}
+
+	// Registrar y suscribir con subMu tomado: si el broker se cae en el medio, resubscribe espera
+	// y encuentra el consumer ya en la lista
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.mu.Lock()
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+
+	if err := r.subscribe(c); err != nil {
+		fmt.Printf("⚠️ Consumer on %s not subscribed yet, retrying on reconnection: %v\n", c.queue, err)
+		r.reintentarConsumers()
 	}
-	msgs, err := r.conCh.Consume(queue, "", false, false, false, false, nil)
+	return nil
+}
+
+// subscribe declara la topología de reintentos y empieza a consumir la cola en el canal vigente
+func (r *RabbitMQAdapter) subscribe(c consumer) error {
+	_, conCh, err := r.channels()
+	if err != nil {
+		return err
+	}
+	if err := r.declareRetryTopology(conCh, c.queue); err != nil {
+		return err
+	}
+
+	msgs, err := conCh.Consume(c.queue, "", false, false, false, false, nil)
 	if err != nil {
 		return fmt.Errorf("consume: %w", err)
 	}
@@ -75,24 +135,29 @@ func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler fun
 	go func() {
 		for {
 			select {
-			case <-ctx.Done():
+			case <-c.ctx.Done():
 				return
 			case d, ok := <-msgs:
 				if !ok {
+					// Se cerró el canal: supervise vuelve a suscribir el consumer al reconectar
+					fmt.Printf("⚠️ Consumer on %s stopped, waiting for reconnection\n", c.queue)
 					return
 				}
 
 				var event domain.Event
 				if err := json.Unmarshal(d.Body, &event); err != nil {
 					fmt.Printf("⚠️ Error unmarshalling message: %v\n", err)
-					_ = d.Nack(false, false) // opcional: no reencolar
+					r.deadLetter(c.ctx, c.queue, d, fmt.Errorf("%w: %v", domain.ErrEventoInvalido, err))
 					continue
 				}
 
 				// 👉 Ahora sí, pasamos el evento de dominio al handler
-				handler(event)
+				if err := c.handler(event); err != nil {
+					r.retryOrDeadLetter(c.ctx, c.queue, d, err)
+					continue
+				}
 
-				// (Opcional) confirmar procesamiento exitoso
+				// Procesado: recién ahora se confirma
 				_ = d.Ack(false)
 
 			}
@@ -101,17 +166,152 @@ func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler fun
 	return nil
 }
 
-func (r *RabbitMQAdapter) Ack(d amqp.Delivery)                { _ = d.Ack(false) }
-func (r *RabbitMQAdapter) Nack(d amqp.Delivery, requeue bool) { _ = d.Nack(false, requeue) }
+// declareRetryTopology declara, para la cola consumida, una cola de espera por intento
+// (<queue>.retry.<espera>, con TTL que devuelve el mensaje a la cola original) y la dead-letter
+// queue <queue>.dlq, atada al exchange <exchange>.dlx. El nombre lleva la espera para que cambiar
+// la configuración declare colas nuevas en lugar de chocar con el TTL de las existentes.
+func (r *RabbitMQAdapter) declareRetryTopology(ch *amqp.Channel, queue string) error {
+	for intento := 1; intento <= r.maxRetries; intento++ {
+		_, err := ch.QueueDeclare(r.retryQueue(queue, intento), true, false, false, false, amqp.Table{
+			"x-message-ttl":             int32(r.retryDelay(intento) / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		})
+		if err != nil {
+			return fmt.Errorf("declare retry queue: %w", err)
+		}
+	}
 
-func (r *RabbitMQAdapter) Close() {
-	if r.pubCh != nil {
-		_ = r.pubCh.Close()
+	if err := ch.ExchangeDeclare(r.deadLetterExchange(), "direct", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare dead-letter exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(queue+".dlq", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare dead-letter queue: %w", err)
+	}
+	if err := ch.QueueBind(queue+".dlq", queue, r.deadLetterExchange(), false, nil); err != nil {
+		return fmt.Errorf("bind dead-letter queue: %w", err)
+	}
+
+	return nil
+}
+
+// retryOrDeadLetter agenda el próximo intento del mensaje fallido o, si no quedan, lo manda a la DLQ.
+// El original se confirma recién cuando el broker confirmó la copia; si no, vuelve a la cola.
+func (r *RabbitMQAdapter) retryOrDeadLetter(ctx context.Context, queue string, d amqp.Delivery, cause error) {
+	intento := retryCount(d) + 1
+	if errors.Is(cause, domain.ErrEventoInvalido) || intento > r.maxRetries {
+		r.deadLetter(ctx, queue, d, cause)
+		return
+	}
+
+	err := r.republicar(ctx, "", r.retryQueue(queue, intento), republish(d, intento, cause))
+	if err != nil {
+		// Sin cola de espera disponible: vuelve a la cola original antes que perderse
+		fmt.Printf("❌ Error scheduling retry %d for message: %v\n", intento, err)
+		_ = d.Nack(false, true)
+		return
 	}
-	if r.conCh != nil {
-		_ = r.conCh.Close()
+
+	fmt.Printf("🔁 Message retry %d/%d in %s: %v\n", intento, r.maxRetries, r.retryDelay(intento), cause)
+	_ = d.Ack(false)
+}
+
+// deadLetter manda el mensaje a la DLQ de la cola con el motivo en los headers
+func (r *RabbitMQAdapter) deadLetter(ctx context.Context, queue string, d amqp.Delivery, cause error) {
+	err := r.republicar(ctx, r.deadLetterExchange(), queue, republish(d, retryCount(d), cause))
+	if err != nil {
+		fmt.Printf("❌ Error dead-lettering message: %v\n", err)
+		_ = d.Nack(false, true)
+		return
 	}
-	if r.conn != nil {
-		_ = r.conn.Close()
+
+	fmt.Printf("☠️ Message sent to %s.dlq: %v\n", queue, cause)
+	_ = d.Ack(false)
+}
+
+// republicar publica la copia de un mensaje consumido por el canal con confirms (ver confirms.go):
+// devuelve nil solo si el broker la ruteó y la confirmó
+func (r *RabbitMQAdapter) republicar(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	publicador, err := r.publisher()
+	if err != nil {
+		return err
 	}
+	return publicador.publish(ctx, exchange, routingKey, msg)
+}
+
+func (r *RabbitMQAdapter) deadLetterExchange() string {
+	return r.exchange + ".dlx"
+}
+
+// Espera máxima entre reintentos
+const maxRetryDelay = time.Hour
+
+// retryDelay es la espera antes del intento: retryBase, 2*retryBase, 4*retryBase... hasta maxRetryDelay
+func (r *RabbitMQAdapter) retryDelay(intento int) time.Duration {
+	delay := r.retryBase
+	for i := 1; i < intento && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func (r *RabbitMQAdapter) retryQueue(queue string, intento int) string {
+	return fmt.Sprintf("%s.retry.%s", queue, r.retryDelay(intento))
+}
+
+// retryCount lee cuántos reintentos lleva el mensaje (0 en la primera entrega)
+func retryCount(d amqp.Delivery) int {
+	switch v := d.Headers[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// republish copia el mensaje con la cuenta de reintentos y el último error en los headers
+func republish(d amqp.Delivery, intento int, cause error) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerRetryCount] = int32(intento)
+	headers[headerLastError] = cause.Error()
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	}
+}
+
+func (r *RabbitMQAdapter) Ack(d amqp.Delivery)                { _ = d.Ack(false) }
+func (r *RabbitMQAdapter) Nack(d amqp.Delivery, requeue bool) { _ = d.Nack(false, requeue) }
+
+// Close detiene la supervisión y cierra canales y conexión; se puede llamar más de una vez
+func (r *RabbitMQAdapter) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.conectado = false
+		if r.pubCh != nil {
+			_ = r.pubCh.Close()
+		}
+		if r.conCh != nil {
+			_ = r.conCh.Close()
+		}
+		if r.conn != nil {
+			_ = r.conn.Close()
+		}
+	})
 }
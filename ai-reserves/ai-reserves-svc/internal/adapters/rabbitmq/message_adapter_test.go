@@ -0,0 +1,204 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/streadway/amqp"
+)
+
+func TestRetryDelay(t *testing.T) {
+	r := &RabbitMQAdapter{retryBase: 5 * time.Second}
+
+	tests := []struct {
+		intento int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{40, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := r.retryDelay(tt.intento); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, se esperaba %s", tt.intento, got, tt.want)
+		}
+	}
+}
+
+func TestNombresRetryYDLX(t *testing.T) {
+	r := &RabbitMQAdapter{exchange: "app_events", retryBase: 5 * time.Second}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"primer reintento", r.retryQueue("ai-reserves.events", 1), "ai-reserves.events.retry.5s"},
+		{"tercer reintento", r.retryQueue("ai-reserves.events", 3), "ai-reserves.events.retry.20s"},
+		{"reintento en el máximo", r.retryQueue("ai-reserves.events", 20), "ai-reserves.events.retry.1h0m0s"},
+		{"exchange de la DLQ", r.deadLetterExchange(), "app_events.dlx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Fatalf("nombre = %s, se esperaba %s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{"primera entrega", nil, 0},
+		{"int32 como lo publica republish", amqp.Table{headerRetryCount: int32(3)}, 3},
+		{"int64 como lo decodifica el broker", amqp.Table{headerRetryCount: int64(2)}, 2},
+		{"int", amqp.Table{headerRetryCount: 4}, 4},
+		{"tipo inesperado", amqp.Table{headerRetryCount: "3"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryCount(amqp.Delivery{Headers: tt.headers}); got != tt.want {
+				t.Fatalf("retryCount = %d, se esperaba %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepublish(t *testing.T) {
+	d := amqp.Delivery{
+		Headers:     amqp.Table{"x-origen": "auth", headerRetryCount: int32(1)},
+		ContentType: "application/json",
+		Body:        []byte(`{"id":"e1"}`),
+	}
+
+	p := republish(d, 2, errors.New("db caída"))
+
+	if got := retryCount(amqp.Delivery{Headers: p.Headers}); got != 2 {
+		t.Errorf("x-retry-count = %d, se esperaba 2", got)
+	}
+	if got := p.Headers[headerLastError]; got != "db caída" {
+		t.Errorf("x-last-error = %v, se esperaba %q", got, "db caída")
+	}
+	if got := p.Headers["x-origen"]; got != "auth" {
+		t.Errorf("header original = %v, se esperaba auth", got)
+	}
+	if string(p.Body) != string(d.Body) || p.ContentType != d.ContentType || p.DeliveryMode != amqp.Persistent {
+		t.Errorf("republish = %+v, se esperaba el mismo mensaje persistente", p)
+	}
+	if got := retryCount(d); got != 1 {
+		t.Errorf("republish modificó los headers del mensaje recibido: x-retry-count = %d", got)
+	}
+}
+
+func TestConsumeSinConexion(t *testing.T) {
+	// La primera suscripción falla con el broker caído: el consumer queda registrado para la reconexión
+	r := &RabbitMQAdapter{ultimoError: "connection refused"}
+	handler := func(domain.Event) error { return nil }
+
+	vivo, cancelar := context.WithCancel(context.Background())
+	defer cancelar()
+	if err := r.Consume(vivo, "ai-reserves.events", handler); err != nil {
+		t.Fatalf("Consume = %v, se esperaba nil", err)
+	}
+	terminado, terminar := context.WithCancel(context.Background())
+	if err := r.Consume(terminado, "ai-reserves.otros", handler); err != nil {
+		t.Fatalf("Consume = %v, se esperaba nil", err)
+	}
+	if len(r.consumers) != 2 {
+		t.Fatalf("consumers registrados = %d, se esperaban 2", len(r.consumers))
+	}
+
+	// Sin conexión resubscribe no suscribe a nadie y descarta los consumers cuyo contexto terminó
+	terminar()
+	if r.resubscribe() {
+		t.Fatalf("resubscribe sin conexión = true, se esperaba false")
+	}
+	if len(r.consumers) != 1 || r.consumers[0].queue != "ai-reserves.events" {
+		t.Fatalf("consumers registrados = %v, se esperaba solo ai-reserves.events", r.consumers)
+	}
+}
+
+// entregaFalsa registra cómo se resolvió el mensaje consumido
+type entregaFalsa struct {
+	acks    int
+	nacks   int
+	requeue bool
+}
+
+func (e *entregaFalsa) Ack(tag uint64, multiple bool) error { e.acks++; return nil }
+func (e *entregaFalsa) Nack(tag uint64, multiple, requeue bool) error {
+	e.nacks++
+	e.requeue = requeue
+	return nil
+}
+func (e *entregaFalsa) Reject(tag uint64, requeue bool) error { return e.Nack(tag, false, requeue) }
+
+func TestRetryOrDeadLetter(t *testing.T) {
+	errHandler := errors.New("db caída")
+
+	tests := []struct {
+		name      string
+		conectado bool
+		reintento int32
+		cause     error
+		responder func(c *canalFalso, tag uint64, msg amqp.Publishing)
+		destino   string // "" si no se publica nada
+		ack       bool
+	}{
+		{"primer fallo va a la cola de espera", true, 0, errHandler, confirmar(true), "/ai-reserves.events.retry.5s", true},
+		{"segundo fallo espera el doble", true, 1, errHandler, confirmar(true), "/ai-reserves.events.retry.10s", true},
+		{"sin reintentos va a la DLQ", true, 2, errHandler, confirmar(true), "app_events.dlx/ai-reserves.events", true},
+		{"evento inválido va directo a la DLQ", true, 0, domain.ErrEventoInvalido, confirmar(true), "app_events.dlx/ai-reserves.events", true},
+		{"reintento nackeado por el broker vuelve a la cola", true, 0, errHandler, confirmar(false), "/ai-reserves.events.retry.5s", false},
+		{"DLQ nackeada por el broker vuelve a la cola", true, 2, errHandler, confirmar(false), "app_events.dlx/ai-reserves.events", false},
+		{"reintento sin ruta vuelve a la cola", true, 0, errHandler, devolver, "/ai-reserves.events.retry.5s", false},
+		{"sin conexión vuelve a la cola", false, 0, errHandler, confirmar(true), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ch := escuchando(tt.responder, time.Second)
+			r := &RabbitMQAdapter{
+				conectado:  tt.conectado,
+				publicador: c,
+				exchange:   "app_events",
+				maxRetries: 2,
+				retryBase:  5 * time.Second,
+			}
+			entrega := &entregaFalsa{}
+			d := amqp.Delivery{Acknowledger: entrega, DeliveryTag: 1, Body: []byte(`{}`)}
+			if tt.reintento > 0 {
+				d.Headers = amqp.Table{headerRetryCount: tt.reintento}
+			}
+
+			r.retryOrDeadLetter(context.Background(), "ai-reserves.events", d, tt.cause)
+
+			if tt.destino == "" && len(ch.destinos) != 0 {
+				t.Fatalf("publicados = %v, no se esperaba ninguno", ch.destinos)
+			}
+			if tt.destino != "" && (len(ch.destinos) != 1 || ch.destinos[0] != tt.destino) {
+				t.Fatalf("publicados = %v, se esperaba %s", ch.destinos, tt.destino)
+			}
+			if tt.ack && (entrega.acks != 1 || entrega.nacks != 0) {
+				t.Fatalf("acks = %d, nacks = %d, se esperaba solo el ack", entrega.acks, entrega.nacks)
+			}
+			// Sin confirmación del broker el original no se pierde: vuelve a la cola
+			if !tt.ack && (entrega.acks != 0 || entrega.nacks != 1 || !entrega.requeue) {
+				t.Fatalf("acks = %d, nacks = %d, requeue = %t, se esperaba un nack con requeue", entrega.acks, entrega.nacks, entrega.requeue)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+func (hs *AiReservesService) CreateBloqueoAPI(ctx context.Context, req domain.AgendaBloqueo) (domain.BloqueoResultado, error) {
+
+	if req.Inicio.IsZero() || req.Fin.IsZero() || !req.Fin.After(req.Inicio) {
+		return domain.BloqueoResultado{}, fmt.Errorf("Inicio y Fin son obligatorios y Fin debe ser posterior a Inicio")
+	}
+
+	if err := hs.resolverEntidadBloqueo(ctx, &req.IDProfesional, &req.IDConfPersonal, req.IDConfEstablecimiento); err != nil {
+		return domain.BloqueoResultado{}, err
+	}
+
+	var resultado domain.BloqueoResultado
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+
+		// 1) Registrar el bloqueo
+		req.ID, err = hs.hr.CreateBloqueo(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+		resultado.IDBloqueo = req.ID
+
+		// 2) Bloquear los slots ya generados del rango
+		resultado.SlotsBloqueados, err = hs.hr.BloquearSlots(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		// 3) Informar las reservas que quedaron dentro y hay que reprogramar
+		resultado.Colisiones, err = hs.hr.GetReservasEnBloqueo(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return domain.BloqueoResultado{}, err
+	}
+
+	return resultado, nil
+}
+
+func (hs *AiReservesService) GetBloqueosAPI(ctx context.Context, req domain.BloqueoFiltro) ([]domain.AgendaBloqueo, error) {
+
+	if err := validarRangoAgenda(req.FechaDesde, req.FechaHasta); err != nil {
+		return nil, err
+	}
+
+	if err := hs.resolverEntidadBloqueo(ctx, &req.IDProfesional, &req.IDConfPersonal, req.IDConfEstablecimiento); err != nil {
+		return nil, err
+	}
+
+	// FechaHasta incluye todo el día
+	req.FechaHasta = truncarFecha(req.FechaHasta).AddDate(0, 0, 1)
+
+	bloqueos, err := hs.hr.GetBloqueos(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return bloqueos, nil
+}
+
+func (hs *AiReservesService) DeleteBloqueoAPI(ctx context.Context, idBloqueo int) error {
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		borrado, err := hs.hr.DeleteBloqueo(ctx, tx, idBloqueo)
+		if err != nil {
+			return err
+		}
+
+		return hs.reaplicarBloqueos(ctx, tx, borrado, map[int]bool{borrado.ID: true})
+	})
+}
+
+// reaplicarBloqueos vuelve a bloquear los slots liberados por un bloqueo borrado que siguen cubiertos
+// por otro bloqueo superpuesto. La consulta no ve la transacción: los ids borrados en ella se excluyen.
+func (hs *AiReservesService) reaplicarBloqueos(ctx context.Context, tx *sql.Tx, borrado domain.AgendaBloqueo, borrados map[int]bool) error {
+	otros, err := hs.hr.GetBloqueos(ctx, domain.BloqueoFiltro{
+		IDConfPersonal:        borrado.IDConfPersonal,
+		IDConfEstablecimiento: borrado.IDConfEstablecimiento,
+		FechaDesde:            borrado.Inicio,
+		FechaHasta:            borrado.Fin,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range otros {
+		if borrados[b.ID] {
+			continue
+		}
+		if _, err := hs.hr.BloquearSlots(ctx, tx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aplicarBloqueos bloquea los slots recién generados que caen dentro de bloqueos existentes
+func (hs *AiReservesService) aplicarBloqueos(ctx context.Context, tx *sql.Tx, dias []domain.AgendaDia) error {
+	if len(dias) == 0 {
+		return nil
+	}
+
+	bloqueos, err := hs.hr.GetBloqueos(ctx, domain.BloqueoFiltro{
+		IDConfPersonal:        dias[0].IDConfPersonal,
+		IDConfEstablecimiento: dias[0].IDConfEstablecimiento,
+		FechaDesde:            dias[0].Fecha,
+		FechaHasta:            dias[len(dias)-1].Fecha.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bloqueos {
+		if _, err := hs.hr.BloquearSlots(ctx, tx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolverEntidadBloqueo exige un profesional o un establecimiento y traduce el profesional a su conf_personal
+func (hs *AiReservesService) resolverEntidadBloqueo(ctx context.Context, idProfesional, idConfPersonal *int, idConfEstablecimiento int) error {
+	entidades := 0
+	if *idProfesional != 0 || *idConfPersonal != 0 {
+		entidades++
+	}
+	if idConfEstablecimiento != 0 {
+		entidades++
+	}
+	if entidades != 1 {
+		return fmt.Errorf("indicar IDProfesional o IDConfEstablecimiento (solo uno)")
+	}
+
+	if *idConfPersonal == 0 && *idProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, *idProfesional)
+		if err != nil {
+			return err
+		}
+		*idConfPersonal = conf.ID
+	}
+
+	return nil
+}
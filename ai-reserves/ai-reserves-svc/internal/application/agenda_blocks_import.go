@@ -0,0 +1,254 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/ical"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+)
+
+// Importación del calendario personal (.ics) de un profesional: cada ocurrencia de un evento ocupado
+// es un bloqueo identificado por UID + ocurrencia. Reimportar sincroniza los UID que trae el archivo
+// (crea, actualiza o elimina ocurrencias); los bloqueos de UID que no vienen en el archivo no se tocan.
+
+// Tope de ocurrencias por evento al expandir RRULE; alcanza para una serie diaria de más de cien años
+const maxOcurrenciasImport = 50000
+
+const motivoBloqueoICal = "Ocupado"
+
+func (hs *AiReservesService) ImportBloqueosICalAPI(ctx context.Context, req domain.BloqueoImport) (domain.BloqueoImportResumen, error) {
+
+	if strings.TrimSpace(req.Contenido) == "" {
+		return domain.BloqueoImportResumen{}, fmt.Errorf("Contenido es obligatorio")
+	}
+	if req.IDProfesional == 0 && req.IDConfPersonal == 0 {
+		return domain.BloqueoImportResumen{}, fmt.Errorf("IDProfesional o IDConfPersonal es obligatorio")
+	}
+	if err := hs.resolverEntidadBloqueo(ctx, &req.IDProfesional, &req.IDConfPersonal, 0); err != nil {
+		return domain.BloqueoImportResumen{}, err
+	}
+
+	// 1) Expandir los eventos ocupados del calendario dentro del horizonte de agenda,
+	// con las horas flotantes y los días completos en la zona del profesional
+	zonaHoraria, err := hs.hr.GetZonaConfPersonal(ctx, req.IDConfPersonal)
+	if err != nil {
+		return domain.BloqueoImportResumen{}, err
+	}
+	loc, err := zona.Cargar(zonaHoraria)
+	if err != nil {
+		return domain.BloqueoImportResumen{}, err
+	}
+
+	desde := time.Now()
+	hasta := desde.AddDate(0, 0, maxDiasAgenda)
+
+	deseados, uids, err := bloqueosICal(strings.NewReader(req.Contenido), desde, hasta, loc)
+	if err != nil {
+		return domain.BloqueoImportResumen{}, err
+	}
+	for i := range deseados {
+		deseados[i].IDConfPersonal = req.IDConfPersonal
+		deseados[i].CreatedBy = req.CreatedBy
+	}
+
+	resumen := domain.BloqueoImportResumen{Colisiones: []domain.Reserva{}}
+	if len(uids) == 0 {
+		return resumen, nil
+	}
+
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+
+		// 2) Bloqueos importados antes para esos UID
+		existentes, err := hs.hr.GetBloqueosICal(ctx, tx, req.IDConfPersonal, uids, desde)
+		if err != nil {
+			return err
+		}
+		porClave := make(map[string]domain.AgendaBloqueo, len(existentes))
+		for _, b := range existentes {
+			porClave[claveOcurrencia(b.UID, b.Ocurrencia)] = b
+		}
+
+		// 3) Clasificar: sin cambios, nuevo o reemplazo; lo que sobra ya no está en el calendario
+		var crear []domain.AgendaBloqueo
+		borrar := map[int]bool{}
+
+		for _, b := range deseados {
+			clave := claveOcurrencia(b.UID, b.Ocurrencia)
+			anterior, ok := porClave[clave]
+			delete(porClave, clave)
+
+			switch {
+			case !ok:
+				resumen.Creados++
+				crear = append(crear, b)
+			case mismoBloqueo(anterior, b):
+				resumen.SinCambios++
+			default:
+				resumen.Actualizados++
+				borrar[anterior.ID] = true
+				crear = append(crear, b)
+			}
+		}
+		for _, b := range porClave {
+			resumen.Eliminados++
+			borrar[b.ID] = true
+		}
+
+		// 4) Borrar primero: los reemplazos reusan la clave UID + ocurrencia
+		for id := range borrar {
+			borrado, err := hs.hr.DeleteBloqueo(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if err := hs.reaplicarBloqueos(ctx, tx, borrado, borrar); err != nil {
+				return err
+			}
+		}
+
+		// 5) Crear los bloqueos nuevos, bloquear sus slots e informar las reservas que quedaron dentro
+		informadas := map[int]bool{}
+		for _, b := range crear {
+			if b.ID, err = hs.hr.CreateBloqueo(ctx, tx, b); err != nil {
+				return err
+			}
+			if _, err := hs.hr.BloquearSlots(ctx, tx, b); err != nil {
+				return err
+			}
+
+			reservas, err := hs.hr.GetReservasEnBloqueo(ctx, tx, b)
+			if err != nil {
+				return err
+			}
+			for _, r := range reservas {
+				if !informadas[r.ID] {
+					informadas[r.ID] = true
+					resumen.Colisiones = append(resumen.Colisiones, r)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.BloqueoImportResumen{}, err
+	}
+
+	fmt.Printf("📆 Calendario importado para conf_personal %d: %d creados, %d actualizados, %d eliminados, %d sin cambios\n",
+		req.IDConfPersonal, resumen.Creados, resumen.Actualizados, resumen.Eliminados, resumen.SinCambios)
+	return resumen, nil
+}
+
+// bloqueosICal expande los VEVENT ocupados a un bloqueo por ocurrencia entre desde y hasta.
+// Devuelve también todos los UID del calendario: un UID cancelado o transparente no genera
+// bloqueos pero sí borra los que se hayan importado antes.
+func bloqueosICal(r io.Reader, desde, hasta time.Time, loc *time.Location) ([]domain.AgendaBloqueo, []string, error) {
+	events, err := ical.ParseInLocation(r, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 1) UIDs del archivo y ocurrencias que un VEVENT con RECURRENCE-ID reemplaza
+	var uids []string
+	vistos := map[string]bool{}
+	reemplazadas := map[string]bool{}
+
+	for _, ev := range events {
+		if ev.UID == "" {
+			continue
+		}
+		if !vistos[ev.UID] {
+			vistos[ev.UID] = true
+			uids = append(uids, ev.UID)
+		}
+		if !ev.RecurrenceID.IsZero() {
+			reemplazadas[claveOcurrencia(ev.UID, enZonaProfesional(ev.RecurrenceID, ev.AllDay, loc))] = true
+		}
+	}
+
+	// 2) Un bloqueo por ocurrencia ocupada dentro del horizonte
+	var bloqueos []domain.AgendaBloqueo
+
+	agregar := func(ev ical.Event, inicio, ocurrencia time.Time) {
+		b := domain.AgendaBloqueo{
+			Inicio:     enZonaProfesional(inicio, ev.AllDay, loc),
+			Fin:        enZonaProfesional(inicio.Add(ev.End.Sub(ev.Start)), ev.AllDay, loc),
+			Motivo:     ev.Summary,
+			UID:        ev.UID,
+			Ocurrencia: ocurrencia,
+		}
+		if b.Motivo == "" {
+			b.Motivo = motivoBloqueoICal
+		}
+		if b.Fin.After(b.Inicio) && b.Fin.After(desde) && b.Inicio.Before(hasta) {
+			bloqueos = append(bloqueos, b)
+		}
+	}
+
+	for _, ev := range events {
+		if ev.UID == "" || ev.Transparent || ev.Status == ical.StatusCancelled {
+			continue
+		}
+
+		// Ocurrencia modificada: un solo bloqueo con los horarios nuevos
+		if !ev.RecurrenceID.IsZero() {
+			agregar(ev, ev.Start, enZonaProfesional(ev.RecurrenceID, ev.AllDay, loc))
+			continue
+		}
+
+		inicios := []time.Time{ev.Start}
+		if ev.RRule != "" {
+			regla, err := ical.ParseRRule(ev.RRule)
+			if err != nil {
+				return nil, nil, fmt.Errorf("evento %s: %w", ev.UID, err)
+			}
+			inicios = regla.Occurrences(ev.Start, hasta, maxOcurrenciasImport)
+		}
+
+		for _, inicio := range inicios {
+			ocurrencia := enZonaProfesional(inicio, ev.AllDay, loc)
+			if excluida(ev, inicio) || reemplazadas[claveOcurrencia(ev.UID, ocurrencia)] {
+				continue
+			}
+			agregar(ev, inicio, ocurrencia)
+		}
+	}
+
+	return bloqueos, uids, nil
+}
+
+// enZonaProfesional expresa un instante del calendario en la zona del profesional.
+// Los eventos de día completo van de medianoche a medianoche de esa zona.
+func enZonaProfesional(t time.Time, diaCompleto bool, loc *time.Location) time.Time {
+	if diaCompleto {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	return t.In(loc)
+}
+
+// excluida indica si la ocurrencia figura en un EXDATE del evento
+func excluida(ev ical.Event, inicio time.Time) bool {
+	for _, ex := range ev.ExDates {
+		if ev.AllDay && ex.Format("20060102") == inicio.Format("20060102") {
+			return true
+		}
+		if ex.Equal(inicio) {
+			return true
+		}
+	}
+	return false
+}
+
+// claveOcurrencia identifica una ocurrencia importada. Se compara en UTC porque lo que vuelve
+// de la base es el mismo instante pero no la misma zona con la que se importó.
+func claveOcurrencia(uid string, ocurrencia time.Time) string {
+	return uid + "|" + ocurrencia.UTC().Format(time.RFC3339)
+}
+
+func mismoBloqueo(a, b domain.AgendaBloqueo) bool {
+	return a.Inicio.Equal(b.Inicio) && a.Fin.Equal(b.Fin) && a.Motivo == b.Motivo
+}
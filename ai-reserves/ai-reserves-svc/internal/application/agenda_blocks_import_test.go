@@ -0,0 +1,95 @@
+package application
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBloqueosICal(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		// serie semanal con una excepción y una ocurrencia movida
+		"BEGIN:VEVENT", "UID:serie", "SUMMARY:Guardia", "DTSTART:20260302T120000Z", "DTEND:20260302T140000Z",
+		"RRULE:FREQ=WEEKLY;COUNT=4", "EXDATE:20260309T120000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:serie", "SUMMARY:Guardia", "RECURRENCE-ID:20260316T120000Z",
+		"DTSTART:20260316T150000Z", "DTEND:20260316T170000Z", "END:VEVENT",
+		// no ocupan tiempo, pero sus UID sí se informan
+		"BEGIN:VEVENT", "UID:libre", "DTSTART:20260303T120000Z", "DTEND:20260303T130000Z", "TRANSP:TRANSPARENT", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:cancelado", "DTSTART:20260304T120000Z", "DTEND:20260304T130000Z", "STATUS:CANCELLED", "END:VEVENT",
+		// fuera del horizonte
+		"BEGIN:VEVENT", "UID:viejo", "DTSTART:20250101T120000Z", "DTEND:20250101T130000Z", "END:VEVENT",
+		// sin SUMMARY
+		"BEGIN:VEVENT", "UID:anonimo", "DTSTART:20260305T120000Z", "DTEND:20260305T123000Z", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	desde := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	bloqueos, uids, err := bloqueosICal(strings.NewReader(cal), desde, hasta, time.UTC)
+	if err != nil {
+		t.Fatalf("bloqueosICal: %v", err)
+	}
+
+	if want := []string{"serie", "libre", "cancelado", "viejo", "anonimo"}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("uids = %v, se esperaba %v", uids, want)
+	}
+
+	utc := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+	esperados := []struct {
+		uid        string
+		motivo     string
+		inicio     time.Time
+		fin        time.Time
+		ocurrencia time.Time
+	}{
+		{"serie", "Guardia", utc(2, 12), utc(2, 14), utc(2, 12)},
+		{"serie", "Guardia", utc(23, 12), utc(23, 14), utc(23, 12)},
+		{"serie", "Guardia", utc(16, 15), utc(16, 17), utc(16, 12)},
+		{"anonimo", motivoBloqueoICal, utc(5, 12), utc(5, 12).Add(30 * time.Minute), utc(5, 12)},
+	}
+
+	if len(bloqueos) != len(esperados) {
+		t.Fatalf("bloqueosICal devolvió %d bloqueos, se esperaban %d: %+v", len(bloqueos), len(esperados), bloqueos)
+	}
+	for i, e := range esperados {
+		b := bloqueos[i]
+		if b.UID != e.uid || b.Motivo != e.motivo || !b.Inicio.Equal(e.inicio) || !b.Fin.Equal(e.fin) || !b.Ocurrencia.Equal(e.ocurrencia) {
+			t.Errorf("bloqueo %d = %s %q %s-%s (ocurrencia %s), se esperaba %s %q %s-%s (ocurrencia %s)", i,
+				b.UID, b.Motivo, b.Inicio.UTC(), b.Fin.UTC(), b.Ocurrencia.UTC(), e.uid, e.motivo, e.inicio, e.fin, e.ocurrencia)
+		}
+	}
+}
+
+func TestBloqueosICalZonaProfesional(t *testing.T) {
+	montevideo, err := time.LoadLocation("America/Montevideo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:congreso", "DTSTART;VALUE=DATE:20260310", "DTEND;VALUE=DATE:20260312", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:flotante", "DTSTART:20260313T090000", "DTEND:20260313T100000", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	bloqueos, _, err := bloqueosICal(strings.NewReader(cal),
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), montevideo)
+	if err != nil {
+		t.Fatalf("bloqueosICal: %v", err)
+	}
+	if len(bloqueos) != 2 {
+		t.Fatalf("bloqueosICal devolvió %d bloqueos, se esperaban 2", len(bloqueos))
+	}
+
+	// día completo: de medianoche a medianoche en la zona del profesional (UTC-3)
+	if b := bloqueos[0]; !b.Inicio.Equal(time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC)) || !b.Fin.Equal(time.Date(2026, 3, 12, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("día completo = %s-%s", b.Inicio.UTC(), b.Fin.UTC())
+	}
+	// hora flotante: hora de reloj del profesional
+	if b := bloqueos[1]; !b.Inicio.Equal(time.Date(2026, 3, 13, 12, 0, 0, 0, time.UTC)) || !b.Fin.Equal(time.Date(2026, 3, 13, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("hora flotante = %s-%s", b.Inicio.UTC(), b.Fin.UTC())
+	}
+}
@@ -0,0 +1,189 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+)
+
+// Máximo de días que se pueden generar en una sola llamada
+const maxDiasAgenda = 366
+
+// horarioAgenda resume la configuración de atención (horario + días hábiles + zona horaria)
+// de la que se generan las agendas diarias y sus slots
+type horarioAgenda struct {
+	horaInicio  time.Time
+	horaFin     time.Time
+	dias        map[time.Weekday]bool
+	zonaHoraria string
+	loc         *time.Location
+}
+
+func horarioDesdeConfPersonal(conf domain.ConfigPersonaFull) (horarioAgenda, error) {
+	return nuevoHorario(conf.ZonaHoraria, conf.HoraInicio, conf.HoraFin,
+		[7]bool{conf.Domingo, conf.Lunes, conf.Martes, conf.Miercoles, conf.Jueves, conf.Viernes, conf.Sabado})
+}
+
+func horarioDesdeConfEstablecimiento(conf domain.ConfEstablecimiento) (horarioAgenda, error) {
+	return nuevoHorario(conf.ZonaHoraria, conf.HoraInicio, conf.HoraFin,
+		[7]bool{conf.Domingo, conf.Lunes, conf.Martes, conf.Miercoles, conf.Jueves, conf.Viernes, conf.Sabado})
+}
+
+// nuevoHorario arma el horario de atención; los días hábiles van en el orden de time.Weekday (domingo primero)
+func nuevoHorario(zonaHoraria string, horaInicio, horaFin time.Time, dias [7]bool) (horarioAgenda, error) {
+	loc, err := zona.Cargar(zonaHoraria)
+	if err != nil {
+		return horarioAgenda{}, err
+	}
+
+	habiles := make(map[time.Weekday]bool, len(dias))
+	for d, habil := range dias {
+		habiles[time.Weekday(d)] = habil
+	}
+
+	return horarioAgenda{
+		horaInicio:  horaInicio,
+		horaFin:     horaFin,
+		dias:        habiles,
+		zonaHoraria: loc.String(),
+		loc:         loc,
+	}, nil
+}
+
+// truncarFecha deja solo la parte de fecha (sin hora) para comparar días
+func truncarFecha(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func validarRangoAgenda(desde, hasta time.Time) error {
+	if desde.IsZero() || hasta.IsZero() {
+		return fmt.Errorf("FechaDesde y FechaHasta son obligatorias")
+	}
+	if hasta.Before(desde) {
+		return fmt.Errorf("FechaHasta (%s) es anterior a FechaDesde (%s)",
+			hasta.Format("2006-01-02"), desde.Format("2006-01-02"))
+	}
+	if dias := int(truncarFecha(hasta).Sub(truncarFecha(desde)).Hours()/24) + 1; dias > maxDiasAgenda {
+		return fmt.Errorf("el rango de %d días supera el máximo de %d", dias, maxDiasAgenda)
+	}
+	return nil
+}
+
+// generarSlots parte el horario de atención de una fecha en slots consecutivos de la duración indicada.
+// Avanza por instantes reales: en los días de cambio de horario cada slot dura lo mismo aunque
+// la hora de reloj salte o se repita. Un slot que no entra completo antes de la hora de fin no se
+// genera, y una hora de reloj repetida se ofrece una sola vez (hora_inicio es única por agenda).
+// Si la apertura o el cierre caen en la hora que no existe, se toman desde el salto (zona.EnFechaAjustada).
+func generarSlots(h horarioAgenda, fecha time.Time, duracion time.Duration) []domain.AgendaSlot {
+	var slots []domain.AgendaSlot
+
+	inicio := zona.EnFechaAjustada(fecha, h.horaInicio, h.loc)
+	fin := zona.EnFechaAjustada(fecha, h.horaFin, h.loc)
+
+	vistas := map[string]bool{}
+	for t := inicio; !t.Add(duracion).After(fin); t = t.Add(duracion) {
+		horaInicio := t.Format("15:04")
+		if vistas[horaInicio] {
+			continue
+		}
+		vistas[horaInicio] = true
+
+		slots = append(slots, domain.AgendaSlot{
+			HoraInicio: horaInicio,
+			HoraFin:    t.Add(duracion).Format("15:04"),
+			Inicio:     t,
+			Fin:        t.Add(duracion),
+			Estado:     domain.SlotLibre,
+		})
+	}
+
+	return slots
+}
+
+// generarDiasAgenda arma una AgendaDia por cada día hábil del rango [desde, hasta]. Los slots
+// se calculan por día porque el desfasaje con UTC cambia con el horario de verano.
+func generarDiasAgenda(h horarioAgenda, desde, hasta time.Time, duracion time.Duration) []domain.AgendaDia {
+	var dias []domain.AgendaDia
+
+	for fecha := truncarFecha(desde); !fecha.After(truncarFecha(hasta)); fecha = fecha.AddDate(0, 0, 1) {
+		if !h.dias[fecha.Weekday()] {
+			continue
+		}
+
+		slots := generarSlots(h, fecha, duracion)
+		if len(slots) == 0 {
+			continue
+		}
+
+		dias = append(dias, domain.AgendaDia{
+			Fecha:       fecha,
+			ZonaHoraria: h.zonaHoraria,
+			Slots:       slots,
+		})
+	}
+
+	return dias
+}
+
+// duracionSubTipo elige la duración de los slots entre los sub tipos que ofrece el profesional
+func duracionSubTipo(subTipos []domain.ConfigPersonalSubTipo, idSubTipo int) (time.Duration, error) {
+	elegido, err := elegirSubTipo(subTipos, idSubTipo)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(elegido.DuracionReservaMinutos) * time.Minute, nil
+}
+
+// elegirSubTipo resuelve el sub tipo pedido (o el único que ofrece el profesional) con duración válida
+func elegirSubTipo(subTipos []domain.ConfigPersonalSubTipo, idSubTipo int) (domain.ConfigPersonalSubTipo, error) {
+	if len(subTipos) == 0 {
+		return domain.ConfigPersonalSubTipo{}, fmt.Errorf("el profesional no tiene sub tipos de unidad de reserva configurados")
+	}
+
+	elegido := subTipos[0]
+	if idSubTipo != 0 {
+		encontrado := false
+		for _, st := range subTipos {
+			if st.IDSubTipoUnidadReserva == idSubTipo {
+				elegido = st
+				encontrado = true
+				break
+			}
+		}
+		if !encontrado {
+			return domain.ConfigPersonalSubTipo{}, fmt.Errorf("el profesional no ofrece el sub_tipo_unidad_reserva %d", idSubTipo)
+		}
+	} else if len(subTipos) > 1 {
+		return domain.ConfigPersonalSubTipo{}, fmt.Errorf("el profesional ofrece %d sub tipos, indicar IDSubTipoUnidadReserva", len(subTipos))
+	}
+
+	if elegido.DuracionReservaMinutos <= 0 {
+		return domain.ConfigPersonalSubTipo{}, fmt.Errorf("sub_tipo_unidad_reserva %d sin duración de reserva configurada", elegido.IDSubTipoUnidadReserva)
+	}
+
+	return elegido, nil
+}
+
+// quitarFeriados descarta los días que caen en feriado y devuelve cuántos se descartaron
+func quitarFeriados(dias []domain.AgendaDia, feriados []domain.Feriado) ([]domain.AgendaDia, int) {
+	if len(feriados) == 0 {
+		return dias, 0
+	}
+
+	esFeriado := make(map[time.Time]bool, len(feriados))
+	for _, f := range feriados {
+		esFeriado[truncarFecha(f.Fecha)] = true
+	}
+
+	habiles := dias[:0]
+	for _, dia := range dias {
+		if esFeriado[truncarFecha(dia.Fecha)] {
+			continue
+		}
+		habiles = append(habiles, dia)
+	}
+
+	return habiles, len(dias) - len(habiles)
+}
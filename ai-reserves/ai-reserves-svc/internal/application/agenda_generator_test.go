@@ -0,0 +1,318 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+)
+
+func reloj(h, m int) time.Time {
+	return time.Date(0, 1, 1, h, m, 0, 0, time.UTC)
+}
+
+func TestHorarioDiasHabiles(t *testing.T) {
+	personal, err := horarioDesdeConfPersonal(domain.ConfigPersonaFull{
+		HoraInicio: reloj(9, 0), HoraFin: reloj(18, 0),
+		Lunes: true, Miercoles: true, Viernes: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	establecimiento, err := horarioDesdeConfEstablecimiento(domain.ConfEstablecimiento{
+		HoraInicio: reloj(10, 0), HoraFin: reloj(22, 0),
+		Sabado: true, Domingo: true, ZonaHoraria: "Europe/Madrid",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		h       horarioAgenda
+		habiles []time.Weekday
+	}{
+		{"profesional", personal, []time.Weekday{time.Monday, time.Wednesday, time.Friday}},
+		{"establecimiento", establecimiento, []time.Weekday{time.Sunday, time.Saturday}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var habiles []time.Weekday
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				if tt.h.dias[d] {
+					habiles = append(habiles, d)
+				}
+			}
+			if !reflect.DeepEqual(habiles, tt.habiles) {
+				t.Errorf("días hábiles = %v, se esperaba %v", habiles, tt.habiles)
+			}
+		})
+	}
+
+	if personal.zonaHoraria != zona.Default || establecimiento.zonaHoraria != "Europe/Madrid" {
+		t.Errorf("zonas = %s, %s; se esperaba %s, Europe/Madrid", personal.zonaHoraria, establecimiento.zonaHoraria, zona.Default)
+	}
+	if establecimiento.horaInicio != reloj(10, 0) || establecimiento.horaFin != reloj(22, 0) {
+		t.Errorf("horario del establecimiento = %s-%s", establecimiento.horaInicio.Format("15:04"), establecimiento.horaFin.Format("15:04"))
+	}
+}
+
+func TestGenerarSlots(t *testing.T) {
+	tests := []struct {
+		name      string
+		inicio    time.Time
+		fin       time.Time
+		duracion  time.Duration
+		esperados []string
+	}{
+		{"horario justo", reloj(9, 0), reloj(11, 0), 30 * time.Minute, []string{"09:00-09:30", "09:30-10:00", "10:00-10:30", "10:30-11:00"}},
+		{"el último no entra completo", reloj(9, 0), reloj(10, 15), 30 * time.Minute, []string{"09:00-09:30", "09:30-10:00"}},
+		{"horario que no empieza en punto", reloj(8, 45), reloj(10, 0), 25 * time.Minute, []string{"08:45-09:10", "09:10-09:35", "09:35-10:00"}},
+		{"duración más larga que el horario", reloj(9, 0), reloj(9, 45), time.Hour, nil},
+		{"sin horario", reloj(9, 0), reloj(9, 0), 30 * time.Minute, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			h := horarioAgenda{horaInicio: tt.inicio, horaFin: tt.fin, loc: time.UTC}
+			for _, s := range generarSlots(h, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), tt.duracion) {
+				if s.Estado != domain.SlotLibre {
+					t.Errorf("slot %s en estado %s", s.HoraInicio, s.Estado)
+				}
+				got = append(got, s.HoraInicio+"-"+s.HoraFin)
+			}
+			if !reflect.DeepEqual(got, tt.esperados) {
+				t.Errorf("generarSlots() = %v, se esperaba %v", got, tt.esperados)
+			}
+		})
+	}
+}
+
+func TestGenerarSlotsCambioDeHorario(t *testing.T) {
+	nuevaYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := horarioAgenda{horaInicio: reloj(0, 0), horaFin: reloj(4, 0), loc: nuevaYork}
+
+	tests := []struct {
+		name    string
+		fecha   time.Time
+		inicios []string
+		utc     []string
+	}{
+		// 2026-03-08 a las 02:00 el reloj salta a las 03:00: los slots siguen durando una hora
+		{"adelanto", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC),
+			[]string{"00:00", "01:00", "03:00"}, []string{"05:00", "06:00", "07:00"}},
+		// 2026-11-01 a las 02:00 el reloj vuelve a la 01:00: la 01:00 repetida se ofrece una vez
+		{"atraso", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			[]string{"00:00", "01:00", "02:00", "03:00"}, []string{"04:00", "05:00", "07:00", "08:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inicios, utc []string
+			for _, s := range generarSlots(h, tt.fecha, time.Hour) {
+				if s.Fin.Sub(s.Inicio) != time.Hour {
+					t.Errorf("slot %s dura %s", s.HoraInicio, s.Fin.Sub(s.Inicio))
+				}
+				inicios = append(inicios, s.HoraInicio)
+				utc = append(utc, s.Inicio.UTC().Format("15:04"))
+			}
+			if !reflect.DeepEqual(inicios, tt.inicios) || !reflect.DeepEqual(utc, tt.utc) {
+				t.Errorf("generarSlots() = %v (UTC %v), se esperaba %v (UTC %v)", inicios, utc, tt.inicios, tt.utc)
+			}
+		})
+	}
+}
+
+func TestGenerarSlotsBordeEnElSalto(t *testing.T) {
+	nuevaYork, err := zona.Cargar("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	santiago, err := zona.Cargar("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		h       horarioAgenda
+		fecha   time.Time
+		inicios []string
+		utc     []string
+	}{
+		// 2026-03-08 las 02:30 no existen: abre cuando el reloj salta a las 03:00
+		{"abre dentro del salto", horarioAgenda{horaInicio: reloj(2, 30), horaFin: reloj(5, 0), loc: nuevaYork},
+			time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), []string{"03:00", "04:00"}, []string{"07:00", "08:00"}},
+		// Cierra a las 02:30 que no existen: el día termina con el salto
+		{"cierra dentro del salto", horarioAgenda{horaInicio: reloj(0, 0), horaFin: reloj(2, 30), loc: nuevaYork},
+			time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), []string{"00:00", "01:00"}, []string{"05:00", "06:00"}},
+		// 2026-09-06 la medianoche de Santiago no existe: nada se corre al día anterior
+		{"medianoche que no existe", horarioAgenda{horaInicio: reloj(0, 0), horaFin: reloj(3, 0), loc: santiago},
+			time.Date(2026, 9, 6, 0, 0, 0, 0, time.UTC), []string{"01:00", "02:00"}, []string{"04:00", "05:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inicios, utc []string
+			for _, s := range generarSlots(tt.h, tt.fecha, time.Hour) {
+				inicios = append(inicios, s.HoraInicio)
+				utc = append(utc, s.Inicio.UTC().Format("15:04"))
+			}
+			if !reflect.DeepEqual(inicios, tt.inicios) || !reflect.DeepEqual(utc, tt.utc) {
+				t.Errorf("generarSlots() = %v (UTC %v), se esperaba %v (UTC %v)", inicios, utc, tt.inicios, tt.utc)
+			}
+		})
+	}
+}
+
+func TestGenerarDiasAgenda(t *testing.T) {
+	semana := horarioAgenda{
+		horaInicio: reloj(9, 0),
+		horaFin:    reloj(10, 0),
+		dias: map[time.Weekday]bool{
+			time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true,
+		},
+		loc: time.UTC,
+	}
+	finDeSemana := horarioAgenda{
+		horaInicio: reloj(9, 0),
+		horaFin:    reloj(10, 0),
+		dias:       map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		loc:        time.UTC,
+	}
+
+	// 2026-03-06 es viernes
+	desde := time.Date(2026, 3, 6, 18, 30, 0, 0, time.UTC)
+	hasta := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		h        horarioAgenda
+		duracion time.Duration
+		fechas   []string
+	}{
+		{"lunes a viernes", semana, 30 * time.Minute, []string{"2026-03-06", "2026-03-09", "2026-03-10"}},
+		{"sábado y domingo", finDeSemana, 30 * time.Minute, []string{"2026-03-07", "2026-03-08"}},
+		{"sin días hábiles", horarioAgenda{horaInicio: reloj(9, 0), horaFin: reloj(10, 0), loc: time.UTC}, 30 * time.Minute, nil},
+		{"sin slots no se genera la agenda", semana, 2 * time.Hour, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fechas []string
+			for _, dia := range generarDiasAgenda(tt.h, desde, hasta, tt.duracion) {
+				if len(dia.Slots) != 2 {
+					t.Errorf("%s con %d slots, se esperaban 2", dia.Fecha.Format("2006-01-02"), len(dia.Slots))
+				}
+				fechas = append(fechas, dia.Fecha.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(fechas, tt.fechas) {
+				t.Errorf("generarDiasAgenda() = %v, se esperaba %v", fechas, tt.fechas)
+			}
+		})
+	}
+}
+
+func TestValidarRangoAgenda(t *testing.T) {
+	desde := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := validarRangoAgenda(desde, desde.AddDate(0, 0, maxDiasAgenda-1)); err != nil {
+		t.Errorf("rango de %d días: %v", maxDiasAgenda, err)
+	}
+	if err := validarRangoAgenda(desde, desde); err != nil {
+		t.Errorf("rango de un día: %v", err)
+	}
+	if err := validarRangoAgenda(desde, desde.AddDate(0, 0, maxDiasAgenda)); err == nil {
+		t.Errorf("rango de %d días aceptado", maxDiasAgenda+1)
+	}
+	if err := validarRangoAgenda(desde, desde.AddDate(0, 0, -1)); err == nil {
+		t.Error("FechaHasta anterior a FechaDesde aceptada")
+	}
+	if err := validarRangoAgenda(time.Time{}, desde); err == nil {
+		t.Error("FechaDesde vacía aceptada")
+	}
+}
+
+func TestDuracionSubTipo(t *testing.T) {
+	corto := domain.ConfigPersonalSubTipo{IDSubTipoUnidadReserva: 1, DuracionReservaMinutos: 20}
+	largo := domain.ConfigPersonalSubTipo{IDSubTipoUnidadReserva: 2, DuracionReservaMinutos: 60}
+	sinDuracion := domain.ConfigPersonalSubTipo{IDSubTipoUnidadReserva: 3}
+
+	tests := []struct {
+		name     string
+		subTipos []domain.ConfigPersonalSubTipo
+		pedido   int
+		duracion time.Duration
+		ok       bool
+	}{
+		{"único sub tipo", []domain.ConfigPersonalSubTipo{corto}, 0, 20 * time.Minute, true},
+		{"sub tipo pedido", []domain.ConfigPersonalSubTipo{corto, largo}, 2, time.Hour, true},
+		{"varios sin indicar cuál", []domain.ConfigPersonalSubTipo{corto, largo}, 0, 0, false},
+		{"sub tipo que no ofrece", []domain.ConfigPersonalSubTipo{corto}, 9, 0, false},
+		{"sin sub tipos", nil, 0, 0, false},
+		{"sin duración configurada", []domain.ConfigPersonalSubTipo{sinDuracion}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := duracionSubTipo(tt.subTipos, tt.pedido)
+			if tt.ok != (err == nil) {
+				t.Fatalf("duracionSubTipo() error = %v", err)
+			}
+			if got != tt.duracion {
+				t.Errorf("duracionSubTipo() = %s, se esperaba %s", got, tt.duracion)
+			}
+		})
+	}
+}
+
+func TestQuitarFeriados(t *testing.T) {
+	h := horarioAgenda{
+		horaInicio: reloj(9, 0),
+		horaFin:    reloj(10, 0),
+		dias:       map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true},
+		loc:        time.UTC,
+	}
+	// lunes 2026-03-23 a miércoles 2026-03-25
+	dias := func() []domain.AgendaDia {
+		return generarDiasAgenda(h, time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 25, 0, 0, 0, 0, time.UTC), time.Hour)
+	}
+
+	tests := []struct {
+		name        string
+		feriados    []domain.Feriado
+		fechas      []string
+		descartados int
+	}{
+		{"sin feriados", nil, []string{"2026-03-23", "2026-03-24", "2026-03-25"}, 0},
+		{"feriado en día hábil", []domain.Feriado{{Fecha: time.Date(2026, 3, 24, 0, 0, 0, 0, time.UTC)}}, []string{"2026-03-23", "2026-03-25"}, 1},
+		{"feriado con hora se compara por fecha", []domain.Feriado{{Fecha: time.Date(2026, 3, 23, 15, 0, 0, 0, time.UTC)}}, []string{"2026-03-24", "2026-03-25"}, 1},
+		{"feriado fuera del rango", []domain.Feriado{{Fecha: time.Date(2026, 3, 26, 0, 0, 0, 0, time.UTC)}}, []string{"2026-03-23", "2026-03-24", "2026-03-25"}, 0},
+		{"todos feriados", []domain.Feriado{
+			{Fecha: time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC)},
+			{Fecha: time.Date(2026, 3, 24, 0, 0, 0, 0, time.UTC)},
+			{Fecha: time.Date(2026, 3, 25, 0, 0, 0, 0, time.UTC)},
+		}, nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			habiles, descartados := quitarFeriados(dias(), tt.feriados)
+
+			var fechas []string
+			for _, dia := range habiles {
+				fechas = append(fechas, dia.Fecha.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(fechas, tt.fechas) || descartados != tt.descartados {
+				t.Errorf("quitarFeriados() = %v, %d; se esperaba %v, %d", fechas, descartados, tt.fechas, tt.descartados)
+			}
+		})
+	}
+}
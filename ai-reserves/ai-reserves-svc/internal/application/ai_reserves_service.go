@@ -2,11 +2,15 @@ package application
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/config"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
 )
 
@@ -26,13 +30,10 @@ func NewAiReservesService(hr ports.AiReservesRepository, conf config.App, rmq po
 	}
 }
 
-func (hs *AiReservesService) CreatePersonaAPI(ctx context.Context, req domain.PersonCreatedPayload) error {
-
-	if err := hs.hr.CreatePersona(ctx, req); err != nil {
-		return err
-	}
-
-	return nil
+func (hs *AiReservesService) CreatePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonCreatedPayload) error {
+	return hs.procesarEvento(ctx, evt, func(tx *sql.Tx) error {
+		return hs.hr.CreatePersona(ctx, tx, req)
+	})
 }
 
 func (hs *AiReservesService) UpdAtributoPersonaAPI(ctx context.Context, req domain.PersonaParcial) error {
@@ -139,39 +140,539 @@ func (hs *AiReservesService) UpdAtributeSubTipoUnidadReservaAPI(ctx context.Cont
 
 func (hs *AiReservesService) CreateReserveAPI(ctx context.Context, req domain.Reserva) error {
 
-	if err := hs.hr.CreateReserve(ctx, req); err != nil {
+	// Solo CreateSerieAPI vincula reservas a una serie
+	req.IDSerie = 0
+
+	req, multiagenda, err := hs.prepararReserva(ctx, req)
+	if err != nil {
+		return err
+	}
+	if multiagenda != nil && req.TokenRetencion != "" {
+		return fmt.Errorf("%w: las agendas MULTIAGENDA no usan retenciones de slot", domain.ErrRetencionInvalida)
+	}
+
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		_, err := hs.reservar(ctx, tx, req, multiagenda)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
-func (hs *AiReservesService) CancelReserveAPI(ctx context.Context, idReserva int) error {
 
-	if err := hs.hr.CancelReserve(ctx, idReserva); err != nil {
-		return err
+// prepararReserva valida el pedido y resuelve la agenda del día. Para un profesional
+// MULTIAGENDA no hay agenda previa: devuelve su configuración para calcular el turno.
+func (hs *AiReservesService) prepararReserva(ctx context.Context, req domain.Reserva) (domain.Reserva, *domain.ConfigPersonaFull, error) {
+
+	// Una reserva solo puede nacer PENDIENTE o ya CONFIRMADA; el resto de los estados se alcanza por transición
+	if req.Estado != "" && req.Estado != domain.ReservaPendiente && req.Estado != domain.ReservaConfirmada {
+		return req, nil, fmt.Errorf("%w: una reserva no puede crearse en estado %s", domain.ErrReservaEstadoInvalido, req.Estado)
 	}
 
-	return nil
+	// Reserva de un espacio físico: la agenda se resuelve por establecimiento + fecha
+	if req.IDAgenda == 0 && req.IDConfEstablecimiento != 0 {
+		conf, err := hs.hr.GetConfEstablecimiento(ctx, req.IDConfEstablecimiento)
+		if err != nil {
+			return req, nil, err
+		}
+
+		if req.IDSubTipoUnidadReserva == 0 {
+			req.IDSubTipoUnidadReserva = conf.IDSubTipoUnidadReserva
+		} else if req.IDSubTipoUnidadReserva != conf.IDSubTipoUnidadReserva {
+			return req, nil, fmt.Errorf("el establecimiento %d no ofrece el sub_tipo_unidad_reserva %d",
+				conf.ID, req.IDSubTipoUnidadReserva)
+		}
+
+		req.IDAgenda, err = hs.hr.GetIDAgenda(ctx, 0, conf.ID, req.Fecha)
+		if err != nil {
+			return req, nil, err
+		}
+	}
+
+	// Reserva con un profesional: PREGENERADA usa el slot de su agenda del día,
+	// MULTIAGENDA calcula el turno en el momento
+	if req.IDAgenda == 0 && req.IDProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return req, nil, err
+		}
+
+		if conf.ModoAgenda == domain.ModoAgendaMultiagenda {
+			return req, &conf, nil
+		}
+		if req.IDAgenda, err = hs.hr.GetIDAgenda(ctx, conf.ID, 0, req.Fecha); err != nil {
+			return req, nil, err
+		}
+	}
+
+	return req, nil, nil
+}
+
+// reservar crea la reserva ya preparada y registra su alta en el historial
+func (hs *AiReservesService) reservar(ctx context.Context, tx *sql.Tx, req domain.Reserva, multiagenda *domain.ConfigPersonaFull) (int, error) {
+	var idReserva int
+	var err error
+
+	// Un usuario revocado o eliminado en auth-security no toma reservas nuevas
+	if req.IDPaciente != nil {
+		baja, err := hs.hr.PersonaDadaDeBaja(ctx, tx, *req.IDPaciente)
+		if err != nil {
+			return 0, err
+		}
+		if baja {
+			return 0, fmt.Errorf("%w: persona %d", domain.ErrPersonaDadaDeBaja, *req.IDPaciente)
+		}
+	}
+
+	if multiagenda != nil {
+		idReserva, err = hs.reservarMultiagenda(ctx, tx, req, *multiagenda)
+	} else {
+		idReserva, err = hs.hr.CreateReserve(ctx, tx, req)
+	}
+	if err != nil {
+		return 0, err // rollback
+	}
+
+	// Recursos adicionales (otro profesional, un espacio) en el mismo horario
+	if err := hs.ocuparRecursos(ctx, tx, idReserva, req.Recursos()); err != nil {
+		return 0, err
+	}
+
+	// Reglas del sub tipo sobre la reserva ya grabada: si no se cumplen, rollback
+	if err := hs.validarReglasReserva(ctx, tx, idReserva); err != nil {
+		return 0, err
+	}
+
+	estado := req.Estado
+	if estado == "" {
+		estado = domain.ReservaPendiente
+	}
+
+	err = hs.hr.InsertHistorialReserva(ctx, tx, domain.ReservaHistorial{
+		IDReserva:   idReserva,
+		EstadoNuevo: estado,
+		Actor:       actorSistema,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// El alta se anuncia con la reserva tal como quedó grabada
+	reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, idReserva)
+	if err != nil {
+		return 0, err
+	}
+	reserva.IDSerie = req.IDSerie
+	if err := hs.encolarEvento(ctx, tx, domain.EventReserveCreated, reservaPayload(reserva, reserva.Estado, actorSistema)); err != nil {
+		return 0, err
+	}
+
+	return idReserva, nil
 }
-func (hs *AiReservesService) SearchReserveAPI(ctx context.Context, req domain.SearchReserve) error {
 
-	if err := hs.hr.SearchReserve(ctx, req); err != nil {
+func (hs *AiReservesService) CancelReserveAPI(ctx context.Context, req domain.ReservaCancel) error {
+
+	if req.Status != "" && req.Status != domain.ReservaCancelada {
+		return fmt.Errorf("status '%s' inválido para cancelar, se espera %s", req.Status, domain.ReservaCancelada)
+	}
+	req.Status = domain.ReservaCancelada
+
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		reserva, err := hs.cancelar(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		// El turno liberado pasa directo a la lista de espera, si hay alguien esperándolo
+		_, err = hs.ofrecerReservaCancelada(ctx, tx, reserva)
 		return err
+	})
+}
+
+// cancelar aplica la cancelación dentro de la transacción: estado, política de anticipación,
+// liberación del slot, historial y evento. Devuelve la reserva tal como estaba antes de cancelarla.
+func (hs *AiReservesService) cancelar(ctx context.Context, tx *sql.Tx, req domain.ReservaCancel) (domain.Reserva, error) {
+
+	// 1) Bloquear la reserva
+	reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, req.IDReserva)
+	if err != nil {
+		return domain.Reserva{}, err
 	}
 
-	return nil
+	inicio, err := inicioReserva(reserva)
+	if err != nil {
+		return domain.Reserva{}, err
+	}
+
+	if err := domain.ValidarTransicion(reserva.Estado, domain.ReservaCancelada, inicio, time.Now()); err != nil {
+		return domain.Reserva{}, fmt.Errorf("reserva %d: %w", reserva.ID, err)
+	}
+
+	// 2) Política de anticipación mínima del sub tipo
+	horas, err := hs.hr.GetAnticipacionCancelacion(ctx, tx, reserva.IDSubTipoUnidadReserva)
+	if err != nil {
+		return domain.Reserva{}, err
+	}
+
+	if err := validarPlazoCancelacion(inicio, horas, time.Now()); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	// 3) Cancelar, liberar el slot y registrar la transición
+	if err := hs.anularReserva(ctx, tx, reserva, req); err != nil {
+		return domain.Reserva{}, err
+	}
+
+	return reserva, nil
 }
-func (hs *AiReservesService) InitAgendaAPI(ctx context.Context, req domain.Agenda) error {
 
-	if err := hs.hr.InitAgenda(ctx, req); err != nil {
+// anularReserva cancela la reserva ya bloqueada y validada: libera su slot, registra el historial
+// y encola reserve.cancelled
+func (hs *AiReservesService) anularReserva(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, req domain.ReservaCancel) error {
+	if err := hs.hr.CancelReserve(ctx, tx, req); err != nil {
 		return err
 	}
 
+	err := hs.hr.InsertHistorialReserva(ctx, tx, historialDe(reserva, domain.ReservaTransicion{
+		IDReserva: req.IDReserva,
+		Estado:    domain.ReservaCancelada,
+		Actor:     req.CanceladoPor,
+		Motivo:    req.Motivo,
+	}))
+	if err != nil {
+		return err
+	}
+
+	return hs.encolarEvento(ctx, tx, domain.EventReserveCanceled, reservaCanceladaPayload(reserva, req))
+}
+
+// validarPlazoCancelacion controla que falten al menos horas de anticipación hasta el inicio del turno;
+// cero horas es sin política
+func validarPlazoCancelacion(inicio time.Time, horas int, ahora time.Time) error {
+	if limite := inicio.Add(-time.Duration(horas) * time.Hour); horas > 0 && ahora.After(limite) {
+		return fmt.Errorf("%w: se requieren %d horas de anticipación (límite %s)",
+			domain.ErrCancelacionFueraDePlazo, horas, limite.Format("2006-01-02 15:04"))
+	}
 	return nil
 }
 
+func reservaCanceladaPayload(reserva domain.Reserva, req domain.ReservaCancel) domain.ReservaCanceladaPayload {
+	return domain.ReservaCanceladaPayload{
+		IDReserva:              reserva.ID,
+		IDAgenda:               reserva.IDAgenda,
+		IDPaciente:             reserva.IDPaciente,
+		IDSubTipoUnidadReserva: reserva.IDSubTipoUnidadReserva,
+		Fecha:                  reserva.Fecha.Format("2006-01-02"),
+		HoraInicio:             reserva.HoraInicio,
+		HoraFin:                reserva.HoraFin,
+		CanceladoPor:           req.CanceladoPor,
+		Motivo:                 req.Motivo,
+	}
+}
+
+func (hs *AiReservesService) ConfirmReserveAPI(ctx context.Context, req domain.ReservaTransicion) error {
+	req.Estado = domain.ReservaConfirmada
+	return hs.transicionarReserva(ctx, req)
+}
+
+func (hs *AiReservesService) CompleteReserveAPI(ctx context.Context, req domain.ReservaTransicion) error {
+	req.Estado = domain.ReservaFinalizada
+	return hs.transicionarReserva(ctx, req)
+}
+
+func (hs *AiReservesService) NoShowReserveAPI(ctx context.Context, req domain.ReservaTransicion) error {
+	req.Estado = domain.ReservaAusente
+	return hs.transicionarReserva(ctx, req)
+}
+
+// transicionarReserva aplica un cambio de estado validado por la máquina de estados del dominio
+// y lo deja registrado en el historial (y, si confirma, en el outbox) dentro de la misma transacción
+func (hs *AiReservesService) transicionarReserva(ctx context.Context, req domain.ReservaTransicion) error {
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, req.IDReserva)
+		if err != nil {
+			return err
+		}
+
+		// Las reservas sin inicio grabado se ubican por fecha y hora en la zona de su agenda
+		inicio, err := inicioReserva(reserva)
+		if err != nil {
+			return err
+		}
+
+		if err := domain.ValidarTransicion(reserva.Estado, req.Estado, inicio, time.Now()); err != nil {
+			return fmt.Errorf("reserva %d: %w", reserva.ID, err)
+		}
+
+		if err := hs.hr.UpdEstadoReserva(ctx, tx, req); err != nil {
+			return err
+		}
+
+		if err := hs.hr.InsertHistorialReserva(ctx, tx, historialDe(reserva, req)); err != nil {
+			return err
+		}
+
+		if req.Estado == domain.ReservaConfirmada {
+			return hs.encolarEvento(ctx, tx, domain.EventReserveConfirmed, reservaPayload(reserva, req.Estado, req.Actor))
+		}
+		return nil
+	})
+}
+
+func (hs *AiReservesService) GetHistorialReservaAPI(ctx context.Context, idReserva int) ([]domain.ReservaHistorial, error) {
+	historial, err := hs.hr.GetHistorialReserva(ctx, idReserva)
+	if err != nil {
+		return nil, err
+	}
+	return historial, nil
+}
+
+// actorSistema identifica los cambios que no vienen de una persona autenticada
+const actorSistema = "ai_reserves"
+
+// historialDe arma la entrada de historial de una transición sobre la reserva en su estado actual
+func historialDe(reserva domain.Reserva, req domain.ReservaTransicion) domain.ReservaHistorial {
+	estadoAnterior := reserva.Estado
+
+	h := domain.ReservaHistorial{
+		IDReserva:      reserva.ID,
+		EstadoAnterior: &estadoAnterior,
+		EstadoNuevo:    req.Estado,
+		Actor:          req.Actor,
+	}
+	if h.Actor == "" {
+		h.Actor = actorSistema
+	}
+	if req.Motivo != "" {
+		h.Motivo = &req.Motivo
+	}
+	return h
+}
+
+// inicioReserva devuelve el instante de inicio de la reserva: el grabado o, si todavía no lo tiene,
+// la fecha y hora de reloj ubicadas en la zona horaria de su agenda
+func inicioReserva(r domain.Reserva) (time.Time, error) {
+	if !r.Inicio.IsZero() {
+		return r.Inicio, nil
+	}
+	return instanteReserva(r, r.HoraInicio)
+}
+
+// finReserva es el equivalente de inicioReserva para la hora de fin
+func finReserva(r domain.Reserva) (time.Time, error) {
+	if !r.Fin.IsZero() {
+		return r.Fin, nil
+	}
+	return instanteReserva(r, r.HoraFin)
+}
+
+func instanteReserva(r domain.Reserva, hora string) (time.Time, error) {
+	loc, err := zona.Cargar(r.ZonaHoraria)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := zona.Instante(r.Fecha, hora, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reserva %d: %w", r.ID, err)
+	}
+	return t, nil
+}
+func (hs *AiReservesService) SearchReserveAPI(ctx context.Context, req domain.SearchReserve) (domain.ResultadoBusqueda, error) {
+
+	if err := validarBusqueda(&req); err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+	if err := validarRecursos(req.Recursos()); err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	// Los profesionales MULTIAGENDA no tienen slots: su disponibilidad se calcula
+	if req.IDProfesional != 0 && req.IDConfEstablecimiento == 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return domain.ResultadoBusqueda{}, err
+		}
+		if conf.ModoAgenda == domain.ModoAgendaMultiagenda {
+			return hs.buscarMultiagenda(ctx, req, conf)
+		}
+	}
+
+	slots, total, err := hs.hr.SearchReserve(ctx, req)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	return domain.ResultadoBusqueda{
+		Dias:          agruparPorDia(slots),
+		Pagina:        req.Pagina,
+		TamanioPagina: req.TamanioPagina,
+		TotalSlots:    total,
+	}, nil
+}
+func (hs *AiReservesService) InitAgendaAPI(ctx context.Context, req domain.Agenda) (domain.AgendaResumen, error) {
+
+	if err := validarRangoAgenda(req.FechaDesde, req.FechaHasta); err != nil {
+		return domain.AgendaResumen{}, err
+	}
+
+	// 1) Generar días y slots: de un establecimiento o de un profesional
+	// (los feriados se saltean salvo que la configuración tenga genera_feriados)
+	var dias []domain.AgendaDia
+	var diasFeriado int
+	var err error
+
+	if req.IDConfEstablecimiento != 0 {
+		dias, diasFeriado, err = hs.diasAgendaEstablecimiento(ctx, req)
+	} else {
+		dias, diasFeriado, err = hs.diasAgendaProfesional(ctx, req)
+	}
+	if err != nil {
+		return domain.AgendaResumen{}, err
+	}
+
+	// 2) Persistir todo en una transacción
+	var resumen domain.AgendaResumen
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		resumen, err = hs.hr.InitAgenda(ctx, tx, dias)
+		if err != nil {
+			return err
+		}
+
+		// Los slots nuevos respetan los bloqueos ya cargados
+		return hs.aplicarBloqueos(ctx, tx, dias)
+	})
+	if err != nil {
+		return domain.AgendaResumen{}, err
+	}
+
+	resumen.DiasFeriado = diasFeriado
+	return resumen, nil
+}
+
+func (hs *AiReservesService) diasAgendaProfesional(ctx context.Context, req domain.Agenda) ([]domain.AgendaDia, int, error) {
+
+	conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if conf.ModoAgenda != domain.ModoAgendaPregenerada {
+		return nil, 0, fmt.Errorf("el profesional %d usa modo_agenda %s, no se pregeneran agendas",
+			req.IDProfesional, conf.ModoAgenda)
+	}
+
+	// Duración y capacidad de los slots según el sub tipo que ofrece
+	subTipos, err := hs.hr.GetSubTiposConfPersonal(ctx, conf.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	subTipo, err := elegirSubTipo(subTipos, req.IDSubTipoUnidadReserva)
+	if err != nil {
+		return nil, 0, err
+	}
+	duracion := time.Duration(subTipo.DuracionReservaMinutos) * time.Minute
+
+	capacidad, err := hs.hr.GetCapacidadSubTipo(ctx, subTipo.IDSubTipoUnidadReserva)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	h, err := horarioDesdeConfPersonal(conf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dias := generarDiasAgenda(h, req.FechaDesde, req.FechaHasta, duracion)
+
+	// Un profesional solo respeta los feriados nacionales
+	dias, diasFeriado, err := hs.filtrarFeriados(ctx, dias, conf.GeneraFeriados, domain.FeriadoFiltro{
+		FechaDesde: req.FechaDesde,
+		FechaHasta: req.FechaHasta,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range dias {
+		dias[i].IDConfPersonal = conf.ID
+		dias[i].Capacidad = capacidad
+	}
+
+	return dias, diasFeriado, nil
+}
+
+func (hs *AiReservesService) diasAgendaEstablecimiento(ctx context.Context, req domain.Agenda) ([]domain.AgendaDia, int, error) {
+
+	conf, err := hs.hr.GetConfEstablecimiento(ctx, req.IDConfEstablecimiento)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// El espacio físico tiene un único sub tipo y su duración estándar
+	minutos, err := hs.hr.GetDuracionSubTipo(ctx, conf.IDSubTipoUnidadReserva)
+	if err != nil {
+		return nil, 0, err
+	}
+	if minutos <= 0 {
+		return nil, 0, fmt.Errorf("sub_tipo_unidad_reserva %d sin duración de reserva configurada", conf.IDSubTipoUnidadReserva)
+	}
+
+	capacidad, err := hs.hr.GetCapacidadSubTipo(ctx, conf.IDSubTipoUnidadReserva)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	h, err := horarioDesdeConfEstablecimiento(conf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dias := generarDiasAgenda(h, req.FechaDesde, req.FechaHasta, time.Duration(minutos)*time.Minute)
+
+	// Feriados nacionales + cierres propios del establecimiento
+	dias, diasFeriado, err := hs.filtrarFeriados(ctx, dias, conf.GeneraFeriados, domain.FeriadoFiltro{
+		IDConfEstablecimiento: conf.ID,
+		FechaDesde:            req.FechaDesde,
+		FechaHasta:            req.FechaHasta,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range dias {
+		dias[i].IDConfEstablecimiento = conf.ID
+		dias[i].Capacidad = capacidad
+	}
+
+	return dias, diasFeriado, nil
+}
+
+// filtrarFeriados saca de la agenda los feriados del filtro, salvo que se trabaje en feriados
+func (hs *AiReservesService) filtrarFeriados(ctx context.Context, dias []domain.AgendaDia, generaFeriados bool, filtro domain.FeriadoFiltro) ([]domain.AgendaDia, int, error) {
+	if generaFeriados || len(dias) == 0 {
+		return dias, 0, nil
+	}
+
+	feriados, err := hs.hr.GetFeriados(ctx, filtro)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dias, diasFeriado := quitarFeriados(dias, feriados)
+	return dias, diasFeriado, nil
+}
+
 func (hs *AiReservesService) InsertFullConfigPersonaAPI(ctx context.Context, req domain.ConfigPersonaFull) error {
 
+	zonaHoraria, err := zona.Validar(req.ZonaHoraria)
+	if err != nil {
+		return err
+	}
+	req.ZonaHoraria = zonaHoraria
+
 	if err := hs.hr.InsertFullConfigPersona(ctx, req); err != nil {
 		return err
 	}
@@ -221,6 +722,12 @@ func (hs *AiReservesService) GetReservasUnidadReservaAPI(ctx context.Context, re
 
 func (hs *AiReservesService) InsertOrUpdateConfEstablecimientoAPI(ctx context.Context, req domain.ConfEstablecimiento) error {
 
+	zonaHoraria, err := zona.Validar(req.ZonaHoraria)
+	if err != nil {
+		return err
+	}
+	req.ZonaHoraria = zonaHoraria
+
 	if err := hs.hr.InsertOrUpdateConfEstablecimiento(ctx, req); err != nil {
 		return err
 	}
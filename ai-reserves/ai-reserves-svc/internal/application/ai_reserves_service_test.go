@@ -0,0 +1,109 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+func TestValidarPlazoCancelacion(t *testing.T) {
+	inicio := time.Date(2026, 5, 20, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		horas  int
+		ahora  time.Time
+		valido bool
+	}{
+		{"sin política", 0, inicio.Add(-time.Minute), true},
+		{"sin política con el turno empezado", 0, inicio.Add(time.Hour), true},
+		{"con anticipación de sobra", 24, inicio.Add(-48 * time.Hour), true},
+		{"justo en el límite", 24, inicio.Add(-24 * time.Hour), true},
+		{"un minuto después del límite", 24, inicio.Add(-24*time.Hour + time.Minute), false},
+		{"el mismo día", 2, inicio.Add(-time.Hour), false},
+		{"turno ya empezado", 2, inicio.Add(time.Hour), false},
+		{"ahora en otra zona", 3, inicio.Add(-4 * time.Hour).In(time.FixedZone("ART", -3*3600)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validarPlazoCancelacion(inicio, tt.horas, tt.ahora)
+			if tt.valido && err != nil {
+				t.Fatalf("validarPlazoCancelacion(%d horas) = %v, se esperaba nil", tt.horas, err)
+			}
+			if !tt.valido && !errors.Is(err, domain.ErrCancelacionFueraDePlazo) {
+				t.Fatalf("validarPlazoCancelacion(%d horas) = %v, se esperaba ErrCancelacionFueraDePlazo", tt.horas, err)
+			}
+		})
+	}
+}
+
+// repoTransicion atiende solo lo que lee y graba un cambio de estado de la reserva
+type repoTransicion struct {
+	ports.AiReservesRepository
+	reserva     domain.Reserva
+	actualizada bool
+}
+
+func (r *repoTransicion) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (r *repoTransicion) GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error) {
+	return r.reserva, nil
+}
+
+func (r *repoTransicion) UpdEstadoReserva(ctx context.Context, tx *sql.Tx, req domain.ReservaTransicion) error {
+	r.actualizada = true
+	return nil
+}
+
+func (r *repoTransicion) InsertHistorialReserva(ctx context.Context, tx *sql.Tx, h domain.ReservaHistorial) error {
+	return nil
+}
+
+func TestTransicionarReservaSinInicioGrabado(t *testing.T) {
+	// Reservas previas a inicio_utc: el inicio sale de fecha y hora en la zona de la agenda
+	ayer := time.Now().AddDate(0, 0, -1)
+	manana := time.Now().AddDate(0, 0, 1)
+	fecha := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		reserva domain.Reserva
+		valida  bool
+		err     error // error esperado si no es válida, si es uno conocido
+	}{
+		{"finalizar un turno de ayer", domain.Reserva{Fecha: fecha(ayer), HoraInicio: "10:00", ZonaHoraria: "America/Argentina/Buenos_Aires"}, true, nil},
+		{"finalizar un turno de mañana", domain.Reserva{Fecha: fecha(manana), HoraInicio: "10:00", ZonaHoraria: "America/Argentina/Buenos_Aires"}, false, domain.ErrReservaEstadoInvalido},
+		{"zona horaria desconocida", domain.Reserva{Fecha: fecha(ayer), HoraInicio: "10:00", ZonaHoraria: "Marte/Olympus"}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.reserva.ID = 7
+			tt.reserva.Estado = domain.ReservaConfirmada
+			repo := &repoTransicion{reserva: tt.reserva}
+			hs := &AiReservesService{hr: repo}
+
+			err := hs.CompleteReserveAPI(context.Background(), domain.ReservaTransicion{IDReserva: 7})
+			if tt.valida && err != nil {
+				t.Fatalf("CompleteReserveAPI = %v, se esperaba nil", err)
+			}
+			if !tt.valida && err == nil {
+				t.Fatalf("CompleteReserveAPI = nil, se esperaba un error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("CompleteReserveAPI = %v, se esperaba %v", err, tt.err)
+			}
+			if repo.actualizada != tt.valida {
+				t.Fatalf("estado actualizado = %t, se esperaba %t", repo.actualizada, tt.valida)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+const (
+	tamanioPaginaDefault = 50
+	tamanioPaginaMaximo  = 500
+)
+
+// validarBusqueda controla los filtros de disponibilidad y completa la paginación por defecto
+func validarBusqueda(req *domain.SearchReserve) error {
+	if req.IDProfesional == 0 && req.IDSubTipoUnidadReserva == 0 && req.IDConfEstablecimiento == 0 {
+		return fmt.Errorf("indicar IDProfesional, IDSubTipoUnidadReserva o IDConfEstablecimiento")
+	}
+
+	if err := validarRangoAgenda(req.FechaDesde, req.FechaHasta); err != nil {
+		return err
+	}
+
+	for _, hora := range []string{req.HoraDesde, req.HoraHasta} {
+		if hora == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", hora); err != nil {
+			return fmt.Errorf("hora inválida '%s', se espera HH:mm", hora)
+		}
+	}
+	if req.HoraDesde != "" && req.HoraHasta != "" && req.HoraHasta <= req.HoraDesde {
+		return fmt.Errorf("HoraHasta (%s) debe ser posterior a HoraDesde (%s)", req.HoraHasta, req.HoraDesde)
+	}
+
+	for _, dia := range req.DiasSemana {
+		if dia < 0 || dia > 6 {
+			return fmt.Errorf("día de semana inválido %d, se espera 0 (domingo) a 6 (sábado)", dia)
+		}
+	}
+
+	if req.Pagina <= 0 {
+		req.Pagina = 1
+	}
+	if req.TamanioPagina <= 0 {
+		req.TamanioPagina = tamanioPaginaDefault
+	}
+	if req.TamanioPagina > tamanioPaginaMaximo {
+		req.TamanioPagina = tamanioPaginaMaximo
+	}
+
+	return nil
+}
+
+// agruparPorDia arma la respuesta por día respetando el orden (fecha, hora) de los slots
+func agruparPorDia(slots []domain.SlotDisponible) []domain.DisponibilidadDia {
+	dias := []domain.DisponibilidadDia{}
+
+	for _, slot := range slots {
+		fecha := truncarFecha(slot.Fecha)
+		if n := len(dias); n == 0 || !dias[n-1].Fecha.Equal(fecha) {
+			dias = append(dias, domain.DisponibilidadDia{Fecha: fecha})
+		}
+		dias[len(dias)-1].Slots = append(dias[len(dias)-1].Slots, slot)
+	}
+
+	return dias
+}
@@ -0,0 +1,196 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// Reglas de reserva por sub tipo (con override por profesional): anticipación mínima, horizonte,
+// topes de reservas por paciente por día / semana y margen entre turnos consecutivos.
+// Se controlan al reservar (sobre la reserva ya grabada, dentro de la transacción) y en la búsqueda
+// de disponibilidad, para no ofrecer turnos que después se rechazarían.
+
+func (hs *AiReservesService) UpsertReglasReservaAPI(ctx context.Context, req domain.ReglasReservaConfig) (domain.ReglasReserva, error) {
+
+	if req.IDSubTipoUnidadReserva == 0 {
+		return domain.ReglasReserva{}, fmt.Errorf("IDSubTipoUnidadReserva es obligatorio")
+	}
+	for nombre, v := range map[string]*int{
+		"AnticipacionMinimaMinutos": req.AnticipacionMinimaMinutos,
+		"HorizonteDias":             req.HorizonteDias,
+		"MaxReservasDia":            req.MaxReservasDia,
+		"MaxReservasSemana":         req.MaxReservasSemana,
+		"BufferMinutos":             req.BufferMinutos,
+	} {
+		if v != nil && *v < 0 {
+			return domain.ReglasReserva{}, fmt.Errorf("%s no puede ser negativo (0 = sin límite)", nombre)
+		}
+	}
+
+	// Sin profesional se configuran las reglas del sub tipo; con profesional, su override
+	idConfPersonal := 0
+	if req.IDProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return domain.ReglasReserva{}, err
+		}
+		idConfPersonal = conf.ID
+
+		if err := hs.hr.UpdReglasConfPersonal(ctx, idConfPersonal, req); err != nil {
+			return domain.ReglasReserva{}, err
+		}
+	} else if err := hs.hr.UpdReglasSubTipo(ctx, req); err != nil {
+		return domain.ReglasReserva{}, err
+	}
+
+	return hs.hr.GetReglasReserva(ctx, req.IDSubTipoUnidadReserva, idConfPersonal)
+}
+
+func (hs *AiReservesService) GetReglasReservaAPI(ctx context.Context, req domain.ReglasReservaFiltro) (domain.ReglasReserva, error) {
+
+	if req.IDSubTipoUnidadReserva == 0 {
+		return domain.ReglasReserva{}, fmt.Errorf("IDSubTipoUnidadReserva es obligatorio")
+	}
+
+	idConfPersonal := 0
+	if req.IDProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return domain.ReglasReserva{}, err
+		}
+		idConfPersonal = conf.ID
+	}
+
+	return hs.hr.GetReglasReserva(ctx, req.IDSubTipoUnidadReserva, idConfPersonal)
+}
+
+// validarReglasReserva controla la reserva recién grabada (o movida) contra las reglas de su sub tipo.
+// Corre dentro de la transacción: si alguna regla no se cumple la reserva no queda.
+func (hs *AiReservesService) validarReglasReserva(ctx context.Context, tx *sql.Tx, idReserva int) error {
+
+	reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, idReserva)
+	if err != nil {
+		return err
+	}
+	turno, err := hs.hr.GetTurnoReserva(ctx, tx, reserva)
+	if err != nil {
+		return err
+	}
+	reglas, err := hs.hr.GetReglasReserva(ctx, reserva.IDSubTipoUnidadReserva, turno.IDConfPersonal)
+	if err != nil {
+		return err
+	}
+
+	// 1) Anticipación mínima y horizonte
+	ahora := time.Now()
+	if err := reglasDeTiempo(reglas, reserva.Inicio, ahora); err != nil {
+		return err
+	}
+
+	// 2) Margen con los turnos vecinos del mismo profesional o espacio
+	if reglas.BufferMinutos > 0 {
+		margen := time.Duration(reglas.BufferMinutos) * time.Minute
+		vecinas, err := hs.hr.GetReservasVecinas(ctx, tx, reserva, margen)
+		if err != nil {
+			return err
+		}
+		if len(vecinas) > 0 {
+			return fmt.Errorf("%w: se requieren %d minutos libres entre turnos y la reserva %d (%s %s-%s) queda más cerca",
+				domain.ErrBufferReserva, reglas.BufferMinutos, vecinas[0].ID,
+				vecinas[0].Fecha.Format("2006-01-02"), vecinas[0].HoraInicio, vecinas[0].HoraFin)
+		}
+	}
+
+	// 3) Topes del paciente: la persona queda bloqueada para que dos reservas simultáneas no pasen ambas
+	if reserva.IDPaciente == nil || (reglas.MaxReservasDia == 0 && reglas.MaxReservasSemana == 0) {
+		return nil
+	}
+	if err := hs.hr.LockPersona(ctx, tx, *reserva.IDPaciente); err != nil {
+		return err
+	}
+
+	lunes := inicioSemana(reserva.Fecha)
+	reservas, err := hs.hr.GetReservasPaciente(ctx, tx, *reserva.IDPaciente, reserva.IDSubTipoUnidadReserva,
+		lunes, lunes.AddDate(0, 0, 6))
+	if err != nil {
+		return err
+	}
+
+	// Las cuentas incluyen la reserva que se está validando
+	porDia, porSemana := contarReservasPaciente(reservas)
+	if dia := truncarFecha(reserva.Fecha); reglas.MaxReservasDia > 0 && porDia[dia] > reglas.MaxReservasDia {
+		return fmt.Errorf("%w: el paciente %d ya tiene %d reservas de este tipo el %s (máximo %d)",
+			domain.ErrMaxReservasDia, *reserva.IDPaciente, porDia[dia]-1, dia.Format("2006-01-02"), reglas.MaxReservasDia)
+	}
+	if reglas.MaxReservasSemana > 0 && porSemana[lunes] > reglas.MaxReservasSemana {
+		return fmt.Errorf("%w: el paciente %d ya tiene %d reservas de este tipo la semana del %s (máximo %d)",
+			domain.ErrMaxReservasSemana, *reserva.IDPaciente, porSemana[lunes]-1, lunes.Format("2006-01-02"), reglas.MaxReservasSemana)
+	}
+
+	return nil
+}
+
+// reglasDeTiempo controla anticipación mínima y horizonte de un turno que empieza en inicio
+func reglasDeTiempo(reglas domain.ReglasReserva, inicio, ahora time.Time) error {
+	if reglas.AnticipacionMinimaMinutos > 0 {
+		if limite := ahora.Add(time.Duration(reglas.AnticipacionMinimaMinutos) * time.Minute); inicio.Before(limite) {
+			return fmt.Errorf("%w: este tipo de turno se reserva con al menos %d minutos de anticipación",
+				domain.ErrAnticipacionMinima, reglas.AnticipacionMinimaMinutos)
+		}
+	}
+	if reglas.HorizonteDias > 0 {
+		if limite := ahora.AddDate(0, 0, reglas.HorizonteDias); inicio.After(limite) {
+			return fmt.Errorf("%w: este tipo de turno se reserva hasta %d días hacia adelante (límite %s)",
+				domain.ErrHorizonteReserva, reglas.HorizonteDias, limite.Format("2006-01-02 15:04"))
+		}
+	}
+	return nil
+}
+
+// contarReservasPaciente cuenta reservas por día y por semana (clave: lunes de la semana)
+func contarReservasPaciente(reservas []domain.Reserva) (map[time.Time]int, map[time.Time]int) {
+	porDia := map[time.Time]int{}
+	porSemana := map[time.Time]int{}
+	for _, r := range reservas {
+		porDia[truncarFecha(r.Fecha)]++
+		porSemana[inicioSemana(r.Fecha)]++
+	}
+	return porDia, porSemana
+}
+
+// inicioSemana devuelve el lunes de la semana de la fecha (misma semana que date_trunc('week') en Postgres)
+func inicioSemana(fecha time.Time) time.Time {
+	dia := truncarFecha(fecha)
+	return dia.AddDate(0, 0, -((int(dia.Weekday()) + 6) % 7))
+}
+
+// topesPaciente marca los días y semanas (por su lunes) del rango en los que el paciente de la
+// búsqueda ya llegó al máximo de reservas del sub tipo. Sin paciente o sin topes no descarta nada.
+func (hs *AiReservesService) topesPaciente(ctx context.Context, req domain.SearchReserve, reglas domain.ReglasReserva, desde, hasta time.Time) (map[time.Time]bool, map[time.Time]bool, error) {
+	topeDia := map[time.Time]bool{}
+	topeSemana := map[time.Time]bool{}
+
+	if req.IDPaciente == 0 || (reglas.MaxReservasDia == 0 && reglas.MaxReservasSemana == 0) {
+		return topeDia, topeSemana, nil
+	}
+
+	reservas, err := hs.hr.GetReservasPaciente(ctx, nil, req.IDPaciente, req.IDSubTipoUnidadReserva,
+		inicioSemana(desde), inicioSemana(hasta).AddDate(0, 0, 6))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	porDia, porSemana := contarReservasPaciente(reservas)
+	for dia, n := range porDia {
+		topeDia[dia] = reglas.MaxReservasDia > 0 && n >= reglas.MaxReservasDia
+	}
+	for lunes, n := range porSemana {
+		topeSemana[lunes] = reglas.MaxReservasSemana > 0 && n >= reglas.MaxReservasSemana
+	}
+
+	return topeDia, topeSemana, nil
+}
@@ -0,0 +1,123 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+// repoReglas atiende solo lo que lee validarReglasReserva
+type repoReglas struct {
+	ports.AiReservesRepository
+	reserva   domain.Reserva
+	reglas    domain.ReglasReserva
+	vecinas   []domain.Reserva
+	paciente  []domain.Reserva
+	margen    time.Duration
+	bloqueada int
+}
+
+func (r *repoReglas) GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error) {
+	return r.reserva, nil
+}
+
+func (r *repoReglas) GetTurnoReserva(ctx context.Context, tx *sql.Tx, reserva domain.Reserva) (domain.TurnoLiberado, error) {
+	return domain.TurnoLiberado{IDConfPersonal: 3}, nil
+}
+
+func (r *repoReglas) GetReglasReserva(ctx context.Context, idSubTipo int, idConfPersonal int) (domain.ReglasReserva, error) {
+	return r.reglas, nil
+}
+
+func (r *repoReglas) GetReservasVecinas(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, margen time.Duration) ([]domain.Reserva, error) {
+	r.margen = margen
+	return r.vecinas, nil
+}
+
+func (r *repoReglas) LockPersona(ctx context.Context, tx *sql.Tx, idPersona int) error {
+	r.bloqueada = idPersona
+	return nil
+}
+
+func (r *repoReglas) GetReservasPaciente(ctx context.Context, tx *sql.Tx, idPaciente int, idSubTipo int, desde, hasta time.Time) ([]domain.Reserva, error) {
+	return r.paciente, nil
+}
+
+func TestValidarReglasReserva(t *testing.T) {
+	paciente := 42
+	inicio := time.Now().Add(72 * time.Hour)
+	reserva := domain.Reserva{ID: 1, Fecha: truncarFecha(inicio), Inicio: inicio, IDPaciente: &paciente, IDSubTipoUnidadReserva: 5}
+
+	// reservas vigentes del paciente en el día del turno, incluida la que se valida
+	delPaciente := func(n int) []domain.Reserva {
+		var rs []domain.Reserva
+		for i := 0; i < n; i++ {
+			rs = append(rs, domain.Reserva{ID: i + 1, Fecha: reserva.Fecha})
+		}
+		return rs
+	}
+
+	tests := []struct {
+		name    string
+		reserva domain.Reserva
+		reglas  domain.ReglasReserva
+		vecinas []domain.Reserva
+		otras   []domain.Reserva
+		err     error
+	}{
+		{"sin reglas", reserva, domain.ReglasReserva{}, nil, nil, nil},
+		{"anticipación suficiente", reserva, domain.ReglasReserva{AnticipacionMinimaMinutos: 24 * 60}, nil, nil, nil},
+		{"anticipación insuficiente", reserva, domain.ReglasReserva{AnticipacionMinimaMinutos: 4 * 24 * 60}, nil, nil, domain.ErrAnticipacionMinima},
+		{"dentro del horizonte", reserva, domain.ReglasReserva{HorizonteDias: 7}, nil, nil, nil},
+		{"fuera del horizonte", reserva, domain.ReglasReserva{HorizonteDias: 2}, nil, nil, domain.ErrHorizonteReserva},
+		{"margen libre", reserva, domain.ReglasReserva{BufferMinutos: 15}, nil, nil, nil},
+		{"turno vecino dentro del margen", reserva, domain.ReglasReserva{BufferMinutos: 15},
+			[]domain.Reserva{{ID: 9, Fecha: reserva.Fecha, HoraInicio: "10:00", HoraFin: "10:30"}}, nil, domain.ErrBufferReserva},
+		{"tope diario sin alcanzar", reserva, domain.ReglasReserva{MaxReservasDia: 2}, nil, delPaciente(2), nil},
+		{"tope diario superado", reserva, domain.ReglasReserva{MaxReservasDia: 1}, nil, delPaciente(2), domain.ErrMaxReservasDia},
+		{"tope semanal sin alcanzar", reserva, domain.ReglasReserva{MaxReservasSemana: 3}, nil, delPaciente(3), nil},
+		{"tope semanal superado", reserva, domain.ReglasReserva{MaxReservasSemana: 2}, nil, delPaciente(3), domain.ErrMaxReservasSemana},
+		{"sin paciente no hay topes", domain.Reserva{ID: 1, Fecha: reserva.Fecha, Inicio: inicio}, domain.ReglasReserva{MaxReservasDia: 1}, nil, delPaciente(2), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &repoReglas{reserva: tt.reserva, reglas: tt.reglas, vecinas: tt.vecinas, paciente: tt.otras}
+			hs := &AiReservesService{hr: repo}
+
+			err := hs.validarReglasReserva(context.Background(), nil, tt.reserva.ID)
+			if tt.err == nil && err != nil {
+				t.Fatalf("validarReglasReserva = %v, se esperaba nil", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("validarReglasReserva = %v, se esperaba %v", err, tt.err)
+			}
+
+			if want := time.Duration(tt.reglas.BufferMinutos) * time.Minute; repo.margen != want {
+				t.Errorf("margen consultado = %s, se esperaba %s", repo.margen, want)
+			}
+			topes := tt.reglas.MaxReservasDia > 0 || tt.reglas.MaxReservasSemana > 0
+			if bloqueo := topes && tt.reserva.IDPaciente != nil; bloqueo != (repo.bloqueada == paciente) {
+				t.Errorf("persona bloqueada = %d", repo.bloqueada)
+			}
+		})
+	}
+}
+
+func TestInicioSemana(t *testing.T) {
+	// 2026-03-09 es lunes
+	for _, dia := range []int{9, 10, 13, 15} {
+		fecha := time.Date(2026, 3, dia, 18, 0, 0, 0, time.UTC)
+		if got := inicioSemana(fecha); got.Format("2006-01-02") != "2026-03-09" {
+			t.Errorf("inicioSemana(%s) = %s, se esperaba 2026-03-09", fecha.Format("2006-01-02"), got.Format("2006-01-02"))
+		}
+	}
+	if got := inicioSemana(time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)); got.Format("2006-01-02") != "2026-03-16" {
+		t.Errorf("inicioSemana(lunes siguiente) = %s", got.Format("2006-01-02"))
+	}
+}
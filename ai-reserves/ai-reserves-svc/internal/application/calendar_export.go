@@ -0,0 +1,224 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/ical"
+)
+
+const prodIDCalendario = "-//ai-reserves//Reservas//ES"
+
+// Ventana por defecto de exportación y de los feeds: el último mes y los próximos seis
+const (
+	diasCalendarioAtras    = 30
+	diasCalendarioAdelante = 180
+)
+
+// Ruta pública del feed; el token va seguido de .ics para que los clientes de calendario lo reconozcan
+const rutaFeedCalendario = "/reserves/calendar-feed/"
+
+// statusICal traduce el estado de la reserva al STATUS del VEVENT
+var statusICal = map[string]string{
+	domain.ReservaPendiente:  ical.StatusTentative,
+	domain.ReservaConfirmada: ical.StatusConfirmed,
+	domain.ReservaFinalizada: ical.StatusConfirmed,
+	domain.ReservaAusente:    ical.StatusConfirmed,
+	domain.ReservaCancelada:  ical.StatusCancelled,
+}
+
+// ExportReservasICSAPI exporta las reservas como archivo .ics: un VCALENDAR METHOD:PUBLISH con las
+// reservas vigentes e históricas y, si hay cancelaciones, un segundo VCALENDAR METHOD:CANCEL con ellas
+func (hs *AiReservesService) ExportReservasICSAPI(ctx context.Context, req domain.CalendarioFiltro) ([]byte, error) {
+
+	if err := validarFiltroCalendario(&req); err != nil {
+		return nil, err
+	}
+
+	reservas, err := hs.hr.GetReservasCalendario(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	publicadas := ical.Calendar{ProdID: prodIDCalendario, Name: nombreCalendario(req.Rol), Method: ical.MethodPublish}
+	canceladas := ical.Calendar{ProdID: prodIDCalendario, Method: ical.MethodCancel}
+
+	for _, rc := range reservas {
+		ev, err := eventoReserva(rc, req.Rol)
+		if err != nil {
+			return nil, err
+		}
+		if rc.Reserva.Estado == domain.ReservaCancelada {
+			canceladas.Events = append(canceladas.Events, ev)
+			continue
+		}
+		publicadas.Events = append(publicadas.Events, ev)
+	}
+
+	calendarios := []ical.Calendar{publicadas}
+	if len(canceladas.Events) > 0 {
+		calendarios = append(calendarios, canceladas)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, calendarios...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (hs *AiReservesService) CreateFeedCalendarioAPI(ctx context.Context, req domain.FeedCalendario) (domain.FeedCalendario, error) {
+
+	filtro := domain.CalendarioFiltro{IDPersona: req.IDPersona, Rol: req.Rol}
+	if err := validarFiltroCalendario(&filtro); err != nil {
+		return domain.FeedCalendario{}, err
+	}
+	req.Rol = filtro.Rol
+
+	token, err := nuevoTokenFeed()
+	if err != nil {
+		return domain.FeedCalendario{}, err
+	}
+	req.Token = token
+
+	feed, err := hs.hr.CreateFeedCalendario(ctx, req)
+	if err != nil {
+		return domain.FeedCalendario{}, err
+	}
+
+	feed.URL = hs.conf.URLPublica + rutaFeedCalendario + feed.Token + ".ics"
+	return feed, nil
+}
+
+// RevokeFeedCalendarioAPI da de baja el feed; solo su dueño puede hacerlo
+func (hs *AiReservesService) RevokeFeedCalendarioAPI(ctx context.Context, token string, idPersona int) error {
+	if token == "" {
+		return fmt.Errorf("token del feed obligatorio")
+	}
+	return hs.hr.RevokeFeedCalendario(ctx, token, idPersona)
+}
+
+// GetFeedCalendarioAPI arma el calendario de un feed. Los clientes que se suscriben a una URL
+// ignoran METHOD, así que el feed es un único VCALENDAR y las cancelaciones van con STATUS:CANCELLED.
+func (hs *AiReservesService) GetFeedCalendarioAPI(ctx context.Context, token string) ([]byte, error) {
+
+	feed, err := hs.hr.GetFeedCalendario(ctx, strings.TrimSuffix(token, ".ics"))
+	if err != nil {
+		return nil, err
+	}
+
+	filtro := domain.CalendarioFiltro{IDPersona: feed.IDPersona, Rol: feed.Rol}
+	if err := validarFiltroCalendario(&filtro); err != nil {
+		return nil, err
+	}
+
+	reservas, err := hs.hr.GetReservasCalendario(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := ical.Calendar{ProdID: prodIDCalendario, Name: nombreCalendario(feed.Rol), Method: ical.MethodPublish}
+	for _, rc := range reservas {
+		ev, err := eventoReserva(rc, feed.Rol)
+		if err != nil {
+			return nil, err
+		}
+		cal.Events = append(cal.Events, ev)
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func validarFiltroCalendario(req *domain.CalendarioFiltro) error {
+	if req.IDPersona == 0 {
+		return fmt.Errorf("IDPersona es obligatorio")
+	}
+
+	switch req.Rol {
+	case "":
+		req.Rol = domain.CalendarioRolPaciente
+	case domain.CalendarioRolPaciente, domain.CalendarioRolProfesional:
+	default:
+		return fmt.Errorf("rol '%s' inválido, se espera %s o %s", req.Rol, domain.CalendarioRolPaciente, domain.CalendarioRolProfesional)
+	}
+
+	hoy := truncarFecha(time.Now())
+	if req.FechaDesde.IsZero() {
+		req.FechaDesde = hoy.AddDate(0, 0, -diasCalendarioAtras)
+	}
+	if req.FechaHasta.IsZero() {
+		req.FechaHasta = hoy.AddDate(0, 0, diasCalendarioAdelante)
+	}
+
+	return validarRangoAgenda(req.FechaDesde, req.FechaHasta)
+}
+
+func nombreCalendario(rol string) string {
+	if rol == domain.CalendarioRolProfesional {
+		return "Agenda"
+	}
+	return "Mis reservas"
+}
+
+// eventoReserva arma el VEVENT de una reserva. El resumen muestra a la otra parte:
+// el profesional (o el espacio) para el paciente, el paciente para el profesional.
+func eventoReserva(rc domain.ReservaCalendario, rol string) (ical.Event, error) {
+	r := rc.Reserva
+
+	inicio, err := inicioReserva(r)
+	if err != nil {
+		return ical.Event{}, err
+	}
+	fin, err := finReserva(r)
+	if err != nil {
+		return ical.Event{}, err
+	}
+
+	contraparte := rc.Profesional
+	if rol == domain.CalendarioRolProfesional {
+		contraparte = rc.Paciente
+	}
+	if contraparte == "" {
+		contraparte = rc.Establecimiento
+	}
+
+	summary := rc.SubTipo
+	if contraparte != "" {
+		summary += " - " + contraparte
+	}
+
+	ev := ical.Event{
+		UID:          fmt.Sprintf("reserva-%d@ai-reserves", r.ID),
+		Summary:      summary,
+		Location:     rc.Establecimiento,
+		Start:        inicio,
+		End:          fin,
+		Status:       statusICal[r.Estado],
+		Sequence:     rc.Secuencia,
+		LastModified: rc.UpdatedAt,
+	}
+	if r.Observaciones != nil {
+		ev.Description = *r.Observaciones
+	}
+
+	return ev, nil
+}
+
+// nuevoTokenFeed genera un token aleatorio difícil de adivinar: es la única credencial del feed
+func nuevoTokenFeed() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generando token de feed: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/google/uuid"
+)
+
+// newEvent arma un evento de dominio de ai-reserves con id y timestamp propios
+func newEvent(routingKey string, payload interface{}) domain.Event {
+	return domain.Event{
+		ID:         uuid.New().String(),
+		Type:       routingKey,
+		RoutingKey: routingKey,
+		Origin:     domain.EventOrigin,
+		Timestamp:  time.Now(),
+		Payload:    payload,
+	}
+}
+
+// encolarEvento graba el evento en el outbox dentro de la transacción del cambio: si la
+// transacción se revierte el evento no existe, y si confirma el relay lo termina publicando
+func (hs *AiReservesService) encolarEvento(ctx context.Context, tx *sql.Tx, routingKey string, payload interface{}) error {
+	return hs.hr.PushEventToQueue(ctx, tx, newEvent(routingKey, payload))
+}
+
+// reservaPayload arma el payload de reserve.created / reserve.confirmed
+func reservaPayload(r domain.Reserva, estado, actor string) domain.ReservaPayload {
+	if actor == "" {
+		actor = actorSistema
+	}
+
+	return domain.ReservaPayload{
+		IDReserva:              r.ID,
+		IDAgenda:               r.IDAgenda,
+		IDPaciente:             r.IDPaciente,
+		IDSubTipoUnidadReserva: r.IDSubTipoUnidadReserva,
+		IDSerie:                r.IDSerie,
+		Estado:                 estado,
+		Fecha:                  r.Fecha.Format("2006-01-02"),
+		HoraInicio:             r.HoraInicio,
+		HoraFin:                r.HoraFin,
+		ZonaHoraria:            r.ZonaHoraria,
+		Inicio:                 r.Inicio.Format(time.RFC3339),
+		Actor:                  actor,
+	}
+}
+
+// procesarEvento aplica un evento recibido en la misma transacción que lo registra en
+// eventos_procesados. Si ya estaba registrado (redelivery, o dos entregas en paralelo) no corre fn y
+// devuelve domain.ErrDuplicateEvent. Un evento sin ID se aplica sin registrar.
+func (hs *AiReservesService) procesarEvento(ctx context.Context, evt domain.Event, fn func(tx *sql.Tx) error) error {
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if evt.ID != "" {
+			nuevo, err := hs.hr.RegistrarEventoProcesado(ctx, tx, evt)
+			if err != nil {
+				return err
+			}
+			if !nuevo {
+				return fmt.Errorf("%w: %s", domain.ErrDuplicateEvent, evt.ID)
+			}
+		}
+		return fn(tx)
+	})
+}
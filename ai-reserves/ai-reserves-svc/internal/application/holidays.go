@@ -0,0 +1,202 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/ical"
+)
+
+func (hs *AiReservesService) CreateFeriadoAPI(ctx context.Context, req domain.Feriado) (int, error) {
+	if req.Fecha.IsZero() {
+		return 0, fmt.Errorf("Fecha es obligatoria")
+	}
+
+	req.Fecha = truncarFecha(req.Fecha)
+	req.Origen = domain.FeriadoOrigenManual
+
+	id, err := hs.hr.CreateFeriado(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (hs *AiReservesService) UpdFeriadoAPI(ctx context.Context, req domain.Feriado) error {
+	if req.ID == 0 || req.Fecha.IsZero() {
+		return fmt.Errorf("ID y Fecha son obligatorios")
+	}
+
+	req.Fecha = truncarFecha(req.Fecha)
+
+	if err := hs.hr.UpdFeriado(ctx, req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (hs *AiReservesService) DeleteFeriadoAPI(ctx context.Context, idFeriado int) error {
+	if err := hs.hr.DeleteFeriado(ctx, idFeriado); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (hs *AiReservesService) GetFeriadosAPI(ctx context.Context, req domain.FeriadoFiltro) ([]domain.Feriado, error) {
+	if err := validarRangoAgenda(req.FechaDesde, req.FechaHasta); err != nil {
+		return nil, err
+	}
+
+	feriados, err := hs.hr.GetFeriados(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return feriados, nil
+}
+
+func (hs *AiReservesService) ImportFeriadosAPI(ctx context.Context, req domain.FeriadoImport) (domain.FeriadoImportResumen, error) {
+
+	// 1) Parsear el archivo según el formato (si no viene, se detecta por el contenido)
+	formato := strings.ToUpper(strings.TrimSpace(req.Formato))
+	if formato == "" {
+		formato = domain.FeriadoOrigenCSV
+		if strings.Contains(req.Contenido, "BEGIN:VCALENDAR") {
+			formato = domain.FeriadoOrigenICal
+		}
+	}
+
+	var feriados []domain.Feriado
+	var err error
+
+	switch formato {
+	case domain.FeriadoOrigenICal, "ICS":
+		feriados, err = parsearFeriadosICal(strings.NewReader(req.Contenido))
+	case domain.FeriadoOrigenCSV:
+		feriados, err = parsearFeriadosCSV(strings.NewReader(req.Contenido))
+	default:
+		return domain.FeriadoImportResumen{}, fmt.Errorf("formato '%s' no soportado, se espera ICAL o CSV", req.Formato)
+	}
+	if err != nil {
+		return domain.FeriadoImportResumen{}, err
+	}
+
+	if len(feriados) == 0 {
+		return domain.FeriadoImportResumen{}, fmt.Errorf("el archivo no contiene feriados")
+	}
+
+	var idConfEstablecimiento *int
+	if req.IDConfEstablecimiento != 0 {
+		idConfEstablecimiento = &req.IDConfEstablecimiento
+	}
+	for i := range feriados {
+		feriados[i].IDConfEstablecimiento = idConfEstablecimiento
+	}
+
+	// 2) Persistir todo o nada
+	var resumen domain.FeriadoImportResumen
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		resumen, err = hs.hr.InsertFeriados(ctx, tx, feriados)
+		return err
+	})
+	if err != nil {
+		return domain.FeriadoImportResumen{}, err
+	}
+
+	return resumen, nil
+}
+
+// parsearFeriadosICal toma un feriado por cada día que cubre cada VEVENT.
+// Las reglas de repetición no se expanden: los calendarios de feriados publican cada ocurrencia.
+func parsearFeriadosICal(r io.Reader) ([]domain.Feriado, error) {
+	events, err := ical.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var feriados []domain.Feriado
+	for _, ev := range events {
+		desde := truncarFecha(ev.Start)
+		hasta := truncarFecha(ev.End)
+
+		// DTEND de un evento de día completo es exclusivo
+		if ev.AllDay || ev.End.Equal(hasta) {
+			hasta = hasta.AddDate(0, 0, -1)
+		}
+		if hasta.Before(desde) {
+			hasta = desde
+		}
+
+		for fecha := desde; !fecha.After(hasta); fecha = fecha.AddDate(0, 0, 1) {
+			feriados = append(feriados, domain.Feriado{
+				Fecha:       fecha,
+				Descripcion: ev.Summary,
+				Origen:      domain.FeriadoOrigenICal,
+			})
+		}
+	}
+
+	return feriados, nil
+}
+
+// parsearFeriadosCSV lee filas fecha,descripcion (también separadas por ';').
+// La fecha puede venir como AAAA-MM-DD o DD/MM/AAAA; una primera fila de encabezado se ignora.
+func parsearFeriadosCSV(r io.Reader) ([]domain.Feriado, error) {
+	contenido, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("leyendo csv: %w", err)
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(contenido)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if primera, _, _ := strings.Cut(string(contenido), "\n"); strings.Contains(primera, ";") && !strings.Contains(primera, ",") {
+		reader.Comma = ';'
+	}
+
+	filas, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("leyendo csv: %w", err)
+	}
+
+	var feriados []domain.Feriado
+	for i, fila := range filas {
+		if len(fila) == 0 || strings.TrimSpace(fila[0]) == "" {
+			continue
+		}
+
+		fecha, err := parsearFechaFeriado(fila[0])
+		if err != nil {
+			if i == 0 {
+				continue // encabezado
+			}
+			return nil, fmt.Errorf("fila %d: %w", i+1, err)
+		}
+
+		f := domain.Feriado{
+			Fecha:  fecha,
+			Origen: domain.FeriadoOrigenCSV,
+		}
+		if len(fila) > 1 {
+			f.Descripcion = strings.TrimSpace(fila[1])
+		}
+		feriados = append(feriados, f)
+	}
+
+	return feriados, nil
+}
+
+func parsearFechaFeriado(valor string) (time.Time, error) {
+	valor = strings.TrimSpace(valor)
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if t, err := time.Parse(layout, valor); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("fecha inválida '%s', se espera AAAA-MM-DD o DD/MM/AAAA", valor)
+}
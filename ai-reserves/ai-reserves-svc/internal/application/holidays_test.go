@@ -0,0 +1,70 @@
+package application
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsearFeriadosCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		csv    string
+		fechas []string
+		desc   []string
+	}{
+		{"con encabezado", "fecha,descripcion\n2026-05-25,Revolución de Mayo\n2026-07-09,Independencia\n",
+			[]string{"2026-05-25", "2026-07-09"}, []string{"Revolución de Mayo", "Independencia"}},
+		{"separado por punto y coma", "25/05/2026;Revolución de Mayo\n09/07/2026; Independencia\n",
+			[]string{"2026-05-25", "2026-07-09"}, []string{"Revolución de Mayo", "Independencia"}},
+		{"sin descripción y con filas vacías", "2026-12-25\n\n2026-12-08\n",
+			[]string{"2026-12-25", "2026-12-08"}, []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feriados, err := parsearFeriadosCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("parsearFeriadosCSV: %v", err)
+			}
+			if len(feriados) != len(tt.fechas) {
+				t.Fatalf("parsearFeriadosCSV devolvió %d feriados, se esperaban %d", len(feriados), len(tt.fechas))
+			}
+			for i, f := range feriados {
+				if f.Fecha.Format("2006-01-02") != tt.fechas[i] || f.Descripcion != tt.desc[i] {
+					t.Errorf("feriado %d = %s %q, se esperaba %s %q", i, f.Fecha.Format("2006-01-02"), f.Descripcion, tt.fechas[i], tt.desc[i])
+				}
+			}
+		})
+	}
+
+	if _, err := parsearFeriadosCSV(strings.NewReader("2026-05-25,ok\n31/02/2026,no existe\n")); err == nil {
+		t.Error("fecha inválida fuera del encabezado aceptada")
+	}
+}
+
+func TestParsearFeriadosICal(t *testing.T) {
+	cal := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Carnaval\r\nDTSTART;VALUE=DATE:20260216\r\nDTEND;VALUE=DATE:20260218\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Navidad\r\nDTSTART;VALUE=DATE:20261225\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	feriados, err := parsearFeriadosICal(strings.NewReader(cal))
+	if err != nil {
+		t.Fatalf("parsearFeriadosICal: %v", err)
+	}
+
+	esperados := []time.Time{
+		time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 17, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+	}
+	if len(feriados) != len(esperados) {
+		t.Fatalf("parsearFeriadosICal devolvió %d feriados, se esperaban %d", len(feriados), len(esperados))
+	}
+	for i, f := range feriados {
+		if !f.Fecha.Equal(esperados[i]) {
+			t.Errorf("feriado %d = %s, se esperaba %s", i, f.Fecha.Format("2006-01-02"), esperados[i].Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+)
+
+// Una reserva puede necesitar, además de su agenda, otros recursos libres en el mismo horario
+// (el sillón de un consultorio odontológico, un segundo profesional). Sus slots se ocupan en la
+// misma transacción que la reserva y quedan registrados en agendas_reservas; al cancelar o mover
+// la reserva se liberan junto con su slot.
+// Un profesional MULTIAGENDA no tiene slots: como recurso alcanza con que el turno caiga en su horario
+// y no se superponga con su ocupación, y queda anotado en agendas_reservas de su agenda del día.
+
+// validarRecursos controla que los recursos adicionales estén identificados y no se repitan
+func validarRecursos(recursos []domain.RecursoReserva) error {
+	vistos := map[domain.RecursoReserva]bool{}
+	for _, r := range recursos {
+		if r.IDProfesional <= 0 && r.IDConfEstablecimiento <= 0 {
+			return fmt.Errorf("recurso adicional sin identificar")
+		}
+		if vistos[r] {
+			return fmt.Errorf("recurso adicional repetido (profesional %d, conf_establecimiento %d)",
+				r.IDProfesional, r.IDConfEstablecimiento)
+		}
+		vistos[r] = true
+	}
+	return nil
+}
+
+// ocuparRecursos toma los slots de los recursos adicionales para la reserva ya grabada
+func (hs *AiReservesService) ocuparRecursos(ctx context.Context, tx *sql.Tx, idReserva int, recursos []domain.RecursoReserva) error {
+	if len(recursos) == 0 {
+		return nil
+	}
+	if err := validarRecursos(recursos); err != nil {
+		return err
+	}
+
+	// Horario ya resuelto de la reserva (instantes incluidos)
+	reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, idReserva)
+	if err != nil {
+		return err
+	}
+
+	// Siempre en el mismo orden: dos reservas que comparten recursos no se bloquean en cruz
+	for _, recurso := range ordenarRecursos(recursos) {
+		conf, multiagenda, err := hs.recursoMultiagenda(ctx, recurso)
+		if err != nil {
+			return err
+		}
+		if multiagenda {
+			err = hs.ocuparRecursoMultiagenda(ctx, tx, reserva, conf)
+		} else {
+			err = hs.hr.OcuparRecurso(ctx, tx, reserva, recurso)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ordenarRecursos devuelve una copia de los recursos ordenada por id de profesional y de conf_establecimiento
+func ordenarRecursos(recursos []domain.RecursoReserva) []domain.RecursoReserva {
+	ordenados := append([]domain.RecursoReserva(nil), recursos...)
+	sort.Slice(ordenados, func(i, j int) bool {
+		if ordenados[i].IDProfesional != ordenados[j].IDProfesional {
+			return ordenados[i].IDProfesional < ordenados[j].IDProfesional
+		}
+		return ordenados[i].IDConfEstablecimiento < ordenados[j].IDConfEstablecimiento
+	})
+	return ordenados
+}
+
+// recursoMultiagenda indica si el recurso es un profesional con agenda MULTIAGENDA (sin slots)
+func (hs *AiReservesService) recursoMultiagenda(ctx context.Context, recurso domain.RecursoReserva) (domain.ConfigPersonaFull, bool, error) {
+	if recurso.IDProfesional <= 0 {
+		return domain.ConfigPersonaFull{}, false, nil
+	}
+	conf, err := hs.hr.GetConfigPersonaFull(ctx, recurso.IDProfesional)
+	if err != nil {
+		return domain.ConfigPersonaFull{}, false, err
+	}
+	return conf, conf.ModoAgenda == domain.ModoAgendaMultiagenda, nil
+}
+
+// ocuparRecursoMultiagenda ocupa a un profesional MULTIAGENDA en el horario que ya fijó la reserva
+func (hs *AiReservesService) ocuparRecursoMultiagenda(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, conf domain.ConfigPersonaFull) error {
+	h, err := horarioDesdeConfPersonal(conf)
+	if err != nil {
+		return err
+	}
+
+	pedido := intervalo{inicio: reserva.Inicio, fin: reserva.Fin}
+	fecha := truncarFecha(pedido.inicio.In(h.loc))
+
+	// 1) El turno tiene que caer dentro del horario de atención del profesional
+	if !dentroDelHorario(h, fecha, pedido) {
+		return fmt.Errorf("%w: el recurso profesional %d no atiende todo el turno %s %s-%s",
+			domain.ErrSlotNotFound, conf.IDPersona, reserva.Fecha.Format("2006-01-02"), reserva.HoraInicio, reserva.HoraFin)
+	}
+
+	// 2) Libre de feriados, bloqueos y otras reservas, con su agenda del día bloqueada
+	idAgenda, err := hs.lockIntervaloMultiagenda(ctx, tx, conf, h, fecha, pedido, reserva.ID)
+	if err != nil {
+		return fmt.Errorf("recurso profesional %d: %w", conf.IDPersona, err)
+	}
+
+	// 3) Anotar la agenda del recurso que usa la reserva
+	if err := hs.hr.RegistrarAgendaRecurso(ctx, tx, idAgenda, reserva.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("🧩 Reserva ID=%d ocupa la agenda %d del recurso profesional %d\n", reserva.ID, idAgenda, conf.IDPersona)
+	return nil
+}
+
+// dentroDelHorario indica si el intervalo cae entero dentro del horario de atención del día
+func dentroDelHorario(h horarioAgenda, fecha time.Time, i intervalo) bool {
+	if !h.dias[fecha.Weekday()] {
+		return false
+	}
+	inicio := zona.EnFechaAjustada(fecha, h.horaInicio, h.loc)
+	fin := zona.EnFechaAjustada(fecha, h.horaFin, h.loc)
+	return !i.inicio.Before(inicio) && !i.fin.After(fin)
+}
+
+// slotsRecursos trae los slots con lugar de cada recurso adicional en el rango [desde, hasta)
+func (hs *AiReservesService) slotsRecursos(ctx context.Context, recursos []domain.RecursoReserva, desde, hasta time.Time) ([][]domain.AgendaSlot, error) {
+	if err := validarRecursos(recursos); err != nil {
+		return nil, err
+	}
+
+	libres := make([][]domain.AgendaSlot, 0, len(recursos))
+	for _, recurso := range recursos {
+		conf, multiagenda, err := hs.recursoMultiagenda(ctx, recurso)
+		if err != nil {
+			return nil, err
+		}
+
+		var slots []domain.AgendaSlot
+		if multiagenda {
+			slots, err = hs.huecosMultiagenda(ctx, conf, desde, hasta)
+		} else {
+			slots, err = hs.hr.GetSlotsLibresRecurso(ctx, recurso, desde, hasta)
+		}
+		if err != nil {
+			return nil, err
+		}
+		libres = append(libres, slots)
+	}
+
+	return libres, nil
+}
+
+// huecosMultiagenda arma los tramos libres del horario de un profesional MULTIAGENDA en [desde, hasta)
+// como slots, para que la búsqueda los cruce igual que los slots pregenerados de otros recursos
+func (hs *AiReservesService) huecosMultiagenda(ctx context.Context, conf domain.ConfigPersonaFull, desde, hasta time.Time) ([]domain.AgendaSlot, error) {
+	h, err := horarioDesdeConfPersonal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	fechaDesde := truncarFecha(desde.In(h.loc))
+	fechaHasta := truncarFecha(hasta.In(h.loc))
+
+	reservas, err := hs.hr.GetReservasProfesional(ctx, conf.ID, fechaDesde, fechaHasta)
+	if err != nil {
+		return nil, err
+	}
+	ofertas, err := hs.hr.GetOfertasVigentesProfesional(ctx, conf.ID, fechaDesde, fechaHasta)
+	if err != nil {
+		return nil, err
+	}
+	bloqueos, err := hs.hr.GetBloqueos(ctx, domain.BloqueoFiltro{IDConfPersonal: conf.ID, FechaDesde: desde, FechaHasta: hasta})
+	if err != nil {
+		return nil, err
+	}
+	ocupados, err := ocupacion(append(reservas, reservasOfertadas(ofertas)...), bloqueos, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	feriados := map[time.Time]bool{}
+	if !conf.GeneraFeriados {
+		lista, err := hs.hr.GetFeriados(ctx, domain.FeriadoFiltro{FechaDesde: fechaDesde, FechaHasta: fechaHasta})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range lista {
+			feriados[truncarFecha(f.Fecha)] = true
+		}
+	}
+
+	var slots []domain.AgendaSlot
+	for fecha := fechaDesde; !fecha.After(fechaHasta); fecha = fecha.AddDate(0, 0, 1) {
+		if !h.dias[fecha.Weekday()] || feriados[fecha] {
+			continue
+		}
+		inicio := zona.EnFechaAjustada(fecha, h.horaInicio, h.loc)
+		fin := zona.EnFechaAjustada(fecha, h.horaFin, h.loc)
+
+		for _, hueco := range huecos(intervalo{inicio: inicio, fin: fin}, ocupados) {
+			slots = append(slots, domain.AgendaSlot{
+				HoraInicio: hueco.inicio.In(h.loc).Format("15:04"),
+				HoraFin:    hueco.fin.In(h.loc).Format("15:04"),
+				Inicio:     hueco.inicio,
+				Fin:        hueco.fin,
+				Estado:     domain.SlotLibre,
+			})
+		}
+	}
+
+	return slots, nil
+}
+
+// huecos devuelve los tramos de la ventana que no se superponen con ningún intervalo ocupado, en orden
+func huecos(ventana intervalo, ocupados []intervalo) []intervalo {
+	ordenados := append([]intervalo(nil), ocupados...)
+	sort.Slice(ordenados, func(i, j int) bool { return ordenados[i].inicio.Before(ordenados[j].inicio) })
+
+	var libres []intervalo
+	desde := ventana.inicio
+	for _, o := range ordenados {
+		if !o.fin.After(desde) || !o.inicio.Before(ventana.fin) {
+			continue
+		}
+		if o.inicio.After(desde) {
+			libres = append(libres, intervalo{inicio: desde, fin: o.inicio})
+		}
+		desde = o.fin
+	}
+	if desde.Before(ventana.fin) {
+		libres = append(libres, intervalo{inicio: desde, fin: ventana.fin})
+	}
+	return libres
+}
+
+// recursosLibres indica si todos los recursos tienen el intervalo cubierto por slots con lugar
+func recursosLibres(i intervalo, libres [][]domain.AgendaSlot) bool {
+	for _, slots := range libres {
+		if !cubierto(i, slots) {
+			return false
+		}
+	}
+	return true
+}
+
+// cubierto indica si los slots (ordenados por inicio) cubren el intervalo sin huecos
+func cubierto(i intervalo, slots []domain.AgendaSlot) bool {
+	hasta := i.inicio
+	for _, s := range slots {
+		if !s.Fin.After(hasta) {
+			continue
+		}
+		if s.Inicio.After(hasta) {
+			return false
+		}
+		hasta = s.Fin
+		if !hasta.Before(i.fin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+// en arma un intervalo de horas del 2026-03-10 en UTC, p. ej. en("09:00", "10:30")
+func en(t *testing.T, desde, hasta string) intervalo {
+	t.Helper()
+	parse := func(hhmm string) time.Time {
+		i, err := time.Parse("2006-01-02 15:04", "2026-03-10 "+hhmm)
+		if err != nil {
+			t.Fatalf("hora %q: %v", hhmm, err)
+		}
+		return i
+	}
+	return intervalo{inicio: parse(desde), fin: parse(hasta)}
+}
+
+func slots(t *testing.T, tramos ...[2]string) []domain.AgendaSlot {
+	t.Helper()
+	var out []domain.AgendaSlot
+	for _, tr := range tramos {
+		i := en(t, tr[0], tr[1])
+		out = append(out, domain.AgendaSlot{Inicio: i.inicio, Fin: i.fin})
+	}
+	return out
+}
+
+// mananaUTC es un profesional MULTIAGENDA que atiende de 09:00 a 13:00 UTC de lunes a viernes
+func mananaUTC(idPersona int) domain.ConfigPersonaFull {
+	return domain.ConfigPersonaFull{
+		ID:          idPersona,
+		IDPersona:   idPersona,
+		ZonaHoraria: "UTC",
+		HoraInicio:  reloj(9, 0),
+		HoraFin:     reloj(13, 0),
+		Lunes:       true, Martes: true, Miercoles: true, Jueves: true, Viernes: true,
+		GeneraFeriados: true,
+		ModoAgenda:     domain.ModoAgendaMultiagenda,
+	}
+}
+
+// repoRecursos atiende solo lo que leen ocuparRecursos y slotsRecursos. Los profesionales de confs
+// son MULTIAGENDA; su agenda del día es la 55 y ya tiene las reservas de agenda.
+type repoRecursos struct {
+	ports.AiReservesRepository
+	reserva     domain.Reserva
+	libres      map[domain.RecursoReserva][]domain.AgendaSlot
+	confs       map[int]domain.ConfigPersonaFull
+	agenda      []domain.Reserva
+	ocupados    []domain.RecursoReserva
+	registradas []int
+	errOcupar   error
+}
+
+func (r *repoRecursos) GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error) {
+	return r.reserva, nil
+}
+
+func (r *repoRecursos) OcuparRecurso(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, recurso domain.RecursoReserva) error {
+	if r.errOcupar != nil {
+		return r.errOcupar
+	}
+	r.ocupados = append(r.ocupados, recurso)
+	return nil
+}
+
+func (r *repoRecursos) GetConfigPersonaFull(ctx context.Context, idPersona int) (domain.ConfigPersonaFull, error) {
+	if conf, ok := r.confs[idPersona]; ok {
+		return conf, nil
+	}
+	return domain.ConfigPersonaFull{ID: idPersona, IDPersona: idPersona, ModoAgenda: domain.ModoAgendaPregenerada}, nil
+}
+
+func (r *repoRecursos) GetBloqueos(ctx context.Context, req domain.BloqueoFiltro) ([]domain.AgendaBloqueo, error) {
+	return nil, nil
+}
+
+func (r *repoRecursos) LockAgendaDia(ctx context.Context, tx *sql.Tx, idConfPersonal int, fecha time.Time) (int, error) {
+	return 55, nil
+}
+
+func (r *repoRecursos) GetReservasAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.Reserva, error) {
+	return r.agenda, nil
+}
+
+func (r *repoRecursos) GetOfertasVigentesAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.OfertaListaEspera, error) {
+	return nil, nil
+}
+
+func (r *repoRecursos) GetReservasProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.Reserva, error) {
+	return r.agenda, nil
+}
+
+func (r *repoRecursos) GetOfertasVigentesProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.OfertaListaEspera, error) {
+	return nil, nil
+}
+
+func (r *repoRecursos) RegistrarAgendaRecurso(ctx context.Context, tx *sql.Tx, idAgenda, idReserva int) error {
+	r.registradas = append(r.registradas, idAgenda)
+	return nil
+}
+
+func (r *repoRecursos) GetSlotsLibresRecurso(ctx context.Context, recurso domain.RecursoReserva, desde, hasta time.Time) ([]domain.AgendaSlot, error) {
+	return r.libres[recurso], nil
+}
+
+func TestCubierto(t *testing.T) {
+	tests := []struct {
+		name  string
+		turno intervalo
+		slots []domain.AgendaSlot
+		want  bool
+	}{
+		{"un slot justo", en(t, "09:00", "09:30"), slots(t, [2]string{"09:00", "09:30"}), true},
+		{"slots consecutivos", en(t, "09:00", "10:00"), slots(t, [2]string{"09:00", "09:30"}, [2]string{"09:30", "10:00"}), true},
+		{"slot más grande que el turno", en(t, "09:15", "09:45"), slots(t, [2]string{"09:00", "10:00"}), true},
+		{"hueco en el medio", en(t, "09:00", "10:00"), slots(t, [2]string{"09:00", "09:30"}, [2]string{"09:45", "10:00"}), false},
+		{"empieza tarde", en(t, "09:00", "10:00"), slots(t, [2]string{"09:15", "10:00"}), false},
+		{"termina antes", en(t, "09:00", "10:00"), slots(t, [2]string{"09:00", "09:45"}), false},
+		{"slots anteriores se ignoran", en(t, "10:00", "10:30"), slots(t, [2]string{"09:00", "09:30"}, [2]string{"10:00", "10:30"}), true},
+		{"sin slots", en(t, "09:00", "09:30"), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cubierto(tt.turno, tt.slots); got != tt.want {
+				t.Fatalf("cubierto = %t, se esperaba %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHuecos(t *testing.T) {
+	ventana := en(t, "09:00", "13:00")
+
+	tests := []struct {
+		name     string
+		ocupados []intervalo
+		want     []intervalo
+	}{
+		{"libre todo el día", nil, []intervalo{ventana}},
+		{"ocupado en el medio", []intervalo{en(t, "10:00", "11:00")},
+			[]intervalo{en(t, "09:00", "10:00"), en(t, "11:00", "13:00")}},
+		{"desordenados y superpuestos", []intervalo{en(t, "11:30", "12:00"), en(t, "10:00", "11:00"), en(t, "10:30", "11:15")},
+			[]intervalo{en(t, "09:00", "10:00"), en(t, "11:15", "11:30"), en(t, "12:00", "13:00")}},
+		{"ocupado desde antes y hasta después", []intervalo{en(t, "08:00", "09:30"), en(t, "12:30", "14:00")},
+			[]intervalo{en(t, "09:30", "12:30")}},
+		{"fuera de la ventana", []intervalo{en(t, "07:00", "08:00"), en(t, "13:00", "14:00")}, []intervalo{ventana}},
+		{"todo ocupado", []intervalo{en(t, "08:00", "14:00")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := huecos(ventana, tt.ocupados); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("huecos = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDentroDelHorario(t *testing.T) {
+	h, err := horarioDesdeConfPersonal(mananaUTC(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	martes := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	domingo := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		fecha time.Time
+		turno intervalo
+		want  bool
+	}{
+		{"dentro", martes, en(t, "10:00", "11:00"), true},
+		{"todo el horario", martes, en(t, "09:00", "13:00"), true},
+		{"empieza antes de abrir", martes, en(t, "08:30", "09:30"), false},
+		{"termina después de cerrar", martes, en(t, "12:30", "13:30"), false},
+		{"día no hábil", domingo, intervalo{inicio: domingo.Add(10 * time.Hour), fin: domingo.Add(11 * time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dentroDelHorario(h, tt.fecha, tt.turno); got != tt.want {
+				t.Fatalf("dentroDelHorario = %t, se esperaba %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrdenarRecursos(t *testing.T) {
+	recursos := []domain.RecursoReserva{
+		{IDConfEstablecimiento: 9},
+		{IDProfesional: 12},
+		{IDConfEstablecimiento: 2},
+		{IDProfesional: 4},
+	}
+	want := []domain.RecursoReserva{
+		{IDConfEstablecimiento: 2},
+		{IDConfEstablecimiento: 9},
+		{IDProfesional: 4},
+		{IDProfesional: 12},
+	}
+
+	got := ordenarRecursos(recursos)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ordenarRecursos = %v, se esperaba %v", got, want)
+	}
+	if recursos[0].IDConfEstablecimiento != 9 {
+		t.Fatalf("ordenarRecursos modificó la lista recibida: %v", recursos)
+	}
+}
+
+func TestRecursosLibres(t *testing.T) {
+	turno := en(t, "09:00", "10:00")
+	sillon := slots(t, [2]string{"09:00", "10:00"})
+	profesional := slots(t, [2]string{"09:00", "09:30"}, [2]string{"09:30", "10:00"})
+	ocupado := slots(t, [2]string{"09:00", "09:30"})
+
+	tests := []struct {
+		name   string
+		libres [][]domain.AgendaSlot
+		want   bool
+	}{
+		{"sin recursos", nil, true},
+		{"todos libres", [][]domain.AgendaSlot{sillon, profesional}, true},
+		{"uno ocupado a mitad del turno", [][]domain.AgendaSlot{sillon, ocupado}, false},
+		{"uno sin slots", [][]domain.AgendaSlot{sillon, nil}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recursosLibres(turno, tt.libres); got != tt.want {
+				t.Fatalf("recursosLibres = %t, se esperaba %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidarRecursos(t *testing.T) {
+	tests := []struct {
+		name     string
+		recursos []domain.RecursoReserva
+		valido   bool
+	}{
+		{"sin recursos", nil, true},
+		{"profesional y establecimiento", []domain.RecursoReserva{{IDProfesional: 3}, {IDConfEstablecimiento: 3}}, true},
+		{"sin identificar", []domain.RecursoReserva{{}}, false},
+		{"repetido", []domain.RecursoReserva{{IDProfesional: 3}, {IDProfesional: 3}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validarRecursos(tt.recursos); (err == nil) != tt.valido {
+				t.Fatalf("validarRecursos = %v, se esperaba válido=%t", err, tt.valido)
+			}
+		})
+	}
+}
+
+func TestOcuparRecursos(t *testing.T) {
+	fallo := errors.New("slot del recurso ocupado")
+	turno := en(t, "10:00", "11:00")
+	reserva := domain.Reserva{ID: 7, Fecha: truncarFecha(turno.inicio), HoraInicio: "10:00", HoraFin: "11:00", Inicio: turno.inicio, Fin: turno.fin}
+	// otra reserva de la agenda del profesional MULTIAGENDA
+	otra := func(desde, hasta string) domain.Reserva {
+		i := en(t, desde, hasta)
+		return domain.Reserva{ID: 9, Fecha: reserva.Fecha, HoraInicio: desde, HoraFin: hasta, Inicio: i.inicio, Fin: i.fin}
+	}
+	multiagenda := domain.RecursoReserva{IDProfesional: 8}
+
+	tests := []struct {
+		name        string
+		turno       intervalo
+		recursos    []domain.RecursoReserva
+		agenda      []domain.Reserva
+		errOcupar   error
+		falla       bool
+		err         error // error esperado, si falla por uno conocido
+		ocupados    []domain.RecursoReserva
+		registradas []int
+	}{
+		{"sin recursos no toca nada", turno, nil, nil, nil, false, nil, nil, nil},
+		{"ocupa cada recurso en orden de id", turno, []domain.RecursoReserva{{IDProfesional: 4}, {IDConfEstablecimiento: 2}}, nil, nil, false, nil,
+			[]domain.RecursoReserva{{IDConfEstablecimiento: 2}, {IDProfesional: 4}}, nil},
+		{"recurso repetido no ocupa ninguno", turno, []domain.RecursoReserva{{IDProfesional: 4}, {IDProfesional: 4}}, nil, nil, true, nil, nil, nil},
+		{"recurso sin lugar", turno, []domain.RecursoReserva{{IDConfEstablecimiento: 2}}, nil, fallo, true, fallo, nil, nil},
+		{"multiagenda libre se anota en su agenda", turno, []domain.RecursoReserva{multiagenda}, []domain.Reserva{otra("11:00", "12:00")}, nil, false, nil, nil, []int{55}},
+		{"multiagenda ocupado", turno, []domain.RecursoReserva{multiagenda}, []domain.Reserva{otra("10:30", "11:30")}, nil, true, domain.ErrSlotAlreadyBooked, nil, nil},
+		{"la misma reserva no se pisa a sí misma", turno, []domain.RecursoReserva{multiagenda}, []domain.Reserva{reserva}, nil, false, nil, nil, []int{55}},
+		{"multiagenda fuera de su horario", en(t, "12:30", "13:30"), []domain.RecursoReserva{multiagenda}, nil, nil, true, domain.ErrSlotNotFound, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reserva
+			r.Inicio, r.Fin = tt.turno.inicio, tt.turno.fin
+			repo := &repoRecursos{
+				reserva:   r,
+				confs:     map[int]domain.ConfigPersonaFull{8: mananaUTC(8)},
+				agenda:    tt.agenda,
+				errOcupar: tt.errOcupar,
+			}
+			hs := &AiReservesService{hr: repo}
+
+			err := hs.ocuparRecursos(context.Background(), nil, r.ID, tt.recursos)
+			if (err != nil) != tt.falla {
+				t.Fatalf("ocuparRecursos = %v, se esperaba falla=%t", err, tt.falla)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("ocuparRecursos = %v, se esperaba %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(repo.ocupados, tt.ocupados) {
+				t.Fatalf("recursos ocupados = %v, se esperaba %v", repo.ocupados, tt.ocupados)
+			}
+			if !reflect.DeepEqual(repo.registradas, tt.registradas) {
+				t.Fatalf("agendas registradas = %v, se esperaba %v", repo.registradas, tt.registradas)
+			}
+		})
+	}
+}
+
+func TestSlotsRecursos(t *testing.T) {
+	sillon := domain.RecursoReserva{IDConfEstablecimiento: 2}
+	profesional := domain.RecursoReserva{IDProfesional: 4}
+	multiagenda := domain.RecursoReserva{IDProfesional: 8}
+	ocupado := en(t, "10:00", "11:00")
+	repo := &repoRecursos{
+		libres: map[domain.RecursoReserva][]domain.AgendaSlot{
+			sillon:      slots(t, [2]string{"09:00", "10:00"}, [2]string{"11:00", "12:00"}),
+			profesional: slots(t, [2]string{"09:30", "10:00"}, [2]string{"11:00", "12:00"}),
+		},
+		confs:  map[int]domain.ConfigPersonaFull{8: mananaUTC(8)},
+		agenda: []domain.Reserva{{ID: 9, Fecha: truncarFecha(ocupado.inicio), Inicio: ocupado.inicio, Fin: ocupado.fin}},
+	}
+	hs := &AiReservesService{hr: repo}
+	dia := en(t, "00:00", "23:59")
+
+	libres, err := hs.slotsRecursos(context.Background(), []domain.RecursoReserva{sillon, profesional, multiagenda}, dia.inicio, dia.fin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libres) != 3 {
+		t.Fatalf("slotsRecursos devolvió %d listas, se esperaban 3", len(libres))
+	}
+	// el profesional MULTIAGENDA no tiene slots: sus huecos del día se arman desde su horario y su ocupación
+	if want := slots(t, [2]string{"09:00", "10:00"}, [2]string{"11:00", "13:00"}); len(libres[2]) != len(want) ||
+		!libres[2][0].Inicio.Equal(want[0].Inicio) || !libres[2][1].Fin.Equal(want[1].Fin) {
+		t.Fatalf("huecos del profesional MULTIAGENDA = %v, se esperaba %v", libres[2], want)
+	}
+
+	tests := []struct {
+		name  string
+		turno intervalo
+		want  bool
+	}{
+		{"libre en los tres", en(t, "09:30", "10:00"), true},
+		{"el profesional recién atiende 09:30", en(t, "09:00", "09:30"), false},
+		{"el MULTIAGENDA está ocupado", en(t, "10:00", "10:30"), false},
+		{"libre después de la reserva", en(t, "11:00", "12:00"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recursosLibres(tt.turno, libres); got != tt.want {
+				t.Fatalf("recursosLibres = %t, se esperaba %t", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := hs.slotsRecursos(context.Background(), []domain.RecursoReserva{{}}, dia.inicio, dia.fin); err == nil {
+		t.Fatalf("slotsRecursos con un recurso sin identificar, se esperaba error")
+	}
+}
@@ -0,0 +1,402 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/zona"
+)
+
+// En MULTIAGENDA no hay slots materializados: la disponibilidad se calcula en el momento
+// a partir del horario del profesional, menos reservas vigentes, bloqueos y feriados.
+// Los inicios posibles avanzan con el paso de la duración más corta que ofrece el profesional,
+// así un control de 15' y un tratamiento de 60' comparten la misma grilla sin pisarse.
+
+// intervalo es un rango [inicio, fin) de instantes
+type intervalo struct {
+	inicio time.Time
+	fin    time.Time
+}
+
+func (i intervalo) superpone(otro intervalo) bool {
+	return i.inicio.Before(otro.fin) && otro.inicio.Before(i.fin)
+}
+
+func superponeAlguno(i intervalo, ocupados []intervalo) bool {
+	for _, o := range ocupados {
+		if i.superpone(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// pasoMultiagenda es la duración más corta entre los sub tipos que ofrece el profesional
+func pasoMultiagenda(subTipos []domain.ConfigPersonalSubTipo) time.Duration {
+	var paso time.Duration
+	for _, st := range subTipos {
+		d := time.Duration(st.DuracionReservaMinutos) * time.Minute
+		if d > 0 && (paso == 0 || d < paso) {
+			paso = d
+		}
+	}
+	return paso
+}
+
+// candidatosDia devuelve los turnos posibles del día que no se superponen con lo ocupado.
+// Como en los slots pregenerados, una hora de reloj repetida por el cambio de horario se ofrece una sola vez.
+func candidatosDia(h horarioAgenda, fecha time.Time, duracion, paso time.Duration, ocupados []intervalo) []intervalo {
+	if !h.dias[fecha.Weekday()] || duracion <= 0 || paso <= 0 {
+		return nil
+	}
+
+	inicio := zona.EnFechaAjustada(fecha, h.horaInicio, h.loc)
+	fin := zona.EnFechaAjustada(fecha, h.horaFin, h.loc)
+
+	var candidatos []intervalo
+	vistas := map[string]bool{}
+	for t := inicio; !t.Add(duracion).After(fin); t = t.Add(paso) {
+		if vistas[t.Format("15:04")] {
+			continue
+		}
+		vistas[t.Format("15:04")] = true
+
+		c := intervalo{inicio: t, fin: t.Add(duracion)}
+		if superponeAlguno(c, ocupados) {
+			continue
+		}
+		candidatos = append(candidatos, c)
+	}
+
+	return candidatos
+}
+
+func intervaloReserva(r domain.Reserva) (intervalo, error) {
+	inicio, err := inicioReserva(r)
+	if err != nil {
+		return intervalo{}, err
+	}
+	fin, err := finReserva(r)
+	if err != nil {
+		return intervalo{}, err
+	}
+	return intervalo{inicio: inicio, fin: fin}, nil
+}
+
+// ocupacion junta reservas y bloqueos como intervalos ocupados. Las reservas se extienden
+// el margen pedido de cada lado (buffer entre turnos de las reglas de reserva).
+func ocupacion(reservas []domain.Reserva, bloqueos []domain.AgendaBloqueo, margen time.Duration) ([]intervalo, error) {
+	ocupados := make([]intervalo, 0, len(reservas)+len(bloqueos))
+	for _, r := range reservas {
+		i, err := intervaloReserva(r)
+		if err != nil {
+			return nil, err
+		}
+		ocupados = append(ocupados, intervalo{inicio: i.inicio.Add(-margen), fin: i.fin.Add(margen)})
+	}
+	for _, b := range bloqueos {
+		ocupados = append(ocupados, intervalo{inicio: b.Inicio, fin: b.Fin})
+	}
+	return ocupados, nil
+}
+
+// datosMultiagenda resuelve duración del sub tipo pedido y paso de la grilla del profesional
+func (hs *AiReservesService) datosMultiagenda(ctx context.Context, conf domain.ConfigPersonaFull, idSubTipo *int) (time.Duration, time.Duration, error) {
+	subTipos, err := hs.hr.GetSubTiposConfPersonal(ctx, conf.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	duracion, err := duracionSubTipo(subTipos, *idSubTipo)
+	if err != nil {
+		return 0, 0, err
+	}
+	if *idSubTipo == 0 {
+		*idSubTipo = subTipos[0].IDSubTipoUnidadReserva
+	}
+
+	return duracion, pasoMultiagenda(subTipos), nil
+}
+
+// buscarMultiagenda calcula la disponibilidad de un profesional MULTIAGENDA con los mismos
+// filtros y paginación que la búsqueda sobre slots pregenerados
+func (hs *AiReservesService) buscarMultiagenda(ctx context.Context, req domain.SearchReserve, conf domain.ConfigPersonaFull) (domain.ResultadoBusqueda, error) {
+
+	duracion, paso, err := hs.datosMultiagenda(ctx, conf, &req.IDSubTipoUnidadReserva)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	h, err := horarioDesdeConfPersonal(conf)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	desde := truncarFecha(req.FechaDesde)
+	hasta := truncarFecha(req.FechaHasta)
+
+	// 1) Lo ocupado del rango: reservas vigentes, turnos retenidos para la lista de espera, bloqueos y feriados
+	reservas, err := hs.hr.GetReservasProfesional(ctx, conf.ID, desde, hasta)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	ofertas, err := hs.hr.GetOfertasVigentesProfesional(ctx, conf.ID, desde, hasta)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+	reservas = append(reservas, reservasOfertadas(ofertas)...)
+
+	bloqueos, err := hs.hr.GetBloqueos(ctx, domain.BloqueoFiltro{
+		IDConfPersonal: conf.ID,
+		FechaDesde:     time.Date(desde.Year(), desde.Month(), desde.Day(), 0, 0, 0, 0, h.loc),
+		FechaHasta:     time.Date(hasta.Year(), hasta.Month(), hasta.Day()+1, 0, 0, 0, 0, h.loc),
+	})
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	reglas, err := hs.hr.GetReglasReserva(ctx, req.IDSubTipoUnidadReserva, conf.ID)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	ocupados, err := ocupacion(reservas, bloqueos, time.Duration(reglas.BufferMinutos)*time.Minute)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	// Slots con lugar de los recursos adicionales que tienen que estar libres a la vez
+	var libresRecursos [][]domain.AgendaSlot
+	if recursos := req.Recursos(); len(recursos) > 0 {
+		libresRecursos, err = hs.slotsRecursos(ctx, recursos,
+			time.Date(desde.Year(), desde.Month(), desde.Day(), 0, 0, 0, 0, h.loc),
+			time.Date(hasta.Year(), hasta.Month(), hasta.Day()+1, 0, 0, 0, 0, h.loc))
+		if err != nil {
+			return domain.ResultadoBusqueda{}, err
+		}
+	}
+
+	// Días y semanas en los que el paciente ya llegó a su tope de reservas
+	topeDia, topeSemana, err := hs.topesPaciente(ctx, req, reglas, desde, hasta)
+	if err != nil {
+		return domain.ResultadoBusqueda{}, err
+	}
+
+	feriados := map[time.Time]bool{}
+	if !conf.GeneraFeriados {
+		lista, err := hs.hr.GetFeriados(ctx, domain.FeriadoFiltro{FechaDesde: desde, FechaHasta: hasta})
+		if err != nil {
+			return domain.ResultadoBusqueda{}, err
+		}
+		for _, f := range lista {
+			feriados[truncarFecha(f.Fecha)] = true
+		}
+	}
+
+	diasSemana := map[int]bool{}
+	for _, d := range req.DiasSemana {
+		diasSemana[d] = true
+	}
+
+	// 2) Candidatos día por día con los filtros de la búsqueda
+	ahora := time.Now()
+	var libres []domain.SlotDisponible
+
+	for fecha := desde; !fecha.After(hasta); fecha = fecha.AddDate(0, 0, 1) {
+		if feriados[fecha] || (len(diasSemana) > 0 && !diasSemana[int(fecha.Weekday())]) ||
+			topeDia[fecha] || topeSemana[inicioSemana(fecha)] {
+			continue
+		}
+
+		for _, c := range candidatosDia(h, fecha, duracion, paso, ocupados) {
+			horaInicio, horaFin := c.inicio.Format("15:04"), c.fin.Format("15:04")
+
+			if !c.inicio.After(ahora) || reglasDeTiempo(reglas, c.inicio, ahora) != nil ||
+				!recursosLibres(c, libresRecursos) ||
+				(req.HoraDesde != "" && horaInicio < req.HoraDesde) ||
+				(req.HoraHasta != "" && horaFin > req.HoraHasta) {
+				continue
+			}
+
+			libres = append(libres, domain.SlotDisponible{
+				IDConfPersonal: conf.ID,
+				IDProfesional:  conf.IDPersona,
+				Fecha:          fecha,
+				HoraInicio:     horaInicio,
+				HoraFin:        horaFin,
+				ZonaHoraria:    h.zonaHoraria,
+				Inicio:         c.inicio,
+				Fin:            c.fin,
+				Capacidad:      1,
+				Cupos:          1,
+			})
+
+			if req.PrimeroDisponible {
+				break
+			}
+		}
+	}
+
+	// 3) Paginar
+	total := len(libres)
+	desdeIdx := (req.Pagina - 1) * req.TamanioPagina
+	if desdeIdx > total {
+		desdeIdx = total
+	}
+	hastaIdx := desdeIdx + req.TamanioPagina
+	if hastaIdx > total {
+		hastaIdx = total
+	}
+
+	return domain.ResultadoBusqueda{
+		Dias:          agruparPorDia(libres[desdeIdx:hastaIdx]),
+		Pagina:        req.Pagina,
+		TamanioPagina: req.TamanioPagina,
+		TotalSlots:    total,
+	}, nil
+}
+
+// reservarMultiagenda reserva un turno calculado: asegura la agenda del día (y la bloquea para
+// serializar reservas concurrentes) y verifica que el turno esté en la grilla y libre
+func (hs *AiReservesService) reservarMultiagenda(ctx context.Context, tx *sql.Tx, req domain.Reserva, conf domain.ConfigPersonaFull) (int, error) {
+
+	req, err := hs.turnoMultiagenda(ctx, tx, req, conf)
+	if err != nil {
+		return 0, err
+	}
+
+	return hs.hr.CreateReservaSinSlot(ctx, tx, req)
+}
+
+// turnoMultiagenda valida el turno pedido y lo deja listo para grabar (agenda, fecha y hora de fin).
+// Si req.ID no es cero se trata de mover esa reserva: no cuenta como ocupación propia.
+func (hs *AiReservesService) turnoMultiagenda(ctx context.Context, tx *sql.Tx, req domain.Reserva, conf domain.ConfigPersonaFull) (domain.Reserva, error) {
+
+	duracion, paso, err := hs.datosMultiagenda(ctx, conf, &req.IDSubTipoUnidadReserva)
+	if err != nil {
+		return req, err
+	}
+
+	h, err := horarioDesdeConfPersonal(conf)
+	if err != nil {
+		return req, err
+	}
+
+	fecha := truncarFecha(req.Fecha)
+	hora, err := time.Parse("15:04", req.HoraInicio)
+	if err != nil {
+		return req, fmt.Errorf("hora inválida '%s', se espera HH:mm", req.HoraInicio)
+	}
+	inicio, existe := zona.EnFecha(fecha, hora, h.loc)
+	if !existe {
+		return req, fmt.Errorf("%w: %s %s no existe en %s (cambio de horario)",
+			domain.ErrSlotNotFound, fecha.Format("2006-01-02"), req.HoraInicio, h.zonaHoraria)
+	}
+	pedido := intervalo{inicio: inicio, fin: inicio.Add(duracion)}
+
+	if !pedido.inicio.After(time.Now()) {
+		return req, fmt.Errorf("no se puede reservar un turno pasado (%s %s)", fecha.Format("2006-01-02"), req.HoraInicio)
+	}
+
+	// 1) El turno tiene que caer en la grilla del horario de atención
+	if !contieneInicio(candidatosDia(h, fecha, duracion, paso, nil), pedido.inicio) {
+		return req, fmt.Errorf("%w: %s %s fuera del horario del profesional %d",
+			domain.ErrSlotNotFound, fecha.Format("2006-01-02"), req.HoraInicio, conf.IDPersona)
+	}
+
+	// 2) Libre de feriados, bloqueos y otras reservas, con la agenda del día bloqueada
+	req.IDAgenda, err = hs.lockIntervaloMultiagenda(ctx, tx, conf, h, fecha, pedido, req.ID)
+	if err != nil {
+		return req, err
+	}
+
+	// 3) Turno sin slot materializado
+	req.Fecha = fecha
+	req.HoraFin = pedido.fin.Format("15:04")
+	req.ZonaHoraria = h.zonaHoraria
+	req.Inicio = pedido.inicio
+	req.Fin = pedido.fin
+
+	return req, nil
+}
+
+// lockIntervaloMultiagenda bloquea la agenda del día del profesional (nadie más reserva ese día hasta el
+// commit) y controla que el intervalo no caiga en feriado ni bloqueo ni se superponga con reservas u
+// ofertas vigentes. idReserva, si no es cero, es la reserva que se está moviendo y no cuenta como ocupación.
+func (hs *AiReservesService) lockIntervaloMultiagenda(ctx context.Context, tx *sql.Tx, conf domain.ConfigPersonaFull, h horarioAgenda,
+	fecha time.Time, pedido intervalo, idReserva int) (int, error) {
+	turno := fecha.Format("2006-01-02") + " " + pedido.inicio.In(h.loc).Format("15:04")
+
+	if !conf.GeneraFeriados {
+		feriados, err := hs.hr.GetFeriados(ctx, domain.FeriadoFiltro{FechaDesde: fecha, FechaHasta: fecha})
+		if err != nil {
+			return 0, err
+		}
+		if len(feriados) > 0 {
+			return 0, fmt.Errorf("%w: %s es feriado", domain.ErrSlotNotFound, fecha.Format("2006-01-02"))
+		}
+	}
+
+	bloqueos, err := hs.hr.GetBloqueos(ctx, domain.BloqueoFiltro{
+		IDConfPersonal: conf.ID,
+		FechaDesde:     pedido.inicio,
+		FechaHasta:     pedido.fin,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(bloqueos) > 0 {
+		return 0, fmt.Errorf("%w: %s está bloqueado", domain.ErrSlotNotFound, turno)
+	}
+
+	idAgenda, err := hs.hr.LockAgendaDia(ctx, tx, conf.ID, fecha)
+	if err != nil {
+		return 0, err
+	}
+
+	reservas, err := hs.hr.GetReservasAgenda(ctx, tx, idAgenda)
+	if err != nil {
+		return 0, err
+	}
+	reservas = sinReserva(reservas, idReserva)
+	ofertas, err := hs.hr.GetOfertasVigentesAgenda(ctx, tx, idAgenda)
+	if err != nil {
+		return 0, err
+	}
+	ocupados, err := ocupacion(append(reservas, reservasOfertadas(ofertas)...), nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	if superponeAlguno(pedido, ocupados) {
+		return 0, fmt.Errorf("%w: %s se superpone con otra reserva", domain.ErrSlotAlreadyBooked, turno)
+	}
+
+	return idAgenda, nil
+}
+
+// sinReserva quita de la lista la reserva indicada (la que se está moviendo)
+func sinReserva(reservas []domain.Reserva, idReserva int) []domain.Reserva {
+	if idReserva == 0 {
+		return reservas
+	}
+	filtradas := reservas[:0]
+	for _, r := range reservas {
+		if r.ID != idReserva {
+			filtradas = append(filtradas, r)
+		}
+	}
+	return filtradas
+}
+
+func contieneInicio(candidatos []intervalo, inicio time.Time) bool {
+	for _, c := range candidatos {
+		if c.inicio.Equal(inicio) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package application
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+func TestCandidatosDia(t *testing.T) {
+	mananaBA, err := horarioDesdeConfPersonal(domain.ConfigPersonaFull{
+		ZonaHoraria: "America/Argentina/Buenos_Aires",
+		HoraInicio:  reloj(9, 0),
+		HoraFin:     reloj(12, 0),
+		Lunes:       true, Martes: true, Miercoles: true, Jueves: true, Viernes: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	madrugadaNY, err := horarioDesdeConfPersonal(domain.ConfigPersonaFull{
+		ZonaHoraria: "America/New_York",
+		HoraInicio:  reloj(0, 0),
+		HoraFin:     reloj(4, 0),
+		Domingo:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	martes := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	sabado := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	// 2026-11-01 a las 02:00 Nueva York vuelve a las 01:00: la 01:00 de reloj se repite
+	cambioNY := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	enBA := func(hhmm string) time.Time {
+		i, _ := time.ParseInLocation("2006-01-02 15:04", "2026-03-10 "+hhmm, mananaBA.loc)
+		return i
+	}
+
+	tests := []struct {
+		name     string
+		h        horarioAgenda
+		fecha    time.Time
+		duracion time.Duration
+		paso     time.Duration
+		ocupados []intervalo
+		inicios  []string
+	}{
+		{
+			name:     "grilla de media hora",
+			h:        mananaBA,
+			fecha:    martes,
+			duracion: 30 * time.Minute,
+			paso:     30 * time.Minute,
+			inicios:  []string{"09:00", "09:30", "10:00", "10:30", "11:00", "11:30"},
+		},
+		{
+			name:     "turno de una hora con paso de quince minutos",
+			h:        mananaBA,
+			fecha:    martes,
+			duracion: time.Hour,
+			paso:     15 * time.Minute,
+			inicios:  []string{"09:00", "09:15", "09:30", "09:45", "10:00", "10:15", "10:30", "10:45", "11:00"},
+		},
+		{
+			name:     "lo ocupado no se ofrece",
+			h:        mananaBA,
+			fecha:    martes,
+			duracion: 30 * time.Minute,
+			paso:     30 * time.Minute,
+			ocupados: []intervalo{{inicio: enBA("10:00"), fin: enBA("10:30")}, {inicio: enBA("11:15"), fin: enBA("11:45")}},
+			inicios:  []string{"09:00", "09:30", "10:30"},
+		},
+		{
+			name:     "día no hábil",
+			h:        mananaBA,
+			fecha:    sabado,
+			duracion: 30 * time.Minute,
+			paso:     30 * time.Minute,
+		},
+		{
+			name:     "turno más largo que el horario",
+			h:        mananaBA,
+			fecha:    martes,
+			duracion: 4 * time.Hour,
+			paso:     30 * time.Minute,
+		},
+		{
+			name:     "sin duración",
+			h:        mananaBA,
+			fecha:    martes,
+			duracion: 0,
+			paso:     30 * time.Minute,
+		},
+		{
+			name:     "la hora repetida por el cambio de horario se ofrece una vez",
+			h:        madrugadaNY,
+			fecha:    cambioNY,
+			duracion: time.Hour,
+			paso:     time.Hour,
+			inicios:  []string{"00:00", "01:00", "02:00", "03:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inicios []string
+			for _, c := range candidatosDia(tt.h, tt.fecha, tt.duracion, tt.paso, tt.ocupados) {
+				if got := c.fin.Sub(c.inicio); got != tt.duracion {
+					t.Fatalf("candidato %s dura %s, se esperaba %s", c.inicio, got, tt.duracion)
+				}
+				inicios = append(inicios, c.inicio.In(tt.h.loc).Format("15:04"))
+			}
+			if !reflect.DeepEqual(inicios, tt.inicios) {
+				t.Fatalf("candidatosDia = %v, se esperaba %v", inicios, tt.inicios)
+			}
+		})
+	}
+}
+
+func TestPasoMultiagenda(t *testing.T) {
+	tests := []struct {
+		name       string
+		duraciones []int
+		paso       time.Duration
+	}{
+		{"sin sub tipos", nil, 0},
+		{"un sub tipo", []int{45}, 45 * time.Minute},
+		{"el más corto", []int{60, 15, 30}, 15 * time.Minute},
+		{"ignora duraciones sin configurar", []int{0, 40}, 40 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subTipos []domain.ConfigPersonalSubTipo
+			for _, d := range tt.duraciones {
+				subTipos = append(subTipos, domain.ConfigPersonalSubTipo{DuracionReservaMinutos: d})
+			}
+			if got := pasoMultiagenda(subTipos); got != tt.paso {
+				t.Fatalf("pasoMultiagenda(%v) = %s, se esperaba %s", tt.duraciones, got, tt.paso)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// Relay del outbox: publica en RabbitMQ los eventos grabados junto con los cambios de las reservas.
+// Cada pasada reclama un lote con un lease corto (PROCESSING hasta locked_until), publica fuera de
+// toda transacción y marca cada evento en la suya: SENT al publicarse; si falla se reintenta con
+// espera creciente y, agotados los intentos, queda FAILED para revisarlo a mano. Entrega
+// at-least-once: si el relay se cae antes de marcar, el evento sale de nuevo al vencer el lease.
+
+// Eventos que reclama cada pasada del relay
+const loteOutbox = 20
+
+// Lease de los eventos reclamados: alcanza para publicar el lote aunque el broker tarde en confirmar
+const leaseOutbox = 5 * time.Minute
+
+// Intentos por defecto si la configuración no define OUTBOX_MAX_ATTEMPTS
+const maxIntentosOutboxDefault = 10
+
+// Espera entre reintentos: se duplica en cada intento hasta el máximo
+const (
+	esperaOutboxInicial = 5 * time.Second
+	esperaOutboxMaxima  = 15 * time.Minute
+)
+
+func (hs *AiReservesService) maxIntentosOutbox() int {
+	if hs.conf.OutboxMaxIntentos > 0 {
+		return hs.conf.OutboxMaxIntentos
+	}
+	return maxIntentosOutboxDefault
+}
+
+// ProcessOutboxAPI publica un lote de eventos pendientes. La llama periódicamente el relay del outbox.
+func (hs *AiReservesService) ProcessOutboxAPI(ctx context.Context) error {
+	var enviados, reintentos, fallidos int
+
+	eventos, err := hs.hr.ClaimOutboxPendientes(ctx, loteOutbox, leaseOutbox)
+	if err != nil {
+		return err
+	}
+
+	for _, ev := range eventos {
+		if errPub := hs.rmq.Publish(ctx, ev.Event); errPub != nil {
+			// 1) No salió: se reprograma o, sin intentos, queda FAILED
+			intentos := ev.Intentos + 1
+			estado := domain.OutboxPendiente
+			if intentos >= hs.maxIntentosOutbox() {
+				estado = domain.OutboxFallido
+				fallidos++
+				fmt.Printf("❌ Evento %s (%s) FAILED tras %d intentos: %v\n", ev.Event.ID, ev.Event.RoutingKey, intentos, errPub)
+			} else {
+				reintentos++
+			}
+
+			// Sin poder marcarlo se corta la pasada: lo que queda del lote vuelve al vencer el lease
+			if err := hs.hr.MarkOutboxError(ctx, ev.ID, estado, time.Now().Add(esperaOutbox(intentos)), errPub.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 2) Publicado
+		if err := hs.hr.MarkOutboxEnviado(ctx, ev.ID); err != nil {
+			return err
+		}
+		enviados++
+	}
+
+	if enviados+reintentos+fallidos > 0 {
+		fmt.Printf("📨 Outbox: %d eventos publicados, %d para reintentar, %d fallidos\n", enviados, reintentos, fallidos)
+	}
+
+	return nil
+}
+
+// esperaOutbox devuelve cuánto esperar antes del próximo intento (5s, 10s, 20s... hasta 15 minutos)
+func esperaOutbox(intentos int) time.Duration {
+	espera := esperaOutboxInicial
+	for i := 1; i < intentos && espera < esperaOutboxMaxima; i++ {
+		espera *= 2
+	}
+	if espera > esperaOutboxMaxima {
+		return esperaOutboxMaxima
+	}
+	return espera
+}
@@ -0,0 +1,143 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/config"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+// repoOutbox atiende solo lo que reclama y marca el relay del outbox
+type repoOutbox struct {
+	ports.AiReservesRepository
+	pendientes []domain.OutboxEvent
+	enviados   []int64
+	estados    map[int64]string
+	errMarcar  error // si no es nil, falla al marcar
+}
+
+func (r *repoOutbox) ClaimOutboxPendientes(ctx context.Context, limite int, lease time.Duration) ([]domain.OutboxEvent, error) {
+	return r.pendientes, nil
+}
+
+func (r *repoOutbox) MarkOutboxEnviado(ctx context.Context, id int64) error {
+	if r.errMarcar != nil {
+		return r.errMarcar
+	}
+	r.enviados = append(r.enviados, id)
+	return nil
+}
+
+func (r *repoOutbox) MarkOutboxError(ctx context.Context, id int64, estado string, proximoIntento time.Time, causa string) error {
+	if r.errMarcar != nil {
+		return r.errMarcar
+	}
+	r.estados[id] = estado
+	return nil
+}
+
+// colaContada cuenta los eventos publicados
+type colaContada struct {
+	publicados *[]string
+}
+
+func (c colaContada) Publish(ctx context.Context, event domain.Event) error {
+	*c.publicados = append(*c.publicados, event.ID)
+	return nil
+}
+
+// colaCaida rechaza la publicación de los eventos indicados
+type colaCaida struct {
+	caidos map[string]bool
+}
+
+func (c colaCaida) Publish(ctx context.Context, event domain.Event) error {
+	if c.caidos[event.ID] {
+		return errors.New("broker caído")
+	}
+	return nil
+}
+
+func TestEsperaOutbox(t *testing.T) {
+	tests := []struct {
+		intentos int
+		want     time.Duration
+	}{
+		{0, 5 * time.Second},
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{8, 640 * time.Second},
+		{9, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := esperaOutbox(tt.intentos); got != tt.want {
+			t.Errorf("esperaOutbox(%d) = %s, se esperaba %s", tt.intentos, got, tt.want)
+		}
+	}
+}
+
+func TestMaxIntentosOutbox(t *testing.T) {
+	if got := (&AiReservesService{}).maxIntentosOutbox(); got != maxIntentosOutboxDefault {
+		t.Errorf("maxIntentosOutbox sin configurar = %d, se esperaba %d", got, maxIntentosOutboxDefault)
+	}
+	if got := (&AiReservesService{conf: config.App{OutboxMaxIntentos: 3}}).maxIntentosOutbox(); got != 3 {
+		t.Errorf("maxIntentosOutbox configurado = %d, se esperaba 3", got)
+	}
+}
+
+func TestProcessOutboxAPI(t *testing.T) {
+	repo := &repoOutbox{
+		pendientes: []domain.OutboxEvent{
+			{ID: 1, Event: domain.Event{ID: "a"}},
+			{ID: 2, Event: domain.Event{ID: "b"}, Intentos: 0},
+			{ID: 3, Event: domain.Event{ID: "c"}, Intentos: 2},
+		},
+		estados: map[int64]string{},
+	}
+	hs := &AiReservesService{
+		hr:   repo,
+		conf: config.App{OutboxMaxIntentos: 3},
+		rmq:  colaCaida{caidos: map[string]bool{"b": true, "c": true}},
+	}
+
+	if err := hs.ProcessOutboxAPI(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1}; !reflect.DeepEqual(repo.enviados, want) {
+		t.Errorf("eventos enviados = %v, se esperaba %v", repo.enviados, want)
+	}
+	want := map[int64]string{2: domain.OutboxPendiente, 3: domain.OutboxFallido}
+	if !reflect.DeepEqual(repo.estados, want) {
+		t.Errorf("eventos con error = %v, se esperaba %v", repo.estados, want)
+	}
+}
+
+func TestProcessOutboxAPICortaSiNoPuedeMarcar(t *testing.T) {
+	// Sin poder marcar el primer evento la pasada termina: el resto queda reclamado hasta que vence el lease
+	errDB := errors.New("db caída")
+	repo := &repoOutbox{
+		pendientes: []domain.OutboxEvent{
+			{ID: 1, Event: domain.Event{ID: "a"}},
+			{ID: 2, Event: domain.Event{ID: "b"}},
+		},
+		estados:   map[int64]string{},
+		errMarcar: errDB,
+	}
+	var publicados []string
+	hs := &AiReservesService{hr: repo, rmq: colaContada{publicados: &publicados}}
+
+	if err := hs.ProcessOutboxAPI(context.Background()); !errors.Is(err, errDB) {
+		t.Fatalf("ProcessOutboxAPI = %v, se esperaba %v", err, errDB)
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(publicados, want) {
+		t.Errorf("eventos publicados = %v, se esperaba %v", publicados, want)
+	}
+}
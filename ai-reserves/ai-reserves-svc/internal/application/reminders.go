@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// Recordatorios: cada sub tipo define cuántas horas antes del turno se avisa a las reservas confirmadas.
+// El scheduler registra cada aviso y encola su reserve.reminder en la misma transacción, así no se
+// repite entre reinicios ni entre réplicas.
+
+// Recordatorios que se procesan por pasada del scheduler
+const loteRecordatorios = 50
+
+func (hs *AiReservesService) UpdRecordatoriosAPI(ctx context.Context, req domain.RecordatoriosConfig) (domain.RecordatoriosConfig, error) {
+
+	if req.IDSubTipoUnidadReserva == 0 {
+		return domain.RecordatoriosConfig{}, fmt.Errorf("IDSubTipoUnidadReserva es obligatorio")
+	}
+	for _, h := range req.HorasAntes {
+		if h <= 0 {
+			return domain.RecordatoriosConfig{}, fmt.Errorf("HorasAntes tiene que ser mayor a 0 (lista vacía = sin recordatorios)")
+		}
+	}
+
+	return hs.hr.UpdRecordatoriosSubTipo(ctx, req)
+}
+
+// ProcessRecordatoriosAPI toma los recordatorios que ya tocan y encola un evento por reserva.
+// La llama periódicamente el scheduler de recordatorios.
+func (hs *AiReservesService) ProcessRecordatoriosAPI(ctx context.Context) error {
+	var recordatorios []domain.RecordatorioReserva
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		recordatorios, err = hs.hr.ClaimRecordatorios(ctx, tx, loteRecordatorios)
+		if err != nil {
+			return err
+		}
+
+		// El aviso queda registrado y encolado en la misma transacción
+		for _, rec := range recordatorios {
+			if err := hs.encolarEvento(ctx, tx, domain.EventReserveReminder, reservaRecordatorioPayload(rec)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(recordatorios) > 0 {
+		fmt.Printf("⏰ %d recordatorios de reserva encolados\n", len(recordatorios))
+	}
+
+	return nil
+}
+
+func reservaRecordatorioPayload(rec domain.RecordatorioReserva) domain.ReservaRecordatorioPayload {
+	r := rec.Reserva
+
+	return domain.ReservaRecordatorioPayload{
+		IDReserva:              r.ID,
+		IDPaciente:             r.IDPaciente,
+		IDAgenda:               r.IDAgenda,
+		IDProfesional:          r.IDProfesional,
+		IDConfEstablecimiento:  r.IDConfEstablecimiento,
+		IDSubTipoUnidadReserva: r.IDSubTipoUnidadReserva,
+		Fecha:                  r.Fecha.Format("2006-01-02"),
+		HoraInicio:             r.HoraInicio,
+		HoraFin:                r.HoraFin,
+		ZonaHoraria:            r.ZonaHoraria,
+		Inicio:                 r.Inicio.Format(time.RFC3339),
+		HorasAntes:             rec.HorasAntes,
+	}
+}
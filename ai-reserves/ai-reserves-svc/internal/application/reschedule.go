@@ -0,0 +1,159 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// RescheduleReserveAPI mueve una reserva vigente a otro turno en una sola transacción: si el turno
+// nuevo no está disponible la reserva queda donde estaba. Conserva id, estado, paciente y observaciones.
+func (hs *AiReservesService) RescheduleReserveAPI(ctx context.Context, req domain.ReservaReprogramacion) (domain.Reserva, error) {
+
+	if req.IDReserva == 0 || req.HoraInicio == "" {
+		return domain.Reserva{}, fmt.Errorf("IDReserva y HoraInicio son obligatorios")
+	}
+
+	var movida domain.Reserva
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 1) Bloquear la reserva: solo se mueven reservas vigentes
+		anterior, err := hs.hr.GetReservaForUpdate(ctx, tx, req.IDReserva)
+		if err != nil {
+			return err
+		}
+		if anterior.Estado != domain.ReservaPendiente && anterior.Estado != domain.ReservaConfirmada {
+			return fmt.Errorf("%w: la reserva %d está %s y no se puede reprogramar",
+				domain.ErrReservaEstadoInvalido, anterior.ID, anterior.Estado)
+		}
+
+		// 2) Resolver el destino; sin agenda ni entidad se queda con el mismo profesional / establecimiento
+		turnoAnterior, err := hs.hr.GetTurnoReserva(ctx, tx, anterior)
+		if err != nil {
+			return err
+		}
+
+		destino := domain.Reserva{
+			ID:                     anterior.ID,
+			IDAgenda:               req.IDAgenda,
+			IDProfesional:          req.IDProfesional,
+			IDConfEstablecimiento:  req.IDConfEstablecimiento,
+			Fecha:                  req.Fecha,
+			HoraInicio:             req.HoraInicio,
+			IDSubTipoUnidadReserva: anterior.IDSubTipoUnidadReserva,
+			TokenRetencion:         req.TokenRetencion,
+		}
+		if destino.IDAgenda == 0 && destino.IDProfesional == 0 && destino.IDConfEstablecimiento == 0 {
+			destino.IDProfesional = turnoAnterior.IDProfesional
+			destino.IDConfEstablecimiento = turnoAnterior.IDConfEstablecimiento
+			if destino.Fecha.IsZero() {
+				destino.Fecha = anterior.Fecha
+			}
+		}
+
+		destino, multiagenda, err := hs.prepararReserva(ctx, destino)
+		if err != nil {
+			return err
+		}
+
+		// Los recursos adicionales acompañan a la reserva al turno nuevo
+		recursos, err := hs.hr.GetRecursosReserva(ctx, tx, anterior.ID)
+		if err != nil {
+			return err
+		}
+
+		if multiagenda != nil {
+			if req.TokenRetencion != "" {
+				return fmt.Errorf("%w: las agendas MULTIAGENDA no usan retenciones de slot", domain.ErrRetencionInvalida)
+			}
+			if destino, err = hs.turnoMultiagenda(ctx, tx, destino, *multiagenda); err != nil {
+				return err
+			}
+		}
+
+		// Con el destino ya resuelto a su agenda: el mismo turno se rechaza antes de tocar slots
+		if destino.IDAgenda == anterior.IDAgenda && destino.HoraInicio == anterior.HoraInicio {
+			return fmt.Errorf("la reserva %d ya está en ese turno", anterior.ID)
+		}
+
+		// 3) Liberar el turno anterior y ocupar el nuevo
+		if multiagenda != nil {
+			err = hs.hr.MoveReservaSinSlot(ctx, tx, anterior.ID, destino)
+		} else {
+			destino, err = hs.hr.MoveReserve(ctx, tx, anterior.ID, destino)
+		}
+		if err != nil {
+			return err
+		}
+		if err := hs.ocuparRecursos(ctx, tx, anterior.ID, recursos); err != nil {
+			return err
+		}
+		if err := hs.validarReglasReserva(ctx, tx, anterior.ID); err != nil {
+			return err
+		}
+		// Los recordatorios se vuelven a enviar según el turno nuevo
+		if err := hs.hr.ResetRecordatorios(ctx, tx, anterior.ID); err != nil {
+			return err
+		}
+
+		movida = anterior
+		movida.IDAgenda = destino.IDAgenda
+		movida.Fecha = destino.Fecha
+		movida.HoraInicio = destino.HoraInicio
+		movida.HoraFin = destino.HoraFin
+
+		// 4) Historial: mismo estado, el motivo deja constancia del cambio de turno
+		motivo := fmt.Sprintf("reprogramada desde %s %s", anterior.Fecha.Format("2006-01-02"), anterior.HoraInicio)
+		if req.Motivo != "" {
+			motivo = strings.Join([]string{motivo, req.Motivo}, ": ")
+		}
+		err = hs.hr.InsertHistorialReserva(ctx, tx, historialDe(anterior, domain.ReservaTransicion{
+			IDReserva: anterior.ID,
+			Estado:    anterior.Estado,
+			Actor:     req.Actor,
+			Motivo:    motivo,
+		}))
+		if err != nil {
+			return err
+		}
+
+		if err := hs.encolarEvento(ctx, tx, domain.EventReserveRescheduled, reservaReprogramadaPayload(anterior, movida, req)); err != nil {
+			return err
+		}
+
+		// 5) El turno que quedó libre pasa a la lista de espera
+		_, err = hs.ofrecerTurno(ctx, tx, turnoAnterior)
+		return err
+	})
+	if err != nil {
+		return domain.Reserva{}, err
+	}
+
+	return movida, nil
+}
+
+func reservaReprogramadaPayload(anterior, movida domain.Reserva, req domain.ReservaReprogramacion) domain.ReservaReprogramadaPayload {
+	actor := req.Actor
+	if actor == "" {
+		actor = actorSistema
+	}
+
+	return domain.ReservaReprogramadaPayload{
+		IDReserva:              movida.ID,
+		IDPaciente:             movida.IDPaciente,
+		IDSubTipoUnidadReserva: movida.IDSubTipoUnidadReserva,
+		IDAgendaAnterior:       anterior.IDAgenda,
+		FechaAnterior:          anterior.Fecha.Format("2006-01-02"),
+		HoraInicioAnterior:     anterior.HoraInicio,
+		HoraFinAnterior:        anterior.HoraFin,
+		IDAgenda:               movida.IDAgenda,
+		Fecha:                  movida.Fecha.Format("2006-01-02"),
+		HoraInicio:             movida.HoraInicio,
+		HoraFin:                movida.HoraFin,
+		ReprogramadoPor:        actor,
+		Motivo:                 req.Motivo,
+	}
+}
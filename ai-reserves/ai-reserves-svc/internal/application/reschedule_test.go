@@ -0,0 +1,146 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+// repoReprogramacion atiende solo lo que recorre RescheduleReserveAPI en una agenda PREGENERADA
+type repoReprogramacion struct {
+	ports.AiReservesRepository
+	reserva   domain.Reserva
+	recursos  []domain.RecursoReserva
+	errMover  error
+	movidas   []domain.Reserva
+	ocupados  []domain.RecursoReserva
+	historial int
+	eventos   []string
+}
+
+func (r *repoReprogramacion) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (r *repoReprogramacion) GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error) {
+	return r.reserva, nil
+}
+
+func (r *repoReprogramacion) GetTurnoReserva(ctx context.Context, tx *sql.Tx, reserva domain.Reserva) (domain.TurnoLiberado, error) {
+	// Turno ya pasado: el que queda libre no se ofrece a la lista de espera
+	return domain.TurnoLiberado{IDAgenda: reserva.IDAgenda, IDSlot: 31, Fecha: reserva.Fecha, HoraInicio: reserva.HoraInicio}, nil
+}
+
+func (r *repoReprogramacion) GetRecursosReserva(ctx context.Context, tx *sql.Tx, idReserva int) ([]domain.RecursoReserva, error) {
+	return r.recursos, nil
+}
+
+func (r *repoReprogramacion) MoveReserve(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) (domain.Reserva, error) {
+	if r.errMover != nil {
+		return domain.Reserva{}, r.errMover
+	}
+	destino.HoraFin = "10:30"
+	r.movidas = append(r.movidas, destino)
+	return destino, nil
+}
+
+func (r *repoReprogramacion) OcuparRecurso(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, recurso domain.RecursoReserva) error {
+	r.ocupados = append(r.ocupados, recurso)
+	return nil
+}
+
+func (r *repoReprogramacion) GetReglasReserva(ctx context.Context, idSubTipo int, idConfPersonal int) (domain.ReglasReserva, error) {
+	return domain.ReglasReserva{}, nil
+}
+
+func (r *repoReprogramacion) ResetRecordatorios(ctx context.Context, tx *sql.Tx, idReserva int) error {
+	return nil
+}
+
+func (r *repoReprogramacion) InsertHistorialReserva(ctx context.Context, tx *sql.Tx, h domain.ReservaHistorial) error {
+	r.historial++
+	return nil
+}
+
+func (r *repoReprogramacion) PushEventToQueue(ctx context.Context, tx *sql.Tx, event domain.Event) error {
+	r.eventos = append(r.eventos, event.RoutingKey)
+	return nil
+}
+
+func TestRescheduleReserveAPI(t *testing.T) {
+	anterior := domain.Reserva{
+		ID:         7,
+		IDAgenda:   10,
+		Fecha:      time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC),
+		HoraInicio: "09:00",
+		HoraFin:    "09:30",
+		Estado:     domain.ReservaConfirmada,
+	}
+	sillon := []domain.RecursoReserva{{IDConfEstablecimiento: 2}}
+
+	tests := []struct {
+		name     string
+		estado   string
+		req      domain.ReservaReprogramacion
+		recursos []domain.RecursoReserva
+		errMover error
+		falla    bool
+		err      error // error esperado, si es uno conocido
+		movidas  int
+		ocupados []domain.RecursoReserva
+	}{
+		{"a un turno libre", domain.ReservaConfirmada, domain.ReservaReprogramacion{IDReserva: 7, IDAgenda: 10, HoraInicio: "10:00"},
+			nil, nil, false, nil, 1, nil},
+		{"los recursos acompañan a la reserva", domain.ReservaConfirmada, domain.ReservaReprogramacion{IDReserva: 7, IDAgenda: 10, HoraInicio: "10:00"},
+			sillon, nil, false, nil, 1, sillon},
+		{"al mismo turno no toca el slot", domain.ReservaConfirmada, domain.ReservaReprogramacion{IDReserva: 7, IDAgenda: 10, HoraInicio: "09:00"},
+			sillon, nil, true, nil, 0, nil},
+		{"a un turno tomado", domain.ReservaConfirmada, domain.ReservaReprogramacion{IDReserva: 7, IDAgenda: 10, HoraInicio: "10:00"},
+			sillon, domain.ErrSlotAlreadyBooked, true, domain.ErrSlotAlreadyBooked, 0, nil},
+		{"reserva cancelada", domain.ReservaCancelada, domain.ReservaReprogramacion{IDReserva: 7, IDAgenda: 10, HoraInicio: "10:00"},
+			nil, nil, true, domain.ErrReservaEstadoInvalido, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reserva := anterior
+			reserva.Estado = tt.estado
+			repo := &repoReprogramacion{reserva: reserva, recursos: tt.recursos, errMover: tt.errMover}
+			hs := &AiReservesService{hr: repo}
+
+			movida, err := hs.RescheduleReserveAPI(context.Background(), tt.req)
+			if (err != nil) != tt.falla {
+				t.Fatalf("RescheduleReserveAPI = %v, se esperaba falla=%t", err, tt.falla)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("RescheduleReserveAPI = %v, se esperaba %v", err, tt.err)
+			}
+			if len(repo.movidas) != tt.movidas {
+				t.Fatalf("MoveReserve llamado %d veces, se esperaban %d", len(repo.movidas), tt.movidas)
+			}
+			if !reflect.DeepEqual(repo.ocupados, tt.ocupados) {
+				t.Fatalf("recursos ocupados = %v, se esperaba %v", repo.ocupados, tt.ocupados)
+			}
+			if tt.falla {
+				if repo.historial != 0 || len(repo.eventos) != 0 {
+					t.Fatalf("reprogramación fallida dejó historial (%d) o eventos (%v)", repo.historial, repo.eventos)
+				}
+				return
+			}
+
+			if movida.HoraInicio != tt.req.HoraInicio || movida.HoraFin != "10:30" || movida.Estado != tt.estado {
+				t.Fatalf("reserva movida = %s-%s %s, se esperaba %s-10:30 %s",
+					movida.HoraInicio, movida.HoraFin, movida.Estado, tt.req.HoraInicio, tt.estado)
+			}
+			if want := []string{domain.EventReserveRescheduled}; !reflect.DeepEqual(repo.eventos, want) {
+				t.Fatalf("eventos = %v, se esperaba %v", repo.eventos, want)
+			}
+		})
+	}
+}
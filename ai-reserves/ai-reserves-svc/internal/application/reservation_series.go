@@ -0,0 +1,236 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/platform/ical"
+)
+
+// Tope de ocurrencias por serie (dos años de una reserva semanal)
+const maxOcurrenciasSerie = 104
+
+// esConflictoReserva indica si el error significa que el turno no está disponible
+// (y por lo tanto se informa como conflicto de la ocurrencia en lugar de cortar todo)
+func esConflictoReserva(err error) bool {
+	return errors.Is(err, domain.ErrSlotNotFound) ||
+		errors.Is(err, domain.ErrSlotAlreadyBooked) ||
+		errors.Is(err, domain.ErrAgendaNotFound) ||
+		errors.Is(err, domain.ErrAnticipacionMinima) ||
+		errors.Is(err, domain.ErrHorizonteReserva) ||
+		errors.Is(err, domain.ErrMaxReservasDia) ||
+		errors.Is(err, domain.ErrMaxReservasSemana) ||
+		errors.Is(err, domain.ErrBufferReserva)
+}
+
+// reglaSerie arma la regla de repetición desde la RRULE o desde los campos sueltos
+func reglaSerie(req domain.ReservaSerie) (ical.RRule, error) {
+	var regla ical.RRule
+
+	if req.RRule != "" {
+		var err error
+		if regla, err = ical.ParseRRule(req.RRule); err != nil {
+			return ical.RRule{}, err
+		}
+	} else {
+		regla = ical.RRule{
+			Freq:     ical.FreqWeekly,
+			Interval: req.Intervalo,
+			Count:    req.Cantidad,
+			Until:    req.Hasta,
+		}
+		if regla.Interval <= 0 {
+			regla.Interval = 1
+		}
+	}
+
+	if regla.Freq != ical.FreqWeekly {
+		return ical.RRule{}, fmt.Errorf("las series de reservas solo admiten FREQ=WEEKLY")
+	}
+	if regla.Count == 0 && regla.Until.IsZero() {
+		return ical.RRule{}, fmt.Errorf("la serie necesita una cantidad de ocurrencias (COUNT) o una fecha de fin (UNTIL)")
+	}
+	if !regla.Until.IsZero() {
+		regla.Until = truncarFecha(regla.Until)
+	}
+
+	return regla, nil
+}
+
+func (hs *AiReservesService) CreateSerieAPI(ctx context.Context, req domain.ReservaSerie) (domain.ResultadoSerie, error) {
+
+	if req.FechaInicio.IsZero() || req.Reserva.HoraInicio == "" {
+		return domain.ResultadoSerie{}, fmt.Errorf("FechaInicio y HoraInicio son obligatorias")
+	}
+	if req.Reserva.IDProfesional == 0 && req.Reserva.IDConfEstablecimiento == 0 {
+		return domain.ResultadoSerie{}, fmt.Errorf("indicar IDProfesional o IDConfEstablecimiento")
+	}
+
+	// 1) Fechas de la serie
+	regla, err := reglaSerie(req)
+	if err != nil {
+		return domain.ResultadoSerie{}, err
+	}
+	req.RRule = regla.String()
+	req.FechaInicio = truncarFecha(req.FechaInicio)
+
+	fechas := regla.Occurrences(req.FechaInicio, time.Time{}, maxOcurrenciasSerie+1)
+	if len(fechas) > maxOcurrenciasSerie {
+		return domain.ResultadoSerie{}, fmt.Errorf("la serie supera el máximo de %d ocurrencias", maxOcurrenciasSerie)
+	}
+
+	resultado := domain.ResultadoSerie{RRule: req.RRule}
+
+	// 2) Resolver la agenda de cada ocurrencia
+	type ocurrencia struct {
+		reserva     domain.Reserva
+		multiagenda *domain.ConfigPersonaFull
+	}
+	preparadas := make([]*ocurrencia, len(fechas))
+
+	for i, fecha := range fechas {
+		resultado.Ocurrencias = append(resultado.Ocurrencias, domain.OcurrenciaSerie{Fecha: fecha})
+
+		r := req.Reserva
+		r.IDAgenda = 0
+		r.Fecha = fecha
+		r.TokenRetencion = ""
+
+		r, multiagenda, err := hs.prepararReserva(ctx, r)
+		if esConflictoReserva(err) {
+			resultado.Ocurrencias[i].Conflicto = err.Error()
+			resultado.Conflictos++
+			continue
+		}
+		if err != nil {
+			return domain.ResultadoSerie{}, err
+		}
+		preparadas[i] = &ocurrencia{reserva: r, multiagenda: multiagenda}
+	}
+
+	// La serie guarda el sub tipo; si no vino, se toma el que resolvió el establecimiento
+	for _, oc := range preparadas {
+		if req.Reserva.IDSubTipoUnidadReserva == 0 && oc != nil {
+			req.Reserva.IDSubTipoUnidadReserva = oc.reserva.IDSubTipoUnidadReserva
+		}
+	}
+	if req.Reserva.IDSubTipoUnidadReserva == 0 {
+		return domain.ResultadoSerie{}, fmt.Errorf("IDSubTipoUnidadReserva es obligatorio para una serie de reservas")
+	}
+
+	// 3) Crear la serie y todas sus reservas: si alguna ocurrencia choca no se crea nada
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		idSerie, err := hs.hr.CreateSerie(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		for i, oc := range preparadas {
+			if oc == nil {
+				continue
+			}
+			oc.reserva.IDSerie = idSerie
+
+			idReserva, err := hs.reservar(ctx, tx, oc.reserva, oc.multiagenda)
+			if esConflictoReserva(err) {
+				resultado.Ocurrencias[i].Conflicto = err.Error()
+				resultado.Conflictos++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			resultado.Ocurrencias[i].IDReserva = idReserva
+		}
+
+		if resultado.Conflictos > 0 {
+			return fmt.Errorf("%w: %d de %d ocurrencias no están disponibles",
+				domain.ErrSerieConConflictos, resultado.Conflictos, len(fechas))
+		}
+
+		resultado.IDSerie = idSerie
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrSerieConConflictos) {
+			// Nada quedó creado: el informe solo lleva los conflictos
+			for i := range resultado.Ocurrencias {
+				resultado.Ocurrencias[i].IDReserva = 0
+			}
+			return resultado, err
+		}
+		return domain.ResultadoSerie{}, err
+	}
+
+	return resultado, nil
+}
+
+// CancelSerieAPI cancela una ocurrencia de la serie o, con Resto, todas las vigentes desde Fecha.
+// Las ocurrencias que ya no cumplen la anticipación mínima se informan y quedan como están.
+func (hs *AiReservesService) CancelSerieAPI(ctx context.Context, req domain.SerieCancel) (domain.ResultadoSerie, error) {
+
+	desde := truncarFecha(req.Fecha)
+	hasta := desde
+	if req.Resto {
+		if req.Fecha.IsZero() {
+			desde = truncarFecha(time.Now())
+		}
+		hasta = time.Time{}
+	} else if req.Fecha.IsZero() {
+		return domain.ResultadoSerie{}, fmt.Errorf("indicar la Fecha de la ocurrencia a cancelar o Resto")
+	}
+
+	resultado := domain.ResultadoSerie{IDSerie: req.IDSerie}
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		reservas, err := hs.hr.GetReservasSerie(ctx, tx, req.IDSerie, desde, hasta)
+		if err != nil {
+			return err
+		}
+
+		if !req.Resto && len(reservas) == 0 {
+			return fmt.Errorf("%w: serie %d fecha %s", domain.ErrOcurrenciaNotFound, req.IDSerie, desde.Format("2006-01-02"))
+		}
+
+		for _, r := range reservas {
+			cancel := domain.ReservaCancel{
+				IDReserva:    r.ID,
+				Status:       domain.ReservaCancelada,
+				CanceladoPor: req.CanceladoPor,
+				Motivo:       req.Motivo,
+			}
+			oc := domain.OcurrenciaSerie{Fecha: r.Fecha, IDReserva: r.ID}
+
+			reserva, err := hs.cancelar(ctx, tx, cancel)
+			if req.Resto && errors.Is(err, domain.ErrCancelacionFueraDePlazo) {
+				oc.Conflicto = err.Error()
+				resultado.Conflictos++
+				resultado.Ocurrencias = append(resultado.Ocurrencias, oc)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			resultado.Ocurrencias = append(resultado.Ocurrencias, oc)
+
+			if _, err := hs.ofrecerReservaCancelada(ctx, tx, reserva); err != nil {
+				return err
+			}
+		}
+
+		if req.Resto {
+			return hs.hr.UpdEstadoSerie(ctx, tx, req.IDSerie, domain.SerieCancelada)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.ResultadoSerie{}, err
+	}
+
+	return resultado, nil
+}
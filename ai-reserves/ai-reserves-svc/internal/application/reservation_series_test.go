@@ -0,0 +1,46 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+func TestReglaSerie(t *testing.T) {
+	hasta := time.Date(2026, 6, 30, 18, 45, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		req    domain.ReservaSerie
+		want   string // RRULE resultante, si no se espera error
+		hayErr bool
+	}{
+		{"RRULE semanal", domain.ReservaSerie{RRule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=8"}, "FREQ=WEEKLY;COUNT=8;BYDAY=MO,WE", false},
+		{"campos sueltos", domain.ReservaSerie{Intervalo: 2, Cantidad: 5}, "FREQ=WEEKLY;INTERVAL=2;COUNT=5", false},
+		{"intervalo por defecto", domain.ReservaSerie{Cantidad: 3}, "FREQ=WEEKLY;COUNT=3", false},
+		{"hasta se trunca al día", domain.ReservaSerie{Hasta: hasta}, "FREQ=WEEKLY;UNTIL=20260630T000000Z", false},
+		{"RRULE no semanal", domain.ReservaSerie{RRule: "FREQ=DAILY;COUNT=5"}, "", true},
+		{"RRULE sin fin", domain.ReservaSerie{RRule: "FREQ=WEEKLY"}, "", true},
+		{"campos sueltos sin fin", domain.ReservaSerie{Intervalo: 1}, "", true},
+		{"RRULE inválida", domain.ReservaSerie{RRule: "FREQ=WEEKLY;COUNT=cero"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regla, err := reglaSerie(tt.req)
+			if tt.hayErr {
+				if err == nil {
+					t.Fatalf("reglaSerie = %s, se esperaba error", regla)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reglaSerie: %v", err)
+			}
+			if got := regla.String(); got != tt.want {
+				t.Fatalf("reglaSerie = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Retención de slots durante el checkout: el flujo web retiene el slot al elegirlo y confirma
+// la reserva con el token; si el checkout se abandona, el barrido libera la retención al vencer.
+
+// Retención por defecto si la configuración no define SLOT_HOLD_MINUTES, y máximo que se puede pedir
+const (
+	holdSlotDefault     = 10 * time.Minute
+	maxMinutosRetencion = 60
+)
+
+func (hs *AiReservesService) HoldSlotAPI(ctx context.Context, req domain.RetencionSlot) (domain.RetencionSlot, error) {
+
+	if req.HoraInicio == "" {
+		return domain.RetencionSlot{}, fmt.Errorf("HoraInicio es obligatoria")
+	}
+
+	if req.Minutos == 0 {
+		hold := hs.conf.HoldSlot
+		if hold <= 0 {
+			hold = holdSlotDefault
+		}
+		req.Minutos = int(hold.Minutes())
+	}
+	if req.Minutos < 0 || req.Minutos > maxMinutosRetencion {
+		return domain.RetencionSlot{}, fmt.Errorf("Minutos debe estar entre 1 y %d", maxMinutosRetencion)
+	}
+
+	// 1) Resolver la agenda igual que al reservar
+	reserva, multiagenda, err := hs.prepararReserva(ctx, domain.Reserva{
+		IDAgenda:              req.IDAgenda,
+		IDProfesional:         req.IDProfesional,
+		IDConfEstablecimiento: req.IDConfEstablecimiento,
+		Fecha:                 req.Fecha,
+		HoraInicio:            req.HoraInicio,
+	})
+	if err != nil {
+		return domain.RetencionSlot{}, err
+	}
+	if multiagenda != nil {
+		return domain.RetencionSlot{}, fmt.Errorf("el profesional %d usa MULTIAGENDA: las retenciones solo aplican a slots pregenerados", req.IDProfesional)
+	}
+	req.IDAgenda = reserva.IDAgenda
+
+	// 2) Retener el slot con un token nuevo
+	req.Token = uuid.New().String()
+
+	var retencion domain.RetencionSlot
+	err = hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		retencion, err = hs.hr.HoldSlot(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return domain.RetencionSlot{}, err
+	}
+
+	return retencion, nil
+}
+
+func (hs *AiReservesService) ReleaseSlotHoldAPI(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("token de retención obligatorio")
+	}
+	return hs.hr.ReleaseSlotHold(ctx, token)
+}
+
+// ReleaseExpiredSlotHoldsAPI libera las retenciones de checkout vencidas. La llama el worker de retenciones.
+func (hs *AiReservesService) ReleaseExpiredSlotHoldsAPI(ctx context.Context) error {
+	liberadas, err := hs.hr.ReleaseExpiredSlotHolds(ctx)
+	if err != nil {
+		return err
+	}
+
+	if liberadas > 0 {
+		fmt.Printf("🔓 %d retenciones de slot vencidas liberadas\n", liberadas)
+	}
+	return nil
+}
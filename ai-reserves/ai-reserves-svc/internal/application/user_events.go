@@ -0,0 +1,135 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// Ciclo de vida de usuarios de auth-security: mantiene ai_res.personas alineada con los cambios de
+// contacto, revocaciones y eliminaciones. Revocar o eliminar corta el acceso (feeds de calendario,
+// lista de espera y reservas nuevas); eliminar además aplica USER_DELETED_POLICY.
+
+// actorAuthSecurity identifica en historial y eventos los cambios que origina auth-security
+const actorAuthSecurity = "auth_security"
+
+func (hs *AiReservesService) politicaBajaUsuario() string {
+	if hs.conf.PoliticaBajaUsuario == domain.PoliticaBajaCancelar {
+		return domain.PoliticaBajaCancelar
+	}
+	return domain.PoliticaBajaAnonimizar
+}
+
+func (hs *AiReservesService) UpdContactoPersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonUpdatedPayload) error {
+
+	if req.ID == 0 {
+		return fmt.Errorf("ID de persona es obligatorio")
+	}
+
+	return hs.procesarEvento(ctx, evt, func(tx *sql.Tx) error {
+		return hs.hr.UpdContactoPersona(ctx, tx, req)
+	})
+}
+
+func (hs *AiReservesService) RevokePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonBajaPayload) (domain.ResultadoBajaPersona, error) {
+	return hs.bajaPersona(ctx, evt, req, false)
+}
+
+func (hs *AiReservesService) DeletePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonBajaPayload) (domain.ResultadoBajaPersona, error) {
+	return hs.bajaPersona(ctx, evt, req, true)
+}
+
+// bajaPersona aplica la revocación o eliminación en la transacción que registra el evento.
+// Además es idempotente: una baja repetida no encuentra nada más que cancelar.
+func (hs *AiReservesService) bajaPersona(ctx context.Context, evt domain.Event, req domain.PersonBajaPayload, eliminada bool) (domain.ResultadoBajaPersona, error) {
+
+	if req.ID == 0 {
+		return domain.ResultadoBajaPersona{}, fmt.Errorf("ID de persona es obligatorio")
+	}
+
+	resultado := domain.ResultadoBajaPersona{IDPersona: req.ID}
+
+	err := hs.procesarEvento(ctx, evt, func(tx *sql.Tx) error {
+		resultado = domain.ResultadoBajaPersona{IDPersona: req.ID}
+
+		// 1) Marcar la baja: desde acá la persona no puede tomar reservas nuevas
+		existe, err := hs.hr.BajaPersona(ctx, tx, req.ID, eliminada)
+		if err != nil || !existe {
+			return err
+		}
+
+		// 2) Cortar el acceso: feeds de calendario y lista de espera (las ofertas pasan al siguiente)
+		if resultado.FeedsRevocados, err = hs.hr.RevokeFeedsPersona(ctx, tx, req.ID); err != nil {
+			return err
+		}
+
+		lista, err := hs.hr.GetListaEsperaPersona(ctx, tx, req.ID)
+		if err != nil {
+			return err
+		}
+		for _, le := range lista {
+			if err := hs.salirListaEspera(ctx, tx, le); err != nil {
+				return err
+			}
+		}
+		resultado.ListasEsperaDejadas = len(lista)
+
+		if !eliminada {
+			return nil
+		}
+
+		// 3) Eliminación: anonimizar o cancelar sus reservas futuras
+		if hs.politicaBajaUsuario() == domain.PoliticaBajaAnonimizar {
+			resultado.Anonimizada = true
+			return hs.hr.AnonimizarPersona(ctx, tx, req.ID)
+		}
+
+		resultado.ReservasCanceladas, err = hs.cancelarReservasFuturas(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return domain.ResultadoBajaPersona{}, err
+	}
+
+	return resultado, nil
+}
+
+// cancelarReservasFuturas cancela las reservas vigentes que todavía no empezaron, sin aplicar la
+// anticipación mínima de cancelación (la baja no la pide el paciente), y ofrece los turnos liberados
+func (hs *AiReservesService) cancelarReservasFuturas(ctx context.Context, tx *sql.Tx, req domain.PersonBajaPayload) (int, error) {
+	ids, err := hs.hr.GetReservasFuturasPaciente(ctx, tx, req.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	motivo := "usuario eliminado"
+	if req.Motivo != "" {
+		motivo = strings.Join([]string{motivo, req.Motivo}, ": ")
+	}
+
+	for _, id := range ids {
+		reserva, err := hs.hr.GetReservaForUpdate(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		err = hs.anularReserva(ctx, tx, reserva, domain.ReservaCancel{
+			IDReserva:    reserva.ID,
+			Status:       domain.ReservaCancelada,
+			CanceladoPor: actorAuthSecurity,
+			Motivo:       motivo,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := hs.ofrecerReservaCancelada(ctx, tx, reserva); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
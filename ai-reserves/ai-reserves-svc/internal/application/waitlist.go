@@ -0,0 +1,386 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+)
+
+// Lista de espera: cuando se libera un turno se ofrece a la primera persona anotada que lo acepta
+// (sub tipo, profesional/establecimiento y ventana). El turno queda retenido hasta que responde
+// o vence la oferta; en ese caso pasa a la siguiente persona.
+
+// Retención por defecto si la configuración no define WAITLIST_HOLD_MINUTES
+const holdListaEsperaDefault = 30 * time.Minute
+
+// Ofertas vencidas que se procesan por pasada del worker
+const loteOfertasVencidas = 50
+
+func (hs *AiReservesService) holdListaEspera() time.Duration {
+	if hs.conf.HoldListaEspera > 0 {
+		return hs.conf.HoldListaEspera
+	}
+	return holdListaEsperaDefault
+}
+
+func (hs *AiReservesService) JoinListaEsperaAPI(ctx context.Context, req domain.ListaEspera) (int, error) {
+
+	if req.IDPersona == 0 || req.IDSubTipoUnidadReserva == 0 {
+		return 0, fmt.Errorf("IDPersona e IDSubTipoUnidadReserva son obligatorios")
+	}
+	if (req.IDProfesional != 0 || req.IDConfPersonal != 0) && req.IDConfEstablecimiento != 0 {
+		return 0, fmt.Errorf("indicar IDProfesional o IDConfEstablecimiento (solo uno)")
+	}
+	if err := validarRangoAgenda(req.FechaDesde, req.FechaHasta); err != nil {
+		return 0, err
+	}
+	for _, h := range []string{req.HoraDesde, req.HoraHasta} {
+		if _, err := time.Parse("15:04", h); h != "" && err != nil {
+			return 0, fmt.Errorf("hora inválida '%s', se espera HH:mm", h)
+		}
+	}
+	if req.HoraDesde != "" && req.HoraHasta != "" && req.HoraHasta <= req.HoraDesde {
+		return 0, fmt.Errorf("HoraHasta debe ser posterior a HoraDesde")
+	}
+
+	if req.IDConfPersonal == 0 && req.IDProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return 0, err
+		}
+		req.IDConfPersonal = conf.ID
+	}
+
+	req.FechaDesde = truncarFecha(req.FechaDesde)
+	req.FechaHasta = truncarFecha(req.FechaHasta)
+
+	return hs.hr.CreateListaEspera(ctx, req)
+}
+
+// LeaveListaEsperaAPI saca a la persona de la lista; si tenía una oferta pendiente el turno pasa al siguiente.
+// Solo la persona anotada puede salir de su entrada.
+func (hs *AiReservesService) LeaveListaEsperaAPI(ctx context.Context, idListaEspera int, idPersona int) error {
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		le, err := hs.hr.GetListaEsperaForUpdate(ctx, tx, idListaEspera)
+		if err != nil {
+			return err
+		}
+
+		// Otra persona no distingue una entrada ajena de una inexistente
+		if le.IDPersona != idPersona {
+			return fmt.Errorf("%w: id=%d persona=%d", domain.ErrListaEsperaNotFound, idListaEspera, idPersona)
+		}
+		return hs.salirListaEspera(ctx, tx, le)
+	})
+}
+
+// salirListaEspera cancela la entrada (ya bloqueada); si tenía una oferta pendiente el turno pasa al siguiente
+func (hs *AiReservesService) salirListaEspera(ctx context.Context, tx *sql.Tx, le domain.ListaEspera) error {
+	switch le.Estado {
+	case domain.ListaEsperaEsperando:
+	case domain.ListaEsperaOfrecido:
+		oferta, err := hs.hr.GetOfertaPendiente(ctx, tx, le.ID)
+		if err != nil && !errors.Is(err, domain.ErrOfertaNotFound) {
+			return err
+		}
+		if err == nil {
+			if _, err = hs.soltarOferta(ctx, tx, oferta, domain.OfertaRechazada); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("la entrada %d de la lista de espera ya está %s", le.ID, le.Estado)
+	}
+
+	return hs.hr.UpdEstadoListaEspera(ctx, tx, le.ID, domain.ListaEsperaCancelado)
+}
+
+// GetListaEsperaAPI lista las entradas de la persona, con los filtros opcionales de turno y estado
+func (hs *AiReservesService) GetListaEsperaAPI(ctx context.Context, req domain.ListaEsperaFiltro) ([]domain.ListaEspera, error) {
+
+	if req.IDPersona == 0 {
+		return nil, fmt.Errorf("IDPersona es obligatorio")
+	}
+
+	if req.IDConfPersonal == 0 && req.IDProfesional != 0 {
+		conf, err := hs.hr.GetConfigPersonaFull(ctx, req.IDProfesional)
+		if err != nil {
+			return nil, err
+		}
+		req.IDConfPersonal = conf.ID
+	}
+
+	return hs.hr.GetListaEspera(ctx, req)
+}
+
+// AcceptOfertaAPI convierte la oferta vigente en una reserva a nombre de la persona de la lista
+func (hs *AiReservesService) AcceptOfertaAPI(ctx context.Context, req domain.OfertaRespuesta) (int, error) {
+	var idReserva int
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// 1) Oferta bloqueada, de la persona y todavía vigente
+		oferta, err := hs.ofertaVigente(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		// 2) Dar por aceptada la oferta: deja de retener el turno para esta misma transacción
+		if err := hs.hr.UpdEstadoOferta(ctx, tx, oferta.ID, domain.OfertaAceptada); err != nil {
+			return err
+		}
+
+		reserva := domain.Reserva{
+			IDAgenda:               oferta.Turno.IDAgenda,
+			Fecha:                  oferta.Turno.Fecha,
+			HoraInicio:             oferta.Turno.HoraInicio,
+			IDSubTipoUnidadReserva: oferta.Turno.IDSubTipoUnidadReserva,
+			IDPaciente:             &oferta.IDPersona,
+		}
+
+		// 3) Reservar: el slot retenido vuelve a LIBRE y se ocupa en la misma transacción;
+		// en MULTIAGENDA se reserva el intervalo calculado
+		if oferta.Turno.IDSlot != 0 {
+			if err := hs.hr.LiberarSlotRetenido(ctx, tx, oferta.Turno.IDSlot); err != nil {
+				return err
+			}
+			idReserva, err = hs.reservar(ctx, tx, reserva, nil)
+		} else {
+			conf, errConf := hs.hr.GetConfigPersonaFull(ctx, oferta.Turno.IDProfesional)
+			if errConf != nil {
+				return errConf
+			}
+			idReserva, err = hs.reservar(ctx, tx, reserva, &conf)
+		}
+		if err != nil {
+			return err
+		}
+
+		return hs.hr.UpdEstadoListaEspera(ctx, tx, oferta.IDListaEspera, domain.ListaEsperaAsignado)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("✅ Oferta %d aceptada: reserva %d\n", req.IDOferta, idReserva)
+	return idReserva, nil
+}
+
+// DeclineOfertaAPI rechaza la oferta: la persona sigue en la lista y el turno pasa al siguiente
+func (hs *AiReservesService) DeclineOfertaAPI(ctx context.Context, req domain.OfertaRespuesta) error {
+	return hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		oferta, err := hs.ofertaVigente(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+
+		_, err = hs.soltarOferta(ctx, tx, oferta, domain.OfertaRechazada)
+		return err
+	})
+}
+
+// ProcessOfertasVencidasAPI vence las ofertas sin respuesta y ofrece cada turno a la siguiente persona.
+// La llama periódicamente el worker de la lista de espera.
+func (hs *AiReservesService) ProcessOfertasVencidasAPI(ctx context.Context) error {
+	var vencidas, reofrecidas int
+
+	err := hs.hr.WithTransaction(ctx, func(tx *sql.Tx) error {
+		ofertas, err := hs.hr.GetOfertasVencidas(ctx, tx, loteOfertasVencidas)
+		if err != nil {
+			return err
+		}
+		vencidas, reofrecidas = len(ofertas), 0
+
+		for _, oferta := range ofertas {
+			ofrecida, err := hs.soltarOferta(ctx, tx, oferta, domain.OfertaVencida)
+			if err != nil {
+				return err
+			}
+			if ofrecida != nil {
+				reofrecidas++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if vencidas > 0 {
+		fmt.Printf("⌛ %d ofertas de lista de espera vencidas, %d turnos reofrecidos\n", vencidas, reofrecidas)
+	}
+
+	return nil
+}
+
+// ofertaVigente bloquea la oferta y verifica que sea de la persona y siga pendiente
+func (hs *AiReservesService) ofertaVigente(ctx context.Context, tx *sql.Tx, req domain.OfertaRespuesta) (domain.OfertaListaEspera, error) {
+	oferta, err := hs.hr.GetOfertaForUpdate(ctx, tx, req.IDOferta)
+	if err != nil {
+		return domain.OfertaListaEspera{}, err
+	}
+
+	// Otra persona no distingue una oferta ajena de una inexistente
+	if req.IDPersona != oferta.IDPersona {
+		return domain.OfertaListaEspera{}, fmt.Errorf("%w: id=%d persona=%d", domain.ErrOfertaNotFound, req.IDOferta, req.IDPersona)
+	}
+
+	if oferta.Estado != domain.OfertaPendiente {
+		return domain.OfertaListaEspera{}, fmt.Errorf("%w: oferta %d está %s", domain.ErrOfertaNoVigente, oferta.ID, oferta.Estado)
+	}
+	if time.Now().After(oferta.VenceEn) {
+		return domain.OfertaListaEspera{}, fmt.Errorf("%w: oferta %d venció el %s",
+			domain.ErrOfertaNoVigente, oferta.ID, oferta.VenceEn.Format("2006-01-02 15:04"))
+	}
+
+	return oferta, nil
+}
+
+// soltarOferta cierra la oferta (rechazada o vencida), devuelve a la persona a la espera,
+// libera el turno retenido y lo ofrece a la siguiente persona
+func (hs *AiReservesService) soltarOferta(ctx context.Context, tx *sql.Tx, oferta domain.OfertaListaEspera, estado string) (*domain.SlotOfrecidoPayload, error) {
+	if err := hs.hr.UpdEstadoOferta(ctx, tx, oferta.ID, estado); err != nil {
+		return nil, err
+	}
+
+	if err := hs.hr.UpdEstadoListaEspera(ctx, tx, oferta.IDListaEspera, domain.ListaEsperaEsperando); err != nil {
+		return nil, err
+	}
+
+	if oferta.Turno.IDSlot != 0 {
+		if err := hs.hr.LiberarSlotRetenido(ctx, tx, oferta.Turno.IDSlot); err != nil {
+			return nil, err
+		}
+	}
+
+	return hs.ofrecerTurno(ctx, tx, oferta.Turno)
+}
+
+// ofrecerReservaCancelada ofrece a la lista de espera el turno que libera una reserva cancelada
+func (hs *AiReservesService) ofrecerReservaCancelada(ctx context.Context, tx *sql.Tx, reserva domain.Reserva) (*domain.SlotOfrecidoPayload, error) {
+	turno, err := hs.hr.GetTurnoReserva(ctx, tx, reserva)
+	if err != nil {
+		return nil, err
+	}
+	return hs.ofrecerTurno(ctx, tx, turno)
+}
+
+// ofrecerTurno retiene el turno para la primera persona de la lista que lo acepta y encola el aviso.
+// Devuelve nil si no hay a quién ofrecerlo (o el turno ya no se puede ofrecer).
+func (hs *AiReservesService) ofrecerTurno(ctx context.Context, tx *sql.Tx, turno domain.TurnoLiberado) (*domain.SlotOfrecidoPayload, error) {
+
+	inicio, err := inicioReserva(domain.Reserva{Fecha: turno.Fecha, HoraInicio: turno.HoraInicio, ZonaHoraria: turno.ZonaHoraria})
+	if err != nil {
+		return nil, err
+	}
+	if !inicio.After(time.Now()) {
+		return nil, nil
+	}
+
+	// En MULTIAGENDA el intervalo puede haber quedado pisado (p. ej. una reserva movida unos minutos)
+	if turno.IDSlot == 0 {
+		libre, err := hs.intervaloLibre(ctx, tx, turno)
+		if err != nil || !libre {
+			return nil, err
+		}
+	}
+
+	// 1) Primera persona en espera que acepta este turno
+	le, err := hs.hr.NextListaEspera(ctx, tx, turno)
+	if errors.Is(err, domain.ErrListaEsperaNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 2) Retener el turno (los slots pasan a RETENIDO; en MULTIAGENDA la oferta pendiente lo ocupa)
+	venceEn := time.Now().Add(hs.holdListaEspera())
+	if turno.IDSlot != 0 {
+		retenido, err := hs.hr.RetenerSlot(ctx, tx, turno.IDSlot, venceEn)
+		if err != nil {
+			return nil, err
+		}
+		if !retenido {
+			return nil, nil
+		}
+	}
+
+	// 3) Registrar la oferta
+	oferta := domain.OfertaListaEspera{
+		IDListaEspera: le.ID,
+		IDPersona:     le.IDPersona,
+		Turno:         turno,
+		Estado:        domain.OfertaPendiente,
+		VenceEn:       venceEn,
+	}
+	oferta.ID, err = hs.hr.CreateOferta(ctx, tx, oferta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := hs.hr.UpdEstadoListaEspera(ctx, tx, le.ID, domain.ListaEsperaOfrecido); err != nil {
+		return nil, err
+	}
+
+	ofrecida := domain.SlotOfrecidoPayload{
+		IDOferta:               oferta.ID,
+		IDListaEspera:          le.ID,
+		IDPersona:              le.IDPersona,
+		IDAgenda:               turno.IDAgenda,
+		IDSlot:                 turno.IDSlot,
+		IDSubTipoUnidadReserva: turno.IDSubTipoUnidadReserva,
+		Fecha:                  turno.Fecha.Format("2006-01-02"),
+		HoraInicio:             turno.HoraInicio,
+		HoraFin:                turno.HoraFin,
+		VenceEn:                venceEn.Format(time.RFC3339),
+	}
+	if err := hs.encolarEvento(ctx, tx, domain.EventSlotOffered, ofrecida); err != nil {
+		return nil, err
+	}
+
+	return &ofrecida, nil
+}
+
+// intervaloLibre indica si el turno de una agenda MULTIAGENDA no se superpone con reservas ni ofertas vigentes
+func (hs *AiReservesService) intervaloLibre(ctx context.Context, tx *sql.Tx, turno domain.TurnoLiberado) (bool, error) {
+	reservas, err := hs.hr.GetReservasAgenda(ctx, tx, turno.IDAgenda)
+	if err != nil {
+		return false, err
+	}
+	ofertas, err := hs.hr.GetOfertasVigentesAgenda(ctx, tx, turno.IDAgenda)
+	if err != nil {
+		return false, err
+	}
+
+	ocupados, err := ocupacion(append(reservas, reservasOfertadas(ofertas)...), nil, 0)
+	if err != nil {
+		return false, err
+	}
+
+	i, err := intervaloReserva(domain.Reserva{Fecha: turno.Fecha, HoraInicio: turno.HoraInicio, HoraFin: turno.HoraFin,
+		ZonaHoraria: turno.ZonaHoraria})
+	if err != nil {
+		return false, err
+	}
+
+	return !superponeAlguno(i, ocupados), nil
+}
+
+// reservasOfertadas presenta las ofertas pendientes como reservas para calcular lo ocupado en MULTIAGENDA
+func reservasOfertadas(ofertas []domain.OfertaListaEspera) []domain.Reserva {
+	reservas := make([]domain.Reserva, 0, len(ofertas))
+	for _, o := range ofertas {
+		reservas = append(reservas, domain.Reserva{
+			IDAgenda:    o.Turno.IDAgenda,
+			Fecha:       o.Turno.Fecha,
+			HoraInicio:  o.Turno.HoraInicio,
+			HoraFin:     o.Turno.HoraFin,
+			ZonaHoraria: o.Turno.ZonaHoraria,
+		})
+	}
+	return reservas
+}
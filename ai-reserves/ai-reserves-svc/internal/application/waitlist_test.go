@@ -0,0 +1,122 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
+	"github.com/FrancoRebollo/ai-reserves-svc/internal/ports"
+)
+
+// repoListaEspera atiende solo lo que leen y escriben la salida y la consulta de la lista de espera
+type repoListaEspera struct {
+	ports.AiReservesRepository
+	entrada  domain.ListaEspera
+	oferta   domain.OfertaListaEspera
+	filtro   domain.ListaEsperaFiltro
+	estados  []string // estados por los que pasó la entrada
+	ofertas  []string // estados por los que pasó la oferta
+	liberado int      // slot retenido que se liberó
+}
+
+func (r *repoListaEspera) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (r *repoListaEspera) GetListaEsperaForUpdate(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.ListaEspera, error) {
+	return r.entrada, nil
+}
+
+func (r *repoListaEspera) GetOfertaPendiente(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.OfertaListaEspera, error) {
+	return r.oferta, nil
+}
+
+func (r *repoListaEspera) UpdEstadoOferta(ctx context.Context, tx *sql.Tx, idOferta int, estado string) error {
+	r.ofertas = append(r.ofertas, estado)
+	return nil
+}
+
+func (r *repoListaEspera) UpdEstadoListaEspera(ctx context.Context, tx *sql.Tx, idListaEspera int, estado string) error {
+	r.estados = append(r.estados, estado)
+	return nil
+}
+
+func (r *repoListaEspera) LiberarSlotRetenido(ctx context.Context, tx *sql.Tx, idSlot int) error {
+	r.liberado = idSlot
+	return nil
+}
+
+func (r *repoListaEspera) GetListaEspera(ctx context.Context, req domain.ListaEsperaFiltro) ([]domain.ListaEspera, error) {
+	r.filtro = req
+	return nil, nil
+}
+
+func TestLeaveListaEsperaAPI(t *testing.T) {
+	// Turno ya pasado: al soltarlo no se vuelve a ofrecer y la prueba no depende de la lista
+	pasado := domain.TurnoLiberado{IDSlot: 31, Fecha: time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), HoraInicio: "09:00"}
+
+	tests := []struct {
+		name     string
+		estado   string
+		persona  int
+		err      error
+		estados  []string
+		ofertas  []string
+		liberado int
+	}{
+		{"en espera sale de la lista", domain.ListaEsperaEsperando, 42, nil,
+			[]string{domain.ListaEsperaCancelado}, nil, 0},
+		{"con oferta pendiente la rechaza y libera el turno", domain.ListaEsperaOfrecido, 42, nil,
+			[]string{domain.ListaEsperaEsperando, domain.ListaEsperaCancelado}, []string{domain.OfertaRechazada}, 31},
+		{"entrada de otra persona", domain.ListaEsperaEsperando, 7, domain.ErrListaEsperaNotFound, nil, nil, 0},
+		{"oferta de otra persona no se suelta", domain.ListaEsperaOfrecido, 7, domain.ErrListaEsperaNotFound, nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &repoListaEspera{
+				entrada: domain.ListaEspera{ID: 5, IDPersona: 42, Estado: tt.estado},
+				oferta:  domain.OfertaListaEspera{ID: 9, IDListaEspera: 5, IDPersona: 42, Turno: pasado},
+			}
+			hs := &AiReservesService{hr: repo}
+
+			err := hs.LeaveListaEsperaAPI(context.Background(), 5, tt.persona)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("LeaveListaEsperaAPI = %v, se esperaba %v", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatalf("LeaveListaEsperaAPI: %v", err)
+			}
+			if !reflect.DeepEqual(repo.estados, tt.estados) {
+				t.Errorf("estados de la entrada = %v, se esperaba %v", repo.estados, tt.estados)
+			}
+			if !reflect.DeepEqual(repo.ofertas, tt.ofertas) {
+				t.Errorf("estados de la oferta = %v, se esperaba %v", repo.ofertas, tt.ofertas)
+			}
+			if repo.liberado != tt.liberado {
+				t.Errorf("slot liberado = %d, se esperaba %d", repo.liberado, tt.liberado)
+			}
+		})
+	}
+}
+
+func TestGetListaEsperaAPISoloDeLaPersona(t *testing.T) {
+	repo := &repoListaEspera{}
+	hs := &AiReservesService{hr: repo}
+
+	if _, err := hs.GetListaEsperaAPI(context.Background(), domain.ListaEsperaFiltro{IDPersona: 42, Estado: domain.ListaEsperaEsperando}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.filtro.IDPersona != 42 {
+		t.Fatalf("GetListaEspera filtró por persona %d, se esperaba 42", repo.filtro.IDPersona)
+	}
+
+	if _, err := hs.GetListaEsperaAPI(context.Background(), domain.ListaEsperaFiltro{}); err == nil {
+		t.Fatalf("GetListaEsperaAPI sin persona, se esperaba error")
+	}
+}
@@ -2,6 +2,29 @@ package domain
 
 import "time"
 
+// Estados posibles de un agenda_slot
+const (
+	SlotLibre     = "LIBRE"
+	SlotOcupado   = "OCUPADO"
+	SlotBloqueado = "BLOQUEADO"
+	SlotRetenido  = "RETENIDO"
+)
+
+// Modos de agenda de conf_personal.modo_agenda
+const (
+	ModoAgendaPregenerada = "PREGENERADA"
+	ModoAgendaMultiagenda = "MULTIAGENDA"
+)
+
+// Estados posibles de una reserva
+const (
+	ReservaPendiente  = "PENDIENTE"
+	ReservaConfirmada = "CONFIRMADA"
+	ReservaCancelada  = "CANCELADA"
+	ReservaFinalizada = "FINALIZADA"
+	ReservaAusente    = "AUSENTE"
+)
+
 type ExternalAPIRequest struct {
 	Method string
 	URL    string
@@ -61,6 +84,7 @@ type ConfigPersonaFull struct {
 	Domingo        bool
 	GeneraFeriados bool
 	ModoAgenda     string
+	ZonaHoraria    string // zona IANA en la que se interpretan HoraInicio y HoraFin
 }
 
 type UnidadReserva struct {
@@ -88,22 +112,152 @@ type SubTipoUnidadReserva struct {
 }
 
 type ReservaCancel struct {
-	IDReserva int
-	Status    string
+	IDReserva    int
+	Status       string
+	CanceladoPor string
+	Motivo       string
+}
+
+// Routing keys de los eventos que publica ai-reserves
+const (
+	EventOrigin             = "ai-reserves"
+	EventReserveCreated     = "reserve.created"
+	EventReserveConfirmed   = "reserve.confirmed"
+	EventReserveCanceled    = "reserve.cancelled"
+	EventSlotOffered        = "reserve.slot_offered"
+	EventReserveRescheduled = "reserve.rescheduled"
+	EventReserveReminder    = "reserve.reminder"
+)
+
+// Estados de un evento en el outbox
+const (
+	OutboxPendiente  = "PENDING"
+	OutboxProcesando = "PROCESSING"
+	OutboxEnviado    = "SENT"
+	OutboxFallido    = "FAILED"
+)
+
+// OutboxEvent es un evento grabado en el outbox a la espera de que el relay lo publique
+type OutboxEvent struct {
+	ID       int64
+	Event    Event
+	Intentos int
+}
+
+// ReservaPayload es el payload de los eventos reserve.created y reserve.confirmed
+type ReservaPayload struct {
+	IDReserva              int    `json:"id_reserva"`
+	IDAgenda               int    `json:"id_agenda"`
+	IDPaciente             *int   `json:"id_paciente"`
+	IDSubTipoUnidadReserva int    `json:"id_sub_tipo_unidad_reserva"`
+	IDSerie                int    `json:"id_serie,omitempty"`
+	Estado                 string `json:"estado"`
+	Fecha                  string `json:"fecha"`
+	HoraInicio             string `json:"hora_inicio"`
+	HoraFin                string `json:"hora_fin"`
+	ZonaHoraria            string `json:"zona_horaria"`
+	Inicio                 string `json:"inicio"`
+	Actor                  string `json:"actor"`
+}
+
+// ReservaCanceladaPayload es el payload del evento reserve.cancelled
+type ReservaCanceladaPayload struct {
+	IDReserva              int    `json:"id_reserva"`
+	IDAgenda               int    `json:"id_agenda"`
+	IDPaciente             *int   `json:"id_paciente"`
+	IDSubTipoUnidadReserva int    `json:"id_sub_tipo_unidad_reserva"`
+	Fecha                  string `json:"fecha"`
+	HoraInicio             string `json:"hora_inicio"`
+	HoraFin                string `json:"hora_fin"`
+	CanceladoPor           string `json:"cancelado_por"`
+	Motivo                 string `json:"motivo"`
 }
 
 type SearchReserve struct {
-	IDProfesional   int
-	IDUnidadReserva int
-	FechaDesde      time.Time
-	FechaHasta      time.Time
+	IDProfesional           int
+	IDUnidadReserva         int
+	IDSubTipoUnidadReserva  int
+	IDConfEstablecimiento   int
+	FechaDesde              time.Time
+	FechaHasta              time.Time
+	HoraDesde               string // HH:mm, opcional
+	HoraHasta               string // HH:mm, opcional
+	DiasSemana              []int  // 0 = domingo ... 6 = sábado, opcional
+	PrimeroDisponible       bool   // solo el primer slot libre de cada día
+	IDPaciente              int    // opcional: descarta días y semanas en los que ya llegó al tope de reservas
+	RecursosProfesional     []int  // opcional: otros profesionales que tienen que estar libres en el mismo horario
+	RecursosEstablecimiento []int  // opcional: espacios que tienen que estar libres en el mismo horario
+	Pagina                  int
+	TamanioPagina           int
+}
+
+type SlotDisponible struct {
+	IDSlot                int
+	IDAgenda              int
+	IDConfPersonal        int
+	IDProfesional         int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	HoraInicio            string
+	HoraFin               string
+	ZonaHoraria           string
+	Inicio                time.Time // instante de inicio expresado en la zona de la agenda
+	Fin                   time.Time
+	Capacidad             int // lugares del slot (1 = turno exclusivo)
+	Cupos                 int // lugares que quedan libres
+}
+
+type DisponibilidadDia struct {
+	Fecha time.Time
+	Slots []SlotDisponible
+}
+
+type ResultadoBusqueda struct {
+	Dias          []DisponibilidadDia
+	Pagina        int
+	TamanioPagina int
+	TotalSlots    int
 }
 
 type Agenda struct {
-	IDProfesional   int
-	IDUnidadReserva int
-	FechaDesde      time.Time
-	FechaHasta      time.Time
+	IDProfesional          int
+	IDUnidadReserva        int
+	IDSubTipoUnidadReserva int
+	IDConfEstablecimiento  int
+	FechaDesde             time.Time
+	FechaHasta             time.Time
+}
+
+// AgendaDia es una agenda diaria a materializar junto con sus slots
+type AgendaDia struct {
+	IDConfPersonal        int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	ZonaHoraria           string
+	Capacidad             int // lugares de cada slot, según el sub tipo con el que se generan
+	Slots                 []AgendaSlot
+}
+
+// AgendaSlot guarda la hora de reloj local del slot y sus instantes (que en los días de cambio
+// de horario no siempre distan lo mismo que las horas de reloj)
+type AgendaSlot struct {
+	ID         int
+	IDAgenda   int
+	HoraInicio string
+	HoraFin    string
+	Inicio     time.Time
+	Fin        time.Time
+	Estado     string
+	IDReserva  *int
+}
+
+// AgendaResumen informa lo creado y lo omitido (ya existente) al inicializar agendas
+type AgendaResumen struct {
+	DiasCreados   int
+	DiasOmitidos  int
+	SlotsCreados  int
+	SlotsOmitidos int
+	DiasFeriado   int
 }
 
 type GetReservaPersona struct {
@@ -124,6 +278,42 @@ type PersonCreatedPayload struct {
 	TePersona string
 }
 
+// Routing keys del ciclo de vida de usuarios que publica auth-security
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserRevoked = "user.revoked"
+	EventUserDeleted = "user.deleted"
+)
+
+// PersonUpdatedPayload es el payload de user.updated: los datos de contacto vacíos no cambian
+type PersonUpdatedPayload struct {
+	ID        int
+	Email     string
+	TePersona string
+}
+
+// PersonBajaPayload es el payload de user.revoked y user.deleted
+type PersonBajaPayload struct {
+	ID     int
+	Motivo string
+}
+
+// Qué hacer con una persona eliminada en auth-security (USER_DELETED_POLICY)
+const (
+	PoliticaBajaAnonimizar = "ANONIMIZAR" // borra sus datos personales; las reservas quedan como historial
+	PoliticaBajaCancelar   = "CANCELAR"   // cancela sus reservas futuras; los datos quedan para el historial
+)
+
+// ResultadoBajaPersona resume lo que se hizo al revocar o eliminar una persona
+type ResultadoBajaPersona struct {
+	IDPersona           int
+	FeedsRevocados      int
+	ListasEsperaDejadas int
+	ReservasCanceladas  int
+	Anonimizada         bool
+}
+
 type UpdAtributeUnidadReserva struct {
 	IDUnidadReserva int
 	Atribute        string
@@ -167,15 +357,52 @@ type UpdSubTipoUnidadReserva struct {
 }
 
 type Reserva struct {
-	ID                     int
-	IDAgenda               int
-	Fecha                  time.Time
-	HoraInicio             string
-	HoraFin                string
-	IDPaciente             *int
-	Estado                 string
-	Observaciones          *string
-	IDSubTipoUnidadReserva int
+	ID                      int
+	IDAgenda                int
+	Fecha                   time.Time
+	HoraInicio              string
+	HoraFin                 string
+	IDPaciente              *int
+	Estado                  string
+	Observaciones           *string
+	IDSubTipoUnidadReserva  int
+	IDConfEstablecimiento   int
+	IDProfesional           int
+	IDSerie                 int
+	TokenRetencion          string    // token de una retención de slot (checkout) a confirmar
+	ZonaHoraria             string    // zona de la agenda: Fecha y horas son de reloj en esta zona
+	Inicio                  time.Time // instante de inicio expresado en ZonaHoraria
+	Fin                     time.Time
+	RecursosProfesional     []int // otros profesionales (id_persona) que la reserva ocupa además de su agenda
+	RecursosEstablecimiento []int // espacios (conf_establecimiento: salas, sillones) que ocupa además de su agenda
+}
+
+// RecursoReserva es un profesional o un espacio adicional que la reserva ocupa en el mismo horario.
+// Sus slots se toman junto con el de la reserva y se registran en agendas_reservas.
+type RecursoReserva struct {
+	IDProfesional         int
+	IDConfEstablecimiento int
+}
+
+// Recursos junta los recursos adicionales de la reserva
+func (r Reserva) Recursos() []RecursoReserva {
+	return recursosAdicionales(r.RecursosProfesional, r.RecursosEstablecimiento)
+}
+
+// Recursos junta los recursos adicionales que tienen que estar libres en la búsqueda
+func (s SearchReserve) Recursos() []RecursoReserva {
+	return recursosAdicionales(s.RecursosProfesional, s.RecursosEstablecimiento)
+}
+
+func recursosAdicionales(profesionales, establecimientos []int) []RecursoReserva {
+	recursos := make([]RecursoReserva, 0, len(profesionales)+len(establecimientos))
+	for _, id := range profesionales {
+		recursos = append(recursos, RecursoReserva{IDProfesional: id})
+	}
+	for _, id := range establecimientos {
+		recursos = append(recursos, RecursoReserva{IDConfEstablecimiento: id})
+	}
+	return recursos
 }
 
 type ConfigPersonalSubTipo struct {
@@ -184,6 +411,35 @@ type ConfigPersonalSubTipo struct {
 	DuracionReservaMinutos int
 }
 
+// ReglasReserva son las reglas efectivas de un sub tipo para un profesional: las del sub tipo
+// con el override del profesional aplicado. En todas 0 significa sin límite.
+type ReglasReserva struct {
+	IDSubTipoUnidadReserva    int
+	IDConfPersonal            int
+	AnticipacionMinimaMinutos int // minutos mínimos entre el momento de reservar y el turno
+	HorizonteDias             int // días hacia adelante que se pueden reservar
+	MaxReservasDia            int // reservas vigentes por paciente por día
+	MaxReservasSemana         int // reservas vigentes por paciente por semana (lunes a domingo)
+	BufferMinutos             int // minutos libres entre turnos consecutivos del profesional o espacio
+}
+
+// ReglasReservaConfig configura las reglas de un sub tipo o, con IDProfesional, el override de ese
+// profesional. En el sub tipo un campo nil no se modifica; en el override nil vuelve a usar la del sub tipo.
+type ReglasReservaConfig struct {
+	IDSubTipoUnidadReserva    int
+	IDProfesional             int
+	AnticipacionMinimaMinutos *int
+	HorizonteDias             *int
+	MaxReservasDia            *int
+	MaxReservasSemana         *int
+	BufferMinutos             *int
+}
+
+type ReglasReservaFiltro struct {
+	IDSubTipoUnidadReserva int
+	IDProfesional          int
+}
+
 type ConfEstablecimiento struct {
 	ID                     int
 	IDPersona              int
@@ -199,6 +455,7 @@ type ConfEstablecimiento struct {
 	Sabado                 bool
 	Domingo                bool
 	GeneraFeriados         bool
+	ZonaHoraria            string
 }
 
 type ConfigEstablecimiento struct {
@@ -207,3 +464,330 @@ type ConfigEstablecimiento struct {
 	Atribute               string
 	Value                  string
 }
+
+// Orígenes de un feriado
+const (
+	FeriadoOrigenManual = "MANUAL"
+	FeriadoOrigenICal   = "ICAL"
+	FeriadoOrigenCSV    = "CSV"
+)
+
+// Feriado es un día sin atención: nacional (IDConfEstablecimiento nil)
+// o un cierre propio de un establecimiento
+type Feriado struct {
+	ID                    int
+	Fecha                 time.Time
+	Descripcion           string
+	IDConfEstablecimiento *int
+	Origen                string
+}
+
+type FeriadoFiltro struct {
+	IDConfEstablecimiento int
+	FechaDesde            time.Time
+	FechaHasta            time.Time
+}
+
+// FeriadoImport es un archivo iCalendar o CSV (fecha,descripcion) con feriados a importar
+type FeriadoImport struct {
+	IDConfEstablecimiento int
+	Formato               string
+	Contenido             string
+}
+
+type FeriadoImportResumen struct {
+	Creados  int
+	Omitidos int
+}
+
+// AgendaBloqueo es un rango horario sin atención (vacaciones, licencia, mantenimiento)
+// de un profesional o de un establecimiento
+type AgendaBloqueo struct {
+	ID                    int
+	IDProfesional         int
+	IDConfPersonal        int
+	IDConfEstablecimiento int
+	Inicio                time.Time
+	Fin                   time.Time
+	Motivo                string
+	CreatedBy             string
+	UID                   string    // UID del VEVENT si el bloqueo se importó de un .ics
+	Ocurrencia            time.Time // ocurrencia de la serie UID que representa
+}
+
+type BloqueoFiltro struct {
+	IDProfesional         int
+	IDConfPersonal        int
+	IDConfEstablecimiento int
+	FechaDesde            time.Time
+	FechaHasta            time.Time
+}
+
+// BloqueoImport es el calendario personal (.ics) de un profesional: sus eventos ocupados
+// se convierten en bloqueos de agenda
+type BloqueoImport struct {
+	IDProfesional  int
+	IDConfPersonal int
+	Contenido      string
+	CreatedBy      string
+}
+
+// BloqueoImportResumen cuenta los bloqueos tocados por la importación y las reservas vigentes
+// que quedaron dentro de los bloqueos nuevos
+type BloqueoImportResumen struct {
+	Creados      int
+	Actualizados int
+	Eliminados   int
+	SinCambios   int
+	Colisiones   []Reserva
+}
+
+// BloqueoResultado informa los slots bloqueados y las reservas vigentes que quedaron
+// dentro del bloqueo y hay que reprogramar
+type BloqueoResultado struct {
+	IDBloqueo       int
+	SlotsBloqueados int
+	Colisiones      []Reserva
+}
+
+// Estados de una serie de reservas
+const (
+	SerieActiva    = "ACTIVA"
+	SerieCancelada = "CANCELADA"
+)
+
+// ReservaSerie es una reserva que se repite (semanal o cada N semanas).
+// Reserva lleva los datos comunes de cada ocurrencia (profesional o establecimiento,
+// sub tipo, paciente, hora); la fecha de la primera ocurrencia es FechaInicio.
+// La repetición llega como RRULE ("FREQ=WEEKLY;INTERVAL=2;COUNT=10") o con los campos sueltos.
+type ReservaSerie struct {
+	ID          int
+	Reserva     Reserva
+	FechaInicio time.Time
+	RRule       string
+	Intervalo   int
+	Cantidad    int
+	Hasta       time.Time
+	CreatedBy   string
+}
+
+// OcurrenciaSerie es una fecha de la serie: la reserva creada o el motivo por el que no se pudo
+type OcurrenciaSerie struct {
+	Fecha     time.Time
+	IDReserva int
+	Conflicto string
+}
+
+type ResultadoSerie struct {
+	IDSerie     int
+	RRule       string
+	Ocurrencias []OcurrenciaSerie
+	Conflictos  int
+}
+
+// SerieCancel cancela una ocurrencia (Fecha) o el resto de la serie desde Fecha (Resto)
+type SerieCancel struct {
+	IDSerie      int
+	Fecha        time.Time
+	Resto        bool
+	CanceladoPor string
+	Motivo       string
+}
+
+// Estados de una entrada de lista de espera
+const (
+	ListaEsperaEsperando = "ESPERANDO"
+	ListaEsperaOfrecido  = "OFRECIDO"
+	ListaEsperaAsignado  = "ASIGNADO"
+	ListaEsperaCancelado = "CANCELADO"
+)
+
+// Estados de una oferta de turno a la lista de espera
+const (
+	OfertaPendiente = "PENDIENTE"
+	OfertaAceptada  = "ACEPTADA"
+	OfertaRechazada = "RECHAZADA"
+	OfertaVencida   = "VENCIDA"
+)
+
+// ListaEspera es el pedido de una persona de ser avisada cuando se libere un turno
+// del sub tipo (y opcionalmente del profesional o establecimiento) dentro de su ventana
+type ListaEspera struct {
+	ID                     int
+	IDPersona              int
+	IDProfesional          int
+	IDConfPersonal         int
+	IDConfEstablecimiento  int
+	IDSubTipoUnidadReserva int
+	FechaDesde             time.Time
+	FechaHasta             time.Time
+	HoraDesde              string // HH:mm, opcional
+	HoraHasta              string // HH:mm, opcional
+	Estado                 string
+	CreatedAt              time.Time
+}
+
+type ListaEsperaFiltro struct {
+	IDPersona              int
+	IDProfesional          int
+	IDConfPersonal         int
+	IDConfEstablecimiento  int
+	IDSubTipoUnidadReserva int
+	Estado                 string
+}
+
+// TurnoLiberado es un turno que quedó libre (por cancelación o por una oferta vencida).
+// IDSlot es 0 en agendas MULTIAGENDA.
+type TurnoLiberado struct {
+	IDAgenda               int
+	IDSlot                 int
+	IDProfesional          int
+	IDConfPersonal         int
+	IDConfEstablecimiento  int
+	IDSubTipoUnidadReserva int
+	Fecha                  time.Time
+	HoraInicio             string
+	HoraFin                string
+	ZonaHoraria            string
+}
+
+// OfertaListaEspera retiene un turno liberado para una persona de la lista de espera hasta VenceEn
+type OfertaListaEspera struct {
+	ID            int
+	IDListaEspera int
+	IDPersona     int
+	Turno         TurnoLiberado
+	Estado        string
+	VenceEn       time.Time
+}
+
+type OfertaRespuesta struct {
+	IDOferta  int
+	IDPersona int
+}
+
+type SlotOfrecidoPayload struct {
+	IDOferta               int    `json:"id_oferta"`
+	IDListaEspera          int    `json:"id_lista_espera"`
+	IDPersona              int    `json:"id_persona"`
+	IDAgenda               int    `json:"id_agenda"`
+	IDSlot                 int    `json:"id_slot,omitempty"`
+	IDSubTipoUnidadReserva int    `json:"id_sub_tipo_unidad_reserva"`
+	Fecha                  string `json:"fecha"`
+	HoraInicio             string `json:"hora_inicio"`
+	HoraFin                string `json:"hora_fin"`
+	VenceEn                string `json:"vence_en"`
+}
+
+// RetencionSlot retiene un slot libre durante el checkout. La agenda se resuelve igual que en una
+// reserva (IDAgenda, o IDProfesional / IDConfEstablecimiento + Fecha); Token y RetenidoHasta
+// se completan al retener.
+type RetencionSlot struct {
+	IDAgenda              int
+	IDProfesional         int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	HoraInicio            string
+	Minutos               int
+	IDSlot                int
+	Token                 string
+	RetenidoHasta         time.Time
+}
+
+// ReservaReprogramacion mueve una reserva a otro turno. El destino se indica como en una reserva
+// nueva (IDAgenda, o IDProfesional / IDConfEstablecimiento + Fecha); si no se indica agenda
+// ni entidad se usa la misma entidad de la reserva original.
+type ReservaReprogramacion struct {
+	IDReserva             int
+	IDAgenda              int
+	IDProfesional         int
+	IDConfEstablecimiento int
+	Fecha                 time.Time
+	HoraInicio            string
+	TokenRetencion        string
+	Actor                 string
+	Motivo                string
+}
+
+type ReservaReprogramadaPayload struct {
+	IDReserva              int    `json:"id_reserva"`
+	IDPaciente             *int   `json:"id_paciente"`
+	IDSubTipoUnidadReserva int    `json:"id_sub_tipo_unidad_reserva"`
+	IDAgendaAnterior       int    `json:"id_agenda_anterior"`
+	FechaAnterior          string `json:"fecha_anterior"`
+	HoraInicioAnterior     string `json:"hora_inicio_anterior"`
+	HoraFinAnterior        string `json:"hora_fin_anterior"`
+	IDAgenda               int    `json:"id_agenda"`
+	Fecha                  string `json:"fecha"`
+	HoraInicio             string `json:"hora_inicio"`
+	HoraFin                string `json:"hora_fin"`
+	ReprogramadoPor        string `json:"reprogramado_por"`
+	Motivo                 string `json:"motivo"`
+}
+
+// RecordatoriosConfig define las horas antes del turno en que se recuerdan las reservas
+// confirmadas del sub tipo; vacío desactiva los recordatorios
+type RecordatoriosConfig struct {
+	IDSubTipoUnidadReserva int
+	HorasAntes             []int
+}
+
+// RecordatorioReserva es una reserva confirmada a la que le toca un recordatorio.
+// Si vencieron varios a la vez (p. ej. el servicio estuvo caído) HorasAntes es el más próximo al turno.
+type RecordatorioReserva struct {
+	Reserva    Reserva
+	HorasAntes int
+}
+
+// ReservaRecordatorioPayload es el payload del evento reserve.reminder
+type ReservaRecordatorioPayload struct {
+	IDReserva              int    `json:"id_reserva"`
+	IDPaciente             *int   `json:"id_paciente"`
+	IDAgenda               int    `json:"id_agenda"`
+	IDProfesional          int    `json:"id_profesional,omitempty"`
+	IDConfEstablecimiento  int    `json:"id_conf_establecimiento,omitempty"`
+	IDSubTipoUnidadReserva int    `json:"id_sub_tipo_unidad_reserva"`
+	Fecha                  string `json:"fecha"`
+	HoraInicio             string `json:"hora_inicio"`
+	HoraFin                string `json:"hora_fin"`
+	ZonaHoraria            string `json:"zona_horaria"`
+	Inicio                 string `json:"inicio"`
+	HorasAntes             int    `json:"horas_antes"`
+}
+
+// Roles de una persona frente a sus reservas en la exportación de calendario
+const (
+	CalendarioRolPaciente    = "PACIENTE"
+	CalendarioRolProfesional = "PROFESIONAL"
+)
+
+// CalendarioFiltro elige las reservas a exportar: las de la persona como paciente o las de
+// su agenda (profesional o establecimiento)
+type CalendarioFiltro struct {
+	IDPersona  int
+	Rol        string
+	FechaDesde time.Time
+	FechaHasta time.Time
+}
+
+// ReservaCalendario es una reserva con los datos necesarios para armar su VEVENT
+type ReservaCalendario struct {
+	Reserva         Reserva
+	SubTipo         string
+	Paciente        string
+	Profesional     string
+	Establecimiento string
+	Secuencia       int // cambios registrados en el historial (SEQUENCE del VEVENT)
+	UpdatedAt       time.Time
+}
+
+// FeedCalendario es una URL de suscripción de solo lectura identificada por su token
+type FeedCalendario struct {
+	ID        int
+	Token     string
+	IDPersona int
+	Rol       string
+	URL       string
+	CreatedBy string
+	CreatedAt time.Time
+}
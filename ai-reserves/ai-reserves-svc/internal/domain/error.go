@@ -45,3 +45,54 @@ func (e *HealthcheckError) Error() string {
 }
 
 var ErrDuplicateEvent = errors.New("duplicate event ignored")
+
+// ErrEventoInvalido marca un evento recibido que no se puede procesar nunca (payload mal formado):
+// el consumer lo manda directo a la dead-letter queue, sin reintentos
+var ErrEventoInvalido = errors.New("invalid event payload")
+
+// ErrBrokerDesconectado lo devuelve el adapter de mensajería mientras reconecta con el broker:
+// el evento no se publicó y hay que reintentarlo más tarde
+var ErrBrokerDesconectado = errors.New("message broker disconnected")
+
+// ErrEventoNoRuteado lo devuelve el adapter de mensajería cuando el broker devolvió el evento
+// publicado porque ninguna cola estaba atada a su routing key
+var ErrEventoNoRuteado = errors.New("event could not be routed to any queue")
+
+// Errores de reserva: el handler los traduce a 404 / 409 / 422
+var (
+	ErrAgendaNotFound    = errors.New("agenda not found")
+	ErrSlotNotFound      = errors.New("agenda slot not found")
+	ErrSlotAlreadyBooked = errors.New("agenda slot already booked")
+
+	ErrReservaNotFound         = errors.New("reserva not found")
+	ErrReservaEstadoInvalido   = errors.New("invalid reserva state")
+	ErrCancelacionFueraDePlazo = errors.New("cancellation notice period not met")
+
+	ErrFeriadoNotFound = errors.New("feriado not found")
+	ErrBloqueoNotFound = errors.New("agenda bloqueo not found")
+
+	ErrSerieNotFound      = errors.New("reserva serie not found")
+	ErrSerieConConflictos = errors.New("reserva serie has conflicting occurrences")
+	ErrOcurrenciaNotFound = errors.New("reserva serie occurrence not found")
+
+	ErrListaEsperaNotFound = errors.New("lista espera entry not found")
+	ErrOfertaNotFound      = errors.New("lista espera offer not found")
+	ErrOfertaNoVigente     = errors.New("lista espera offer is no longer valid")
+
+	ErrSlotRetenido      = errors.New("slot is held by another booking in progress")
+	ErrRetencionInvalida = errors.New("slot hold token is invalid or expired")
+
+	ErrFeedNotFound = errors.New("calendar feed not found")
+
+	ErrAnticipacionMinima = errors.New("booking lead time not met")
+	ErrHorizonteReserva   = errors.New("booking beyond the allowed horizon")
+	ErrMaxReservasDia     = errors.New("daily booking limit reached")
+	ErrMaxReservasSemana  = errors.New("weekly booking limit reached")
+	ErrBufferReserva      = errors.New("booking too close to another appointment")
+
+	ErrPersonaDadaDeBaja = errors.New("persona was revoked or deleted")
+)
+
+// ErrConflictoConcurrente marca una transacción que la base abortó por deadlock o por conflicto de
+// serialización con otra: no se grabó nada y el mismo pedido se puede reintentar
+var ErrConflictoConcurrente = errors.New("concurrent update conflict, retry the request")
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// transicionesReserva define, para cada estado, a qué estados puede pasar una reserva.
+// CANCELADA, FINALIZADA y AUSENTE son estados finales.
+var transicionesReserva = map[string][]string{
+	ReservaPendiente:  {ReservaConfirmada, ReservaCancelada},
+	ReservaConfirmada: {ReservaFinalizada, ReservaAusente, ReservaCancelada},
+}
+
+// estadosDesdeInicio solo se alcanzan una vez que empezó el turno: no se puede finalizar ni marcar
+// ausente una reserva de la semana que viene
+var estadosDesdeInicio = map[string]bool{
+	ReservaFinalizada: true,
+	ReservaAusente:    true,
+}
+
+// ValidarTransicion controla que la reserva que empieza en inicio pueda pasar del estado desde al
+// estado hacia en el instante ahora. Los instantes se comparan en UTC.
+func ValidarTransicion(desde, hacia string, inicio, ahora time.Time) error {
+	permitida := false
+	for _, permitido := range transicionesReserva[desde] {
+		if permitido == hacia {
+			permitida = true
+			break
+		}
+	}
+	if !permitida {
+		return fmt.Errorf("%w: no se puede pasar de %s a %s", ErrReservaEstadoInvalido, desde, hacia)
+	}
+
+	if estadosDesdeInicio[hacia] && inicio.UTC().After(ahora.UTC()) {
+		return fmt.Errorf("%w: la reserva empieza el %s UTC, recién entonces puede pasar a %s",
+			ErrReservaEstadoInvalido, inicio.UTC().Format("2006-01-02 15:04"), hacia)
+	}
+	return nil
+}
+
+// ReservaTransicion es el pedido de cambio de estado de una reserva
+type ReservaTransicion struct {
+	IDReserva int
+	Estado    string
+	Actor     string
+	Motivo    string
+}
+
+// ReservaHistorial es un cambio de estado registrado en reservas_historial
+type ReservaHistorial struct {
+	ID             int
+	IDReserva      int
+	EstadoAnterior *string
+	EstadoNuevo    string
+	Actor          string
+	Motivo         *string
+	CreatedAt      time.Time
+}
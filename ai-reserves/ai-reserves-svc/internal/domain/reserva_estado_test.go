@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidarTransicion(t *testing.T) {
+	ahora := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	buenosAires := time.FixedZone("ART", -3*3600)
+
+	tests := []struct {
+		name   string
+		desde  string
+		hacia  string
+		inicio time.Time
+		valida bool
+	}{
+		{"pendiente a confirmada", ReservaPendiente, ReservaConfirmada, ahora.Add(24 * time.Hour), true},
+		{"pendiente a cancelada", ReservaPendiente, ReservaCancelada, ahora.Add(24 * time.Hour), true},
+		{"pendiente a finalizada", ReservaPendiente, ReservaFinalizada, ahora.Add(-time.Hour), false},
+		{"pendiente a ausente", ReservaPendiente, ReservaAusente, ahora.Add(-time.Hour), false},
+		{"confirmada a cancelada", ReservaConfirmada, ReservaCancelada, ahora.Add(24 * time.Hour), true},
+		{"confirmada a finalizada ya empezada", ReservaConfirmada, ReservaFinalizada, ahora.Add(-time.Hour), true},
+		{"confirmada a ausente ya empezada", ReservaConfirmada, ReservaAusente, ahora.Add(-time.Minute), true},
+		{"confirmada a finalizada justo al empezar", ReservaConfirmada, ReservaFinalizada, ahora, true},
+		{"confirmada a finalizada antes de empezar", ReservaConfirmada, ReservaFinalizada, ahora.Add(time.Minute), false},
+		{"confirmada a ausente la semana que viene", ReservaConfirmada, ReservaAusente, ahora.AddDate(0, 0, 7), false},
+		{"inicio en otra zona se compara en UTC", ReservaConfirmada, ReservaFinalizada, ahora.In(buenosAires).Add(-time.Minute), true},
+		{"inicio futuro en otra zona", ReservaConfirmada, ReservaAusente, ahora.In(buenosAires).Add(time.Minute), false},
+		{"cancelada es final", ReservaCancelada, ReservaConfirmada, ahora.Add(24 * time.Hour), false},
+		{"finalizada es final", ReservaFinalizada, ReservaAusente, ahora.Add(-time.Hour), false},
+		{"ausente es final", ReservaAusente, ReservaFinalizada, ahora.Add(-time.Hour), false},
+		{"confirmada a pendiente", ReservaConfirmada, ReservaPendiente, ahora.Add(24 * time.Hour), false},
+		{"estado desconocido", "ARCHIVADA", ReservaCancelada, ahora.Add(24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidarTransicion(tt.desde, tt.hacia, tt.inicio, ahora)
+			if tt.valida && err != nil {
+				t.Fatalf("ValidarTransicion(%s, %s) = %v, se esperaba nil", tt.desde, tt.hacia, err)
+			}
+			if !tt.valida && !errors.Is(err, ErrReservaEstadoInvalido) {
+				t.Fatalf("ValidarTransicion(%s, %s) = %v, se esperaba ErrReservaEstadoInvalido", tt.desde, tt.hacia, err)
+			}
+		})
+	}
+}
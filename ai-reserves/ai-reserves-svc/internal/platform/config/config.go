@@ -3,6 +3,8 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +32,16 @@ type (
 		Client       string
 		Version      string
 		FechaStartUp string
+		// Tiempo que un turno liberado queda retenido para la persona de la lista de espera
+		HoldListaEspera time.Duration
+		// Retención por defecto de un slot durante el checkout
+		HoldSlot time.Duration
+		// URL pública del servicio, para armar links absolutos (feeds de calendario)
+		URLPublica string
+		// Intentos de publicación de un evento del outbox antes de marcarlo FAILED
+		OutboxMaxIntentos int
+		// Qué hacer con un usuario eliminado en auth-security: ANONIMIZAR o CANCELAR
+		PoliticaBajaUsuario string
 	}
 
 	//Configuracion para conexiones a la base de datos
@@ -84,6 +96,26 @@ func loadGlobalConfiguration() (*GlobalConfiguration, error) {
 		appVer = "1.0.0"
 	}
 
+	holdListaEspera := 30 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("WAITLIST_HOLD_MINUTES")); err == nil && v > 0 {
+		holdListaEspera = time.Duration(v) * time.Minute
+	}
+
+	holdSlot := 10 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("SLOT_HOLD_MINUTES")); err == nil && v > 0 {
+		holdSlot = time.Duration(v) * time.Minute
+	}
+
+	outboxMaxIntentos := 10
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS")); err == nil && v > 0 {
+		outboxMaxIntentos = v
+	}
+
+	politicaBajaUsuario := strings.ToUpper(strings.TrimSpace(os.Getenv("USER_DELETED_POLICY")))
+	if politicaBajaUsuario == "" {
+		politicaBajaUsuario = "ANONIMIZAR"
+	}
+
 	startupTime := time.Now()
 	fecha := startupTime.Format("02/01/2006 15:04:05")
 
@@ -93,6 +125,13 @@ func loadGlobalConfiguration() (*GlobalConfiguration, error) {
 		Client:       appClient,
 		Version:      appVer,
 		FechaStartUp: fecha,
+
+		HoldListaEspera: holdListaEspera,
+		HoldSlot:        holdSlot,
+		URLPublica:      strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+
+		OutboxMaxIntentos:   outboxMaxIntentos,
+		PoliticaBajaUsuario: politicaBajaUsuario,
 	}
 
 	var dbs []*DB
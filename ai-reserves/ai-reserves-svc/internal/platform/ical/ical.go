@@ -0,0 +1,220 @@
+// Package ical lee y escribe el subconjunto de iCalendar (RFC 5545) que usa ai-reserves:
+// eventos VEVENT con UID, SUMMARY, DTSTART, DTEND, RRULE, EXDATE y RECURRENCE-ID.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event es un VEVENT del calendario
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time // ocurrencia de la serie UID que este VEVENT reemplaza
+	Transparent  bool      // TRANSP:TRANSPARENT, el evento no ocupa tiempo
+	Status       string    // TENTATIVE / CONFIRMED / CANCELLED
+	Sequence     int
+	LastModified time.Time
+}
+
+// property es una línea de contenido ya desplegada: NOMBRE;PARAM=VALOR:valor
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse lee los VEVENT de un calendario. Los eventos sin DTSTART se descartan.
+// Las horas flotantes (sin Z ni TZID) se interpretan en la hora local del servidor.
+func Parse(r io.Reader) ([]Event, error) {
+	return ParseInLocation(r, time.Local)
+}
+
+// ParseInLocation es como Parse pero interpreta las horas flotantes en loc
+func ParseInLocation(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	inCalendar := false
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %w", i+1, err)
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			inCalendar = true
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			current = &Event{}
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if current != nil && !current.Start.IsZero() {
+				if current.End.IsZero() {
+					current.End = defaultEnd(*current)
+				}
+				events = append(events, *current)
+			}
+			current = nil
+		case current != nil:
+			if err := current.set(p, loc); err != nil {
+				return nil, fmt.Errorf("línea %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if !inCalendar {
+		return nil, fmt.Errorf("el contenido no es un calendario iCalendar (falta BEGIN:VCALENDAR)")
+	}
+
+	return events, nil
+}
+
+func (e *Event) set(p property, loc *time.Location) error {
+	var err error
+
+	switch p.name {
+	case "UID":
+		e.UID = p.value
+	case "SUMMARY":
+		e.Summary = unescape(p.value)
+	case "DESCRIPTION":
+		e.Description = unescape(p.value)
+	case "LOCATION":
+		e.Location = unescape(p.value)
+	case "STATUS":
+		e.Status = strings.ToUpper(p.value)
+	case "TRANSP":
+		e.Transparent = strings.EqualFold(p.value, "TRANSPARENT")
+	case "RECURRENCE-ID":
+		e.RecurrenceID, _, err = parseTime(p, loc)
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseTime(p, loc)
+	case "DTEND":
+		e.End, _, err = parseTime(p, loc)
+	case "RRULE":
+		e.RRule = p.value
+	case "EXDATE":
+		for _, v := range strings.Split(p.value, ",") {
+			t, _, perr := parseTime(property{name: p.name, params: p.params, value: v}, loc)
+			if perr != nil {
+				return perr
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	}
+
+	return err
+}
+
+// defaultEnd aplica la regla de RFC 5545 para eventos sin DTEND: un día si es de día completo,
+// si no, el mismo instante de inicio
+func defaultEnd(e Event) time.Time {
+	if e.AllDay {
+		return e.Start.AddDate(0, 0, 1)
+	}
+	return e.Start
+}
+
+// unfold une las líneas plegadas (las que empiezan con espacio o tab continúan la anterior)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("leyendo calendario: %w", err)
+	}
+
+	return lines, nil
+}
+
+func parseProperty(line string) (property, error) {
+	sep := strings.Index(line, ":")
+	if sep < 0 {
+		return property{}, fmt.Errorf("línea sin ':' %q", line)
+	}
+
+	parts := strings.Split(line[:sep], ";")
+	p := property{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  line[sep+1:],
+	}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+
+	return p, nil
+}
+
+// parseTime interpreta DATE (día completo), DATE-TIME UTC (sufijo Z), DATE-TIME con TZID
+// o DATE-TIME flotante (hora de reloj en loc)
+func parseTime(p property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+
+	if p.params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s: fecha inválida %q", p.name, value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s: fecha-hora inválida %q", p.name, value)
+		}
+		return t, false, nil
+	}
+
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s: TZID desconocido %q", p.name, tzid)
+		}
+		loc = l
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s: fecha-hora inválida %q", p.name, value)
+	}
+	return t, false, nil
+}
+
+func unescape(v string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(v)
+}
@@ -0,0 +1,167 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func calendario(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func TestParse(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		events []Event
+	}{
+		{
+			name: "UTC",
+			input: calendario("BEGIN:VEVENT", "UID:a", "SUMMARY:Congreso", "DTSTART:20260310T120000Z",
+				"DTEND:20260310T150000Z", "END:VEVENT"),
+			events: []Event{{UID: "a", Summary: "Congreso",
+				Start: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "TZID",
+			input: calendario("BEGIN:VEVENT", "UID:b", `DTSTART;TZID="Europe/Madrid":20260310T090000`,
+				"DTEND;TZID=Europe/Madrid:20260310T093000", "END:VEVENT"),
+			events: []Event{{UID: "b",
+				Start: time.Date(2026, 3, 10, 9, 0, 0, 0, madrid), End: time.Date(2026, 3, 10, 9, 30, 0, 0, madrid)}},
+		},
+		{
+			name:  "feriado de día completo sin DTEND",
+			input: calendario("BEGIN:VEVENT", "UID:c", "SUMMARY:Revolución de Mayo", "DTSTART;VALUE=DATE:20260525", "END:VEVENT"),
+			events: []Event{{UID: "c", Summary: "Revolución de Mayo", AllDay: true,
+				Start: time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 26, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "líneas plegadas y texto escapado",
+			input: calendario("BEGIN:VEVENT", "UID:d", `SUMMARY:Carnaval\, lunes\; `, " y martes", `DESCRIPTION:uno\ndos`,
+				"DTSTART;VALUE=DATE:20260216", "DTEND;VALUE=DATE:20260218", "END:VEVENT"),
+			events: []Event{{UID: "d", Summary: "Carnaval, lunes; y martes", Description: "uno\ndos", AllDay: true,
+				Start: time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 2, 18, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name: "RRULE y EXDATE",
+			input: calendario("BEGIN:VEVENT", "UID:e", "DTSTART:20260310T120000Z", "RRULE:FREQ=WEEKLY;COUNT=4",
+				"EXDATE:20260317T120000Z,20260324T120000Z", "END:VEVENT"),
+			events: []Event{{UID: "e", RRule: "FREQ=WEEKLY;COUNT=4",
+				Start: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+				ExDates: []time.Time{time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC), time.Date(2026, 3, 24, 12, 0, 0, 0, time.UTC)}}},
+		},
+		{
+			name: "ocurrencia reemplazada de una serie",
+			input: calendario("BEGIN:VEVENT", "UID:g", "DTSTART:20260310T120000Z", "DTEND:20260310T130000Z",
+				"RRULE:FREQ=WEEKLY;COUNT=4", "STATUS:confirmed", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:g", "RECURRENCE-ID:20260317T120000Z", "DTSTART:20260317T140000Z",
+				"DTEND:20260317T150000Z", "END:VEVENT"),
+			events: []Event{
+				{UID: "g", RRule: "FREQ=WEEKLY;COUNT=4", Status: StatusConfirmed,
+					Start: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)},
+				{UID: "g", RecurrenceID: time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC),
+					Start: time.Date(2026, 3, 17, 14, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 17, 15, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "evento transparente",
+			input: calendario("BEGIN:VEVENT", "UID:h", "DTSTART:20260310T120000Z", "DTEND:20260310T130000Z", "TRANSP:TRANSPARENT", "END:VEVENT"),
+			events: []Event{{UID: "h", Transparent: true,
+				Start: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:   "evento sin DTSTART",
+			input:  calendario("BEGIN:VEVENT", "UID:f", "SUMMARY:sin fecha", "END:VEVENT"),
+			events: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(events) != len(tt.events) {
+				t.Fatalf("Parse devolvió %d eventos, se esperaba %d", len(events), len(tt.events))
+			}
+			for i, want := range tt.events {
+				if !mismoEvento(events[i], want) {
+					t.Errorf("evento %d = %+v, se esperaba %+v", i, events[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrores(t *testing.T) {
+	inputs := map[string]string{
+		"no es un calendario":  "BEGIN:VEVENT\r\nDTSTART:20260310T120000Z\r\nEND:VEVENT\r\n",
+		"línea sin dos puntos": calendario("BEGIN:VEVENT", "DTSTART 20260310T120000Z", "END:VEVENT"),
+		"fecha inválida":       calendario("BEGIN:VEVENT", "DTSTART;VALUE=DATE:2026-03-10", "END:VEVENT"),
+		"TZID desconocido":     calendario("BEGIN:VEVENT", "DTSTART;TZID=Marte/Olympus:20260310T120000", "END:VEVENT"),
+		"EXDATE inválido":      calendario("BEGIN:VEVENT", "DTSTART:20260310T120000Z", "EXDATE:ayer", "END:VEVENT"),
+	}
+
+	for name, input := range inputs {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: Parse no devolvió error", name)
+		}
+	}
+}
+
+func mismoEvento(a, b Event) bool {
+	if a.UID != b.UID || a.Summary != b.Summary || a.Description != b.Description || a.AllDay != b.AllDay ||
+		a.RRule != b.RRule || a.Transparent != b.Transparent || a.Status != b.Status ||
+		!a.Start.Equal(b.Start) || !a.End.Equal(b.End) || !a.RecurrenceID.Equal(b.RecurrenceID) ||
+		len(a.ExDates) != len(b.ExDates) {
+		return false
+	}
+	for i := range a.ExDates {
+		if !a.ExDates[i].Equal(b.ExDates[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseInLocation(t *testing.T) {
+	montevideo, err := time.LoadLocation("America/Montevideo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := calendario(
+		"BEGIN:VEVENT", "UID:flotante", "DTSTART:20260310T090000", "DTEND:20260310T100000", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:tzid", "DTSTART;TZID=Europe/Madrid:20260310T090000", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:utc", "DTSTART:20260310T090000Z", "END:VEVENT",
+	)
+	events, err := ParseInLocation(strings.NewReader(input), montevideo)
+	if err != nil {
+		t.Fatalf("ParseInLocation: %v", err)
+	}
+
+	// Montevideo es UTC-3 y Madrid UTC+1 en marzo
+	esperados := []time.Time{
+		time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+	}
+	if len(events) != len(esperados) {
+		t.Fatalf("ParseInLocation devolvió %d eventos, se esperaban %d", len(events), len(esperados))
+	}
+	for i, want := range esperados {
+		if !events[i].Start.Equal(want) {
+			t.Errorf("%s empieza %s, se esperaba %s", events[i].UID, events[i].Start.UTC(), want)
+		}
+	}
+	if !events[0].End.Equal(time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("flotante termina %s", events[0].End.UTC())
+	}
+}
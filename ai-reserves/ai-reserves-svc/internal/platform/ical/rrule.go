@@ -0,0 +1,177 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frecuencias soportadas de RRULE
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RRule es una regla de repetición: FREQ, INTERVAL, COUNT, UNTIL y BYDAY (solo con WEEKLY)
+type RRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// ParseRRule interpreta el valor de una propiedad RRULE, p. ej. "FREQ=WEEKLY;INTERVAL=2;COUNT=10"
+func ParseRRule(value string) (RRule, error) {
+	r := RRule{Interval: 1}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return RRule{}, fmt.Errorf("RRULE: parte inválida %q", part)
+		}
+
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(v)
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return RRule{}, fmt.Errorf("RRULE: INTERVAL inválido %q", v)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return RRule{}, fmt.Errorf("RRULE: COUNT inválido %q", v)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, _, err := parseTime(property{name: "UNTIL", params: map[string]string{}, value: v}, time.Local)
+			if err != nil {
+				return RRule{}, fmt.Errorf("RRULE: %w", err)
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return RRule{}, fmt.Errorf("RRULE: BYDAY %q no soportado", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		}
+	}
+
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return RRule{}, fmt.Errorf("RRULE: FREQ %q no soportada", r.Freq)
+	}
+
+	if len(r.ByDay) > 0 && r.Freq != FreqWeekly {
+		return RRule{}, fmt.Errorf("RRULE: BYDAY solo se soporta con FREQ=WEEKLY")
+	}
+
+	return r, nil
+}
+
+// String vuelve a armar la regla en formato RRULE
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		dias := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			for k, v := range weekdays {
+				if v == wd {
+					dias = append(dias, k)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(dias, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences expande la regla a partir de start (que es la primera ocurrencia).
+// Se detiene en COUNT, en UNTIL (inclusive), en hasta (exclusivo, si no es cero) o al llegar a max.
+func (r RRule) Occurrences(start time.Time, hasta time.Time, max int) []time.Time {
+	var out []time.Time
+
+	fin := func(t time.Time) bool {
+		return (!r.Until.IsZero() && t.After(r.Until)) ||
+			(!hasta.IsZero() && !t.Before(hasta)) ||
+			(r.Count > 0 && len(out) >= r.Count) ||
+			len(out) >= max
+	}
+
+	// WEEKLY con BYDAY: cada semana (de a Interval) se recorren los días pedidos desde el inicio de la semana de start
+	if r.Freq == FreqWeekly && len(r.ByDay) > 0 {
+		semana := start.AddDate(0, 0, -int(start.Weekday()))
+		for i := 0; ; i++ {
+			base := semana.AddDate(0, 0, 7*r.Interval*i)
+			for d := 0; d < 7; d++ {
+				t := base.AddDate(0, 0, d)
+				if t.Before(start) || !r.incluyeDia(t.Weekday()) {
+					continue
+				}
+				if fin(t) {
+					return out
+				}
+				out = append(out, t)
+			}
+		}
+	}
+
+	for i := 0; ; i++ {
+		var t time.Time
+		switch r.Freq {
+		case FreqDaily:
+			t = start.AddDate(0, 0, i*r.Interval)
+		case FreqWeekly:
+			t = start.AddDate(0, 0, 7*i*r.Interval)
+		case FreqMonthly:
+			t = start.AddDate(0, i*r.Interval, 0)
+		case FreqYearly:
+			t = start.AddDate(i*r.Interval, 0, 0)
+		}
+		if fin(t) {
+			return out
+		}
+		out = append(out, t)
+	}
+}
+
+func (r RRule) incluyeDia(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
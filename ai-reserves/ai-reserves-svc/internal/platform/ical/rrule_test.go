@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fechas(t *testing.T, valores ...string) []time.Time {
+	t.Helper()
+	var out []time.Time
+	for _, v := range valores {
+		f, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			t.Fatalf("fecha %q: %v", v, err)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func TestOccurrences(t *testing.T) {
+	// martes
+	inicio := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		rrule string
+		hasta string
+		max   int
+		want  []string
+	}{
+		{"semanal con COUNT", "FREQ=WEEKLY;COUNT=4", "", 100,
+			[]string{"2026-03-10", "2026-03-17", "2026-03-24", "2026-03-31"}},
+		{"cada dos semanas", "FREQ=WEEKLY;INTERVAL=2;COUNT=3", "", 100,
+			[]string{"2026-03-10", "2026-03-24", "2026-04-07"}},
+		{"UNTIL es inclusive", "FREQ=WEEKLY;UNTIL=20260324T000000Z", "", 100,
+			[]string{"2026-03-10", "2026-03-17", "2026-03-24"}},
+		{"hasta es exclusivo", "FREQ=WEEKLY;COUNT=10", "2026-03-24", 100,
+			[]string{"2026-03-10", "2026-03-17"}},
+		{"corta en max", "FREQ=WEEKLY;COUNT=10", "", 2,
+			[]string{"2026-03-10", "2026-03-17"}},
+		{"BYDAY martes y jueves", "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=5", "", 100,
+			[]string{"2026-03-10", "2026-03-12", "2026-03-17", "2026-03-19", "2026-03-24"}},
+		{"BYDAY no repite días anteriores al inicio", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", "", 100,
+			[]string{"2026-03-11", "2026-03-16", "2026-03-18"}},
+		{"BYDAY cada dos semanas", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TU;COUNT=4", "", 100,
+			[]string{"2026-03-10", "2026-03-23", "2026-03-24", "2026-04-06"}},
+		{"BYDAY con UNTIL", "FREQ=WEEKLY;BYDAY=TU,FR;UNTIL=20260320T000000Z", "", 100,
+			[]string{"2026-03-10", "2026-03-13", "2026-03-17", "2026-03-20"}},
+		{"diaria", "FREQ=DAILY;COUNT=3", "", 100,
+			[]string{"2026-03-10", "2026-03-11", "2026-03-12"}},
+		{"mensual", "FREQ=MONTHLY;COUNT=3", "", 100,
+			[]string{"2026-03-10", "2026-04-10", "2026-05-10"}},
+		{"anual", "FREQ=YEARLY;COUNT=2", "", 100,
+			[]string{"2026-03-10", "2027-03-10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rrule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rrule, err)
+			}
+			var hasta time.Time
+			if tt.hasta != "" {
+				hasta = fechas(t, tt.hasta)[0]
+			}
+
+			got := r.Occurrences(inicio, hasta, tt.max)
+			if want := fechas(t, tt.want...); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Occurrences(%q) = %v, se esperaba %v", tt.rrule, got, want)
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   string // String() de la regla, si no se espera error
+		hayErr bool
+	}{
+		{"semanal", "FREQ=WEEKLY;COUNT=10", "FREQ=WEEKLY;COUNT=10", false},
+		{"con prefijo y minúsculas", "RRULE:freq=weekly;interval=2;count=3", "FREQ=WEEKLY;INTERVAL=2;COUNT=3", false},
+		{"BYDAY", "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4", "FREQ=WEEKLY;COUNT=4;BYDAY=MO,TH", false},
+		{"UNTIL en UTC", "FREQ=WEEKLY;UNTIL=20261231T235959Z", "FREQ=WEEKLY;UNTIL=20261231T235959Z", false},
+		{"sin FREQ", "COUNT=3", "", true},
+		{"FREQ no soportada", "FREQ=HOURLY;COUNT=3", "", true},
+		{"INTERVAL cero", "FREQ=WEEKLY;INTERVAL=0", "", true},
+		{"COUNT inválido", "FREQ=WEEKLY;COUNT=x", "", true},
+		{"BYDAY desconocido", "FREQ=WEEKLY;BYDAY=XX", "", true},
+		{"BYDAY sin WEEKLY", "FREQ=DAILY;BYDAY=MO", "", true},
+		{"parte sin valor", "FREQ=WEEKLY;COUNT", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.value)
+			if tt.hayErr {
+				if err == nil {
+					t.Fatalf("ParseRRule(%q) = %v, se esperaba error", tt.value, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.value, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Fatalf("ParseRRule(%q).String() = %q, se esperaba %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Valores de STATUS de un VEVENT y de METHOD de un calendario
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+
+	MethodPublish = "PUBLISH"
+	MethodCancel  = "CANCEL"
+)
+
+// Largo máximo de una línea de contenido (en octetos, sin el CRLF)
+const maxLineOctets = 75
+
+// Calendar es un VCALENDAR a escribir. Method es opcional (PUBLISH, CANCEL, ...).
+type Calendar struct {
+	ProdID string
+	Name   string
+	Method string
+	Events []Event
+}
+
+// Write escribe uno o más VCALENDAR en formato iCalendar (CRLF y líneas plegadas a 75 octetos).
+// Las fechas-hora se escriben en UTC; los eventos de día completo como DATE.
+func Write(w io.Writer, calendars ...Calendar) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC()
+
+	for _, cal := range calendars {
+		line(bw, "BEGIN:VCALENDAR")
+		line(bw, "VERSION:2.0")
+		line(bw, "PRODID:"+cal.ProdID)
+		line(bw, "CALSCALE:GREGORIAN")
+		if cal.Method != "" {
+			line(bw, "METHOD:"+cal.Method)
+		}
+		if cal.Name != "" {
+			line(bw, "X-WR-CALNAME:"+escape(cal.Name))
+		}
+
+		for _, e := range cal.Events {
+			writeEvent(bw, e, stamp)
+		}
+
+		line(bw, "END:VCALENDAR")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("escribiendo calendario: %w", err)
+	}
+	return nil
+}
+
+func writeEvent(w *bufio.Writer, e Event, stamp time.Time) {
+	line(w, "BEGIN:VEVENT")
+	line(w, "UID:"+e.UID)
+	line(w, "DTSTAMP:"+formatUTC(stamp))
+
+	if e.AllDay {
+		line(w, "DTSTART;VALUE=DATE:"+e.Start.Format("20060102"))
+		line(w, "DTEND;VALUE=DATE:"+e.End.Format("20060102"))
+	} else {
+		line(w, "DTSTART:"+formatUTC(e.Start))
+		line(w, "DTEND:"+formatUTC(e.End))
+	}
+
+	if e.Summary != "" {
+		line(w, "SUMMARY:"+escape(e.Summary))
+	}
+	if e.Description != "" {
+		line(w, "DESCRIPTION:"+escape(e.Description))
+	}
+	if e.Location != "" {
+		line(w, "LOCATION:"+escape(e.Location))
+	}
+	if e.Status != "" {
+		line(w, "STATUS:"+e.Status)
+	}
+	line(w, "SEQUENCE:"+strconv.Itoa(e.Sequence))
+	if !e.LastModified.IsZero() {
+		line(w, "LAST-MODIFIED:"+formatUTC(e.LastModified))
+	}
+	if e.RRule != "" {
+		line(w, "RRULE:"+e.RRule)
+	}
+	for _, ex := range e.ExDates {
+		line(w, "EXDATE:"+formatUTC(ex))
+	}
+
+	line(w, "END:VEVENT")
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// line escribe una línea de contenido plegándola a 75 octetos sin cortar caracteres UTF-8
+func line(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // la continuación ya lleva el espacio inicial
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,148 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWrite(t *testing.T) {
+	art := time.FixedZone("ART", -3*3600)
+	inicio := time.Date(2026, 3, 10, 9, 30, 0, 0, art)
+
+	tests := []struct {
+		name  string
+		cal   Calendar
+		lines []string // líneas de contenido (desplegadas) que tiene que tener la salida
+	}{
+		{
+			name: "turno en UTC",
+			cal: Calendar{ProdID: "-//ai-reserves//ES", Method: MethodPublish, Events: []Event{{
+				UID: "reserva-1@ai-reserves", Summary: "Control", Start: inicio, End: inicio.Add(30 * time.Minute),
+				Status: StatusConfirmed, Sequence: 2,
+			}}},
+			lines: []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//ai-reserves//ES", "METHOD:PUBLISH",
+				"BEGIN:VEVENT", "UID:reserva-1@ai-reserves", "DTSTART:20260310T123000Z", "DTEND:20260310T130000Z",
+				"SUMMARY:Control", "STATUS:CONFIRMED", "SEQUENCE:2", "END:VEVENT", "END:VCALENDAR"},
+		},
+		{
+			name: "día completo como DATE",
+			cal: Calendar{ProdID: "p", Events: []Event{{
+				UID: "feriado", Start: time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 26, 0, 0, 0, 0, time.UTC), AllDay: true,
+			}}},
+			lines: []string{"DTSTART;VALUE=DATE:20260525", "DTEND;VALUE=DATE:20260526", "SEQUENCE:0"},
+		},
+		{
+			name: "texto escapado",
+			cal: Calendar{ProdID: "p", Name: "Agenda; Dr. Pérez", Events: []Event{{
+				UID: "x", Start: inicio, End: inicio, Summary: "Consulta, control; seguimiento",
+				Description: "línea 1\nlínea 2 \\ fin", Location: "Consultorio 3",
+			}}},
+			lines: []string{`X-WR-CALNAME:Agenda\; Dr. Pérez`, `SUMMARY:Consulta\, control\; seguimiento`,
+				`DESCRIPTION:línea 1\nlínea 2 \\ fin`, "LOCATION:Consultorio 3"},
+		},
+		{
+			name: "cancelación con serie",
+			cal: Calendar{ProdID: "p", Method: MethodCancel, Events: []Event{{
+				UID: "serie-7", Start: inicio, End: inicio.Add(time.Hour), Status: StatusCancelled,
+				RRule: "FREQ=WEEKLY;COUNT=4", ExDates: []time.Time{inicio.AddDate(0, 0, 7)},
+				LastModified: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+			}}},
+			lines: []string{"METHOD:CANCEL", "STATUS:CANCELLED", "RRULE:FREQ=WEEKLY;COUNT=4",
+				"EXDATE:20260317T123000Z", "LAST-MODIFIED:20260301T100000Z"},
+		},
+		{
+			name: "línea larga con acentos se pliega",
+			cal: Calendar{ProdID: "p", Events: []Event{{
+				UID: "largo", Start: inicio, End: inicio, Description: strings.Repeat("áéíóú ñandú ", 20),
+			}}},
+			lines: []string{"DESCRIPTION:" + strings.Repeat("áéíóú ñandú ", 20)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.cal); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			out := buf.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("la salida no termina en CRLF")
+			}
+			fisicas := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for _, l := range fisicas {
+				if len(l) > maxLineOctets {
+					t.Errorf("línea de %d octetos (máximo %d): %q", len(l), maxLineOctets, l)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("línea plegada en medio de un carácter: %q", l)
+				}
+				if strings.Contains(l, "\n") {
+					t.Errorf("línea con salto sin escapar: %q", l)
+				}
+			}
+
+			desplegadas, err := unfold(strings.NewReader(out))
+			if err != nil {
+				t.Fatalf("unfold: %v", err)
+			}
+			for _, want := range tt.lines {
+				if !contiene(desplegadas, want) {
+					t.Errorf("falta la línea %q en:\n%s", want, strings.Join(desplegadas, "\n"))
+				}
+			}
+		})
+	}
+}
+
+func TestWriteParseIdaYVuelta(t *testing.T) {
+	inicio := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	original := Event{
+		UID:         "reserva-42@ai-reserves",
+		Summary:     "Tratamiento, sesión 1; sillón 2",
+		Description: strings.Repeat("Traer estudios previos. ", 8),
+		Location:    "Av. Siempreviva 742",
+		Start:       inicio,
+		End:         inicio.Add(45 * time.Minute),
+		RRule:       "FREQ=WEEKLY;COUNT=3",
+		ExDates:     []time.Time{inicio.AddDate(0, 0, 7)},
+		Status:      StatusTentative,
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Calendar{ProdID: "p", Events: []Event{original}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	events, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Parse devolvió %d eventos, se esperaba 1", len(events))
+	}
+
+	got := events[0]
+	if got.UID != original.UID || got.Summary != original.Summary || got.Description != original.Description ||
+		got.Location != original.Location || got.RRule != original.RRule || got.Status != original.Status {
+		t.Fatalf("evento leído %+v, se esperaba %+v", got, original)
+	}
+	if !got.Start.Equal(original.Start) || !got.End.Equal(original.End) {
+		t.Fatalf("horario leído %s-%s, se esperaba %s-%s", got.Start, got.End, original.Start, original.End)
+	}
+	if len(got.ExDates) != 1 || !got.ExDates[0].Equal(original.ExDates[0]) {
+		t.Fatalf("EXDATE leído %v, se esperaba %v", got.ExDates, original.ExDates)
+	}
+}
+
+func contiene(lines []string, want string) bool {
+	for _, l := range lines {
+		if l == want {
+			return true
+		}
+	}
+	return false
+}
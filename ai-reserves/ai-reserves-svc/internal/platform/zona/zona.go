@@ -0,0 +1,93 @@
+// Package zona resuelve las zonas horarias IANA de profesionales y establecimientos.
+// Las agendas guardan fecha y hora de reloj en la zona de la entidad; los instantes se guardan en UTC.
+package zona
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "time/tzdata" // las imágenes mínimas no traen la base de zonas del sistema
+)
+
+// Default es la zona de las configuraciones que no indican una
+const Default = "America/Argentina/Buenos_Aires"
+
+var cache sync.Map
+
+// ErrHoraInexistente indica una hora de reloj que ese día no existe en la zona (salto de horario de verano)
+var ErrHoraInexistente = errors.New("local time does not exist in time zone")
+
+// Cargar devuelve la zona por nombre IANA (p. ej. "America/Montevideo"); vacío es la zona por defecto
+func Cargar(nombre string) (*time.Location, error) {
+	if nombre == "" {
+		nombre = Default
+	}
+
+	if loc, ok := cache.Load(nombre); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(nombre)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria '%s' inválida, se espera un nombre IANA como %s", nombre, Default)
+	}
+
+	cache.Store(nombre, loc)
+	return loc, nil
+}
+
+// Validar normaliza y valida el nombre de una zona recibida por API
+func Validar(nombre string) (string, error) {
+	if nombre == "" {
+		return Default, nil
+	}
+	if _, err := Cargar(nombre); err != nil {
+		return "", err
+	}
+	return nombre, nil
+}
+
+// EnFecha ubica la hora de reloj de hora en la fecha y zona indicadas. Devuelve false si esa hora
+// no existe ese día (el salto de la hora al entrar en horario de verano).
+func EnFecha(fecha time.Time, hora time.Time, loc *time.Location) (time.Time, bool) {
+	t := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), hora.Hour(), hora.Minute(), 0, 0, loc)
+	return t, t.Hour() == hora.Hour() && t.Minute() == hora.Minute()
+}
+
+// EnFechaAjustada es EnFecha para los bordes de un horario de atención: una hora que ese día no
+// existe se toma como el instante del salto, el primero que existe después de ella. Así un horario
+// que abre o cierra dentro del salto pierde solo la hora que no existe, y ningún borde se corre al
+// día anterior (en Santiago la medianoche del cambio no existe).
+func EnFechaAjustada(fecha time.Time, hora time.Time, loc *time.Location) time.Time {
+	t, existe := EnFecha(fecha, hora, loc)
+	if existe {
+		return t
+	}
+
+	// time.Date ubica la hora inexistente con el desfasaje de uno de los dos lados del salto:
+	// si quedó antes de la pedida el salto es el fin de esa zona, si quedó después es su comienzo
+	pedida := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), hora.Hour(), hora.Minute(), 0, 0, time.UTC)
+	obtenida := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	desde, hasta := t.ZoneBounds()
+	if obtenida.Before(pedida) {
+		return hasta
+	}
+	return desde
+}
+
+// Instante combina una fecha y una hora HH:mm de reloj en la zona indicada. Una hora que ese día
+// no existe (salto de horario de verano) es error: nunca se corre una hora en silencio.
+func Instante(fecha time.Time, hora string, loc *time.Location) (time.Time, error) {
+	h, err := time.Parse("15:04", hora)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("hora inválida '%s', se espera HH:mm", hora)
+	}
+	t, existe := EnFecha(fecha, h, loc)
+	if !existe {
+		return time.Time{}, fmt.Errorf("%w: %s %s en %s (cambio de horario)",
+			ErrHoraInexistente, fecha.Format("2006-01-02"), hora, loc)
+	}
+	return t, nil
+}
@@ -0,0 +1,155 @@
+package zona
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEnFecha(t *testing.T) {
+	nuevaYork, err := Cargar("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hora := func(h, m int) time.Time { return time.Date(0, 1, 1, h, m, 0, 0, time.UTC) }
+	salto := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	atraso := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		fecha  time.Time
+		hora   time.Time
+		utc    string
+		existe bool
+	}{
+		{"antes del salto", salto, hora(1, 59), "2026-03-08T06:59:00Z", true},
+		{"dentro del salto", salto, hora(2, 30), "2026-03-08T06:30:00Z", false},
+		{"después del salto", salto, hora(3, 0), "2026-03-08T07:00:00Z", true},
+		{"hora repetida toma la primera", atraso, hora(1, 30), "2026-11-01T05:30:00Z", true},
+		{"fecha con hora y zona propias", time.Date(2026, 7, 1, 23, 0, 0, 0, time.FixedZone("X", 5*3600)), hora(9, 0), "2026-07-01T13:00:00Z", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, existe := EnFecha(tt.fecha, tt.hora, nuevaYork)
+			if s := got.UTC().Format(time.RFC3339); s != tt.utc || existe != tt.existe {
+				t.Errorf("EnFecha() = %s, %v; se esperaba %s, %v", s, existe, tt.utc, tt.existe)
+			}
+		})
+	}
+}
+
+func TestEnFechaAjustada(t *testing.T) {
+	hora := func(h, m int) time.Time { return time.Date(0, 1, 1, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		zona  string
+		fecha time.Time
+		hora  time.Time
+		utc   string
+	}{
+		{"hora que existe", "America/New_York", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), hora(1, 59), "2026-03-08T06:59:00Z"},
+		{"hora del salto", "America/New_York", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), hora(2, 0), "2026-03-08T07:00:00Z"},
+		{"dentro del salto", "America/New_York", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), hora(2, 30), "2026-03-08T07:00:00Z"},
+		{"medianoche que no existe en Santiago", "America/Santiago", time.Date(2026, 9, 6, 0, 0, 0, 0, time.UTC), hora(0, 0), "2026-09-06T04:00:00Z"},
+		{"hora repetida toma la primera", "America/New_York", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), hora(1, 30), "2026-11-01T05:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := Cargar(tt.zona)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := EnFechaAjustada(tt.fecha, tt.hora, loc).UTC().Format(time.RFC3339); got != tt.utc {
+				t.Errorf("EnFechaAjustada() = %s, se esperaba %s", got, tt.utc)
+			}
+		})
+	}
+}
+
+func TestInstante(t *testing.T) {
+	dia := func(v string) time.Time {
+		f, _ := time.Parse("2006-01-02", v)
+		return f
+	}
+
+	tests := []struct {
+		name  string
+		zona  string
+		fecha string
+		hora  string
+		utc   string // instante esperado en UTC, vacío si se espera error
+		err   error
+	}{
+		{"sin horario de verano", "America/Argentina/Buenos_Aires", "2026-03-10", "09:30", "2026-03-10T12:30:00Z", nil},
+		{"horario de verano de Nueva York", "America/New_York", "2026-07-01", "09:00", "2026-07-01T13:00:00Z", nil},
+		{"antes del salto", "America/New_York", "2026-03-08", "01:59", "2026-03-08T06:59:00Z", nil},
+		{"hora del salto", "America/New_York", "2026-03-08", "02:00", "", ErrHoraInexistente},
+		{"dentro del salto", "America/New_York", "2026-03-08", "02:30", "", ErrHoraInexistente},
+		{"después del salto", "America/New_York", "2026-03-08", "03:00", "2026-03-08T07:00:00Z", nil},
+		{"medianoche que no existe en Santiago", "America/Santiago", "2026-09-06", "00:30", "", ErrHoraInexistente},
+		{"Santiago después del salto", "America/Santiago", "2026-09-06", "01:00", "2026-09-06T04:00:00Z", nil},
+		{"hora repetida toma la primera", "America/New_York", "2026-11-01", "01:30", "2026-11-01T05:30:00Z", nil},
+		{"hora mal formada", "America/New_York", "2026-03-10", "9.30", "", nil},
+		{"hora fuera de rango", "America/New_York", "2026-03-10", "25:00", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := Cargar(tt.zona)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Instante(dia(tt.fecha), tt.hora, loc)
+			if tt.utc == "" {
+				if err == nil {
+					t.Fatalf("Instante(%s %s) = %s, se esperaba error", tt.fecha, tt.hora, got)
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("Instante(%s %s) = %v, se esperaba %v", tt.fecha, tt.hora, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Instante(%s %s): %v", tt.fecha, tt.hora, err)
+			}
+			if s := got.UTC().Format(time.RFC3339); s != tt.utc {
+				t.Fatalf("Instante(%s %s) = %s, se esperaba %s", tt.fecha, tt.hora, s, tt.utc)
+			}
+		})
+	}
+}
+
+func TestCargar(t *testing.T) {
+	tests := []struct {
+		name   string
+		nombre string
+		want   string // nombre de la zona cargada, vacío si se espera error
+	}{
+		{"vacío es la zona por defecto", "", Default},
+		{"nombre IANA", "America/Montevideo", "America/Montevideo"},
+		{"UTC", "UTC", "UTC"},
+		{"nombre inválido", "Marte/Olympus", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := Cargar(tt.nombre)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Cargar(%q) = %s, se esperaba error", tt.nombre, loc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Cargar(%q): %v", tt.nombre, err)
+			}
+			if loc.String() != tt.want {
+				t.Fatalf("Cargar(%q) = %s, se esperaba %s", tt.nombre, loc, tt.want)
+			}
+		})
+	}
+}
@@ -3,18 +3,24 @@ package ports
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/FrancoRebollo/ai-reserves-svc/internal/domain"
 )
 
 type AiReservesService interface {
-	CreatePersonaAPI(ctx context.Context, req domain.PersonCreatedPayload) error
+	CreatePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonCreatedPayload) error
 	UpdAtributoPersonaAPI(ctx context.Context, req domain.PersonaParcial) error
 	UpdPersonaAPI(ctx context.Context, req domain.Persona) error
+	UpdContactoPersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonUpdatedPayload) error
+	RevokePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonBajaPayload) (domain.ResultadoBajaPersona, error)
+	DeletePersonaAPI(ctx context.Context, evt domain.Event, req domain.PersonBajaPayload) (domain.ResultadoBajaPersona, error)
 
 	InsertFullConfigPersonaAPI(ctx context.Context, req domain.ConfigPersonaFull) error
 	UpsertConfigPersonaAPI(ctx context.Context, req domain.ConfigPersona) error
 	InsertConfigPersonalSubTipoAPI(ctx context.Context, config domain.ConfigPersonalSubTipo) error
+	UpsertReglasReservaAPI(ctx context.Context, req domain.ReglasReservaConfig) (domain.ReglasReserva, error)
+	GetReglasReservaAPI(ctx context.Context, req domain.ReglasReservaFiltro) (domain.ReglasReserva, error)
 
 	InsertOrUpdateConfEstablecimientoAPI(ctx context.Context, req domain.ConfEstablecimiento) error
 	UpdateConfEstablecimientoFieldAPI(ctx context.Context, req domain.ConfigEstablecimiento) error
@@ -32,9 +38,47 @@ type AiReservesService interface {
 	UpdAtributeSubTipoUnidadReservaAPI(ctx context.Context, req domain.UpdAtributeSubTipoUnidadReserva) error
 
 	CreateReserveAPI(ctx context.Context, req domain.Reserva) error
-	CancelReserveAPI(ctx context.Context, idReserva int) error
-	SearchReserveAPI(ctx context.Context, req domain.SearchReserve) error
-	InitAgendaAPI(ctx context.Context, req domain.Agenda) error
+	CancelReserveAPI(ctx context.Context, req domain.ReservaCancel) error
+	RescheduleReserveAPI(ctx context.Context, req domain.ReservaReprogramacion) (domain.Reserva, error)
+	ConfirmReserveAPI(ctx context.Context, req domain.ReservaTransicion) error
+	CompleteReserveAPI(ctx context.Context, req domain.ReservaTransicion) error
+	NoShowReserveAPI(ctx context.Context, req domain.ReservaTransicion) error
+	GetHistorialReservaAPI(ctx context.Context, idReserva int) ([]domain.ReservaHistorial, error)
+
+	CreateFeriadoAPI(ctx context.Context, req domain.Feriado) (int, error)
+	UpdFeriadoAPI(ctx context.Context, req domain.Feriado) error
+	DeleteFeriadoAPI(ctx context.Context, idFeriado int) error
+	GetFeriadosAPI(ctx context.Context, req domain.FeriadoFiltro) ([]domain.Feriado, error)
+	ImportFeriadosAPI(ctx context.Context, req domain.FeriadoImport) (domain.FeriadoImportResumen, error)
+
+	CreateBloqueoAPI(ctx context.Context, req domain.AgendaBloqueo) (domain.BloqueoResultado, error)
+	GetBloqueosAPI(ctx context.Context, req domain.BloqueoFiltro) ([]domain.AgendaBloqueo, error)
+	DeleteBloqueoAPI(ctx context.Context, idBloqueo int) error
+	ImportBloqueosICalAPI(ctx context.Context, req domain.BloqueoImport) (domain.BloqueoImportResumen, error)
+
+	CreateSerieAPI(ctx context.Context, req domain.ReservaSerie) (domain.ResultadoSerie, error)
+	CancelSerieAPI(ctx context.Context, req domain.SerieCancel) (domain.ResultadoSerie, error)
+
+	JoinListaEsperaAPI(ctx context.Context, req domain.ListaEspera) (int, error)
+	LeaveListaEsperaAPI(ctx context.Context, idListaEspera int, idPersona int) error
+	GetListaEsperaAPI(ctx context.Context, req domain.ListaEsperaFiltro) ([]domain.ListaEspera, error)
+	AcceptOfertaAPI(ctx context.Context, req domain.OfertaRespuesta) (int, error)
+	DeclineOfertaAPI(ctx context.Context, req domain.OfertaRespuesta) error
+	ProcessOfertasVencidasAPI(ctx context.Context) error
+
+	HoldSlotAPI(ctx context.Context, req domain.RetencionSlot) (domain.RetencionSlot, error)
+	ReleaseSlotHoldAPI(ctx context.Context, token string) error
+	ReleaseExpiredSlotHoldsAPI(ctx context.Context) error
+	UpdRecordatoriosAPI(ctx context.Context, req domain.RecordatoriosConfig) (domain.RecordatoriosConfig, error)
+	ProcessRecordatoriosAPI(ctx context.Context) error
+	ProcessOutboxAPI(ctx context.Context) error
+
+	ExportReservasICSAPI(ctx context.Context, req domain.CalendarioFiltro) ([]byte, error)
+	CreateFeedCalendarioAPI(ctx context.Context, req domain.FeedCalendario) (domain.FeedCalendario, error)
+	RevokeFeedCalendarioAPI(ctx context.Context, token string, idPersona int) error
+	GetFeedCalendarioAPI(ctx context.Context, token string) ([]byte, error)
+	SearchReserveAPI(ctx context.Context, req domain.SearchReserve) (domain.ResultadoBusqueda, error)
+	InitAgendaAPI(ctx context.Context, req domain.Agenda) (domain.AgendaResumen, error)
 
 	GetInfoPersonaAPI(ctx context.Context, idPersona int) (domain.Persona, error)
 	GetReservasPersonaAPI(ctx context.Context, req domain.GetReservaPersona) ([]domain.Reserva, error)
@@ -42,9 +86,16 @@ type AiReservesService interface {
 }
 
 type AiReservesRepository interface {
-	CreatePersona(ctx context.Context, req domain.PersonCreatedPayload) error
+	CreatePersona(ctx context.Context, tx *sql.Tx, req domain.PersonCreatedPayload) error
 	UpdAtributoPersona(ctx context.Context, req domain.PersonaParcial) error
 	UpdPersona(ctx context.Context, req domain.Persona) error
+	UpdContactoPersona(ctx context.Context, tx *sql.Tx, req domain.PersonUpdatedPayload) error
+	BajaPersona(ctx context.Context, tx *sql.Tx, idPersona int, eliminada bool) (bool, error)
+	PersonaDadaDeBaja(ctx context.Context, tx *sql.Tx, idPersona int) (bool, error)
+	AnonimizarPersona(ctx context.Context, tx *sql.Tx, idPersona int) error
+	GetReservasFuturasPaciente(ctx context.Context, tx *sql.Tx, idPaciente int) ([]int, error)
+	RevokeFeedsPersona(ctx context.Context, tx *sql.Tx, idPersona int) (int, error)
+	GetListaEsperaPersona(ctx context.Context, tx *sql.Tx, idPersona int) ([]domain.ListaEspera, error)
 
 	InsertFullConfigPersona(ctx context.Context, req domain.ConfigPersonaFull) error
 	UpsertConfigPersona(ctx context.Context, req domain.ConfigPersona) error
@@ -65,16 +116,94 @@ type AiReservesRepository interface {
 	ModifSubTipoUnidadReserva(ctx context.Context, req domain.UpdSubTipoUnidadReserva) error
 	UpdAtributeSubTipoUnidadReserva(ctx context.Context, req domain.UpdAtributeSubTipoUnidadReserva) error
 
-	CreateReserve(ctx context.Context, req domain.Reserva) error
-	CancelReserve(ctx context.Context, idReserva int) error
-	SearchReserve(ctx context.Context, req domain.SearchReserve) error
-	InitAgenda(ctx context.Context, req domain.Agenda) error
+	CreateReserve(ctx context.Context, tx *sql.Tx, req domain.Reserva) (int, error)
+	CancelReserve(ctx context.Context, tx *sql.Tx, req domain.ReservaCancel) error
+	MoveReserve(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) (domain.Reserva, error)
+	MoveReservaSinSlot(ctx context.Context, tx *sql.Tx, idReserva int, destino domain.Reserva) error
+	OcuparRecurso(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, recurso domain.RecursoReserva) error
+	RegistrarAgendaRecurso(ctx context.Context, tx *sql.Tx, idAgenda, idReserva int) error
+	GetRecursosReserva(ctx context.Context, tx *sql.Tx, idReserva int) ([]domain.RecursoReserva, error)
+	GetSlotsLibresRecurso(ctx context.Context, recurso domain.RecursoReserva, desde, hasta time.Time) ([]domain.AgendaSlot, error)
+	GetReservaForUpdate(ctx context.Context, tx *sql.Tx, idReserva int) (domain.Reserva, error)
+	UpdEstadoReserva(ctx context.Context, tx *sql.Tx, req domain.ReservaTransicion) error
+	InsertHistorialReserva(ctx context.Context, tx *sql.Tx, h domain.ReservaHistorial) error
+	GetHistorialReserva(ctx context.Context, idReserva int) ([]domain.ReservaHistorial, error)
+
+	CreateFeriado(ctx context.Context, req domain.Feriado) (int, error)
+	UpdFeriado(ctx context.Context, req domain.Feriado) error
+	DeleteFeriado(ctx context.Context, idFeriado int) error
+	GetFeriados(ctx context.Context, req domain.FeriadoFiltro) ([]domain.Feriado, error)
+	InsertFeriados(ctx context.Context, tx *sql.Tx, feriados []domain.Feriado) (domain.FeriadoImportResumen, error)
+
+	CreateBloqueo(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) (int, error)
+	BloquearSlots(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) (int, error)
+	GetReservasEnBloqueo(ctx context.Context, tx *sql.Tx, req domain.AgendaBloqueo) ([]domain.Reserva, error)
+	DeleteBloqueo(ctx context.Context, tx *sql.Tx, idBloqueo int) (domain.AgendaBloqueo, error)
+	GetBloqueos(ctx context.Context, req domain.BloqueoFiltro) ([]domain.AgendaBloqueo, error)
+	GetBloqueosICal(ctx context.Context, tx *sql.Tx, idConfPersonal int, uids []string, desde time.Time) ([]domain.AgendaBloqueo, error)
+	GetZonaConfPersonal(ctx context.Context, idConfPersonal int) (string, error)
+
+	GetReservasProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.Reserva, error)
+	GetReservasAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.Reserva, error)
+	LockAgendaDia(ctx context.Context, tx *sql.Tx, idConfPersonal int, fecha time.Time) (int, error)
+	CreateReservaSinSlot(ctx context.Context, tx *sql.Tx, req domain.Reserva) (int, error)
+
+	CreateSerie(ctx context.Context, tx *sql.Tx, req domain.ReservaSerie) (int, error)
+	UpdEstadoSerie(ctx context.Context, tx *sql.Tx, idSerie int, estado string) error
+	GetReservasSerie(ctx context.Context, tx *sql.Tx, idSerie int, desde, hasta time.Time) ([]domain.Reserva, error)
+
+	CreateListaEspera(ctx context.Context, req domain.ListaEspera) (int, error)
+	GetListaEspera(ctx context.Context, req domain.ListaEsperaFiltro) ([]domain.ListaEspera, error)
+	GetListaEsperaForUpdate(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.ListaEspera, error)
+	NextListaEspera(ctx context.Context, tx *sql.Tx, turno domain.TurnoLiberado) (domain.ListaEspera, error)
+	UpdEstadoListaEspera(ctx context.Context, tx *sql.Tx, idListaEspera int, estado string) error
+	GetTurnoReserva(ctx context.Context, tx *sql.Tx, reserva domain.Reserva) (domain.TurnoLiberado, error)
+	RetenerSlot(ctx context.Context, tx *sql.Tx, idSlot int, hasta time.Time) (bool, error)
+	LiberarSlotRetenido(ctx context.Context, tx *sql.Tx, idSlot int) error
+	CreateOferta(ctx context.Context, tx *sql.Tx, req domain.OfertaListaEspera) (int, error)
+	GetOfertaForUpdate(ctx context.Context, tx *sql.Tx, idOferta int) (domain.OfertaListaEspera, error)
+	GetOfertaPendiente(ctx context.Context, tx *sql.Tx, idListaEspera int) (domain.OfertaListaEspera, error)
+	UpdEstadoOferta(ctx context.Context, tx *sql.Tx, idOferta int, estado string) error
+	GetOfertasVencidas(ctx context.Context, tx *sql.Tx, limite int) ([]domain.OfertaListaEspera, error)
+	GetOfertasVigentesProfesional(ctx context.Context, idConfPersonal int, desde, hasta time.Time) ([]domain.OfertaListaEspera, error)
+	GetOfertasVigentesAgenda(ctx context.Context, tx *sql.Tx, idAgenda int) ([]domain.OfertaListaEspera, error)
+
+	HoldSlot(ctx context.Context, tx *sql.Tx, req domain.RetencionSlot) (domain.RetencionSlot, error)
+	ReleaseSlotHold(ctx context.Context, token string) error
+	ReleaseExpiredSlotHolds(ctx context.Context) (int, error)
+	UpdRecordatoriosSubTipo(ctx context.Context, req domain.RecordatoriosConfig) (domain.RecordatoriosConfig, error)
+	ClaimRecordatorios(ctx context.Context, tx *sql.Tx, limite int) ([]domain.RecordatorioReserva, error)
+	ResetRecordatorios(ctx context.Context, tx *sql.Tx, idReserva int) error
+
+	GetReservasCalendario(ctx context.Context, req domain.CalendarioFiltro) ([]domain.ReservaCalendario, error)
+	CreateFeedCalendario(ctx context.Context, req domain.FeedCalendario) (domain.FeedCalendario, error)
+	GetFeedCalendario(ctx context.Context, token string) (domain.FeedCalendario, error)
+	RevokeFeedCalendario(ctx context.Context, token string, idPersona int) error
+	GetAnticipacionCancelacion(ctx context.Context, tx *sql.Tx, idSubTipo int) (int, error)
+	GetReglasReserva(ctx context.Context, idSubTipo int, idConfPersonal int) (domain.ReglasReserva, error)
+	UpdReglasSubTipo(ctx context.Context, req domain.ReglasReservaConfig) error
+	UpdReglasConfPersonal(ctx context.Context, idConfPersonal int, req domain.ReglasReservaConfig) error
+	LockPersona(ctx context.Context, tx *sql.Tx, idPersona int) error
+	GetReservasPaciente(ctx context.Context, tx *sql.Tx, idPaciente int, idSubTipo int, desde, hasta time.Time) ([]domain.Reserva, error)
+	GetReservasVecinas(ctx context.Context, tx *sql.Tx, reserva domain.Reserva, margen time.Duration) ([]domain.Reserva, error)
+	SearchReserve(ctx context.Context, req domain.SearchReserve) ([]domain.SlotDisponible, int, error)
+	InitAgenda(ctx context.Context, tx *sql.Tx, dias []domain.AgendaDia) (domain.AgendaResumen, error)
+	GetConfigPersonaFull(ctx context.Context, idPersona int) (domain.ConfigPersonaFull, error)
+	GetSubTiposConfPersonal(ctx context.Context, idConfPersonal int) ([]domain.ConfigPersonalSubTipo, error)
+	GetConfEstablecimiento(ctx context.Context, idConfEstablecimiento int) (domain.ConfEstablecimiento, error)
+	GetDuracionSubTipo(ctx context.Context, idSubTipo int) (int, error)
+	GetCapacidadSubTipo(ctx context.Context, idSubTipo int) (int, error)
+	GetIDAgenda(ctx context.Context, idConfPersonal int, idConfEstablecimiento int, fecha time.Time) (int, error)
 
 	GetInfoPersona(ctx context.Context, idPersona int) (domain.Persona, error)
 	GetReservasPersona(ctx context.Context, req domain.GetReservaPersona) ([]domain.Reserva, error)
 	GetReservasUnidadReserva(ctx context.Context, req domain.GetReservaUnidadReserva) ([]domain.Reserva, error)
 
 	PushEventToQueue(ctx context.Context, tx *sql.Tx, event domain.Event) error
+	RegistrarEventoProcesado(ctx context.Context, tx *sql.Tx, event domain.Event) (bool, error)
+	ClaimOutboxPendientes(ctx context.Context, limite int, lease time.Duration) ([]domain.OutboxEvent, error)
+	MarkOutboxEnviado(ctx context.Context, id int64) error
+	MarkOutboxError(ctx context.Context, id int64, estado string, proximoIntento time.Time, causa string) error
 	WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error
 }
 
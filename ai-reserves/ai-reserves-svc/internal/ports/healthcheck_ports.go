@@ -13,3 +13,7 @@ type HealthcheckService interface {
 type HealthcheckRepository interface {
 	GetDatabasesPing(ctx context.Context) ([]domain.Database, error)
 }
+
+type BrokerStatus interface {
+	Status() domain.Broker
+}
@@ -0,0 +1,13 @@
+-- Cancelación de reservas: quién y por qué se canceló + política de anticipación por sub tipo
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.reservas
+    ADD COLUMN IF NOT EXISTS cancelado_por VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS motivo_cancelacion TEXT,
+    ADD COLUMN IF NOT EXISTS fecha_cancelacion TIMESTAMP;
+
+-- Horas mínimas de anticipación para cancelar (0 = se puede cancelar en cualquier momento)
+ALTER TABLE ai_res.sub_tipo_unidad_reserva
+    ADD COLUMN IF NOT EXISTS anticipacion_cancelacion_horas INT NOT NULL DEFAULT 0;
+
+RESET ROLE;
@@ -0,0 +1,23 @@
+-- Máquina de estados de reservas: PENDIENTE → CONFIRMADA → FINALIZADA / AUSENTE (+ CANCELADA)
+SET ROLE ai_reserves;
+
+COMMENT ON COLUMN ai_res.reservas.estado IS 'PENDIENTE / CONFIRMADA / CANCELADA / FINALIZADA / AUSENTE';
+
+-- Historial de transiciones de estado (base de los reportes de asistencia y ausentismo)
+CREATE TABLE IF NOT EXISTS ai_res.reservas_historial (
+    id SERIAL PRIMARY KEY,
+    id_reserva INT NOT NULL REFERENCES ai_res.reservas(id) ON DELETE CASCADE,
+    estado_anterior VARCHAR(50), -- NULL en el alta de la reserva
+    estado_nuevo VARCHAR(50) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    motivo TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservas_historial_reserva
+    ON ai_res.reservas_historial (id_reserva, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_reservas_historial_estado
+    ON ai_res.reservas_historial (estado_nuevo, created_at);
+
+RESET ROLE;
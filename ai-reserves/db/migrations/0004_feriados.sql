@@ -0,0 +1,21 @@
+-- Calendario de feriados: nacionales (id_conf_establecimiento NULL) y cierres por establecimiento.
+-- La generación de agendas saltea estas fechas salvo que genera_feriados sea TRUE.
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.feriados (
+    id SERIAL PRIMARY KEY,
+    fecha DATE NOT NULL,
+    descripcion VARCHAR(255),
+    id_conf_establecimiento INT REFERENCES ai_res.conf_establecimiento(id) ON DELETE CASCADE,
+    origen VARCHAR(20) NOT NULL DEFAULT 'MANUAL', -- MANUAL / ICAL / CSV
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(100),
+    updated_by VARCHAR(100)
+);
+
+-- Un solo feriado por día y alcance (nacional = 0)
+CREATE UNIQUE INDEX IF NOT EXISTS ux_feriados_fecha_alcance
+    ON ai_res.feriados (fecha, (COALESCE(id_conf_establecimiento, 0)));
+
+RESET ROLE;
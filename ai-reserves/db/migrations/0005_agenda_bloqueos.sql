@@ -0,0 +1,31 @@
+-- Bloqueos de agenda: ausencias de un profesional o cierres de un establecimiento por un rango horario
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.agenda_bloqueos (
+    id SERIAL PRIMARY KEY,
+    id_conf_personal INT REFERENCES ai_res.conf_personal(id) ON DELETE CASCADE,
+    id_conf_establecimiento INT REFERENCES ai_res.conf_establecimiento(id) ON DELETE CASCADE,
+    inicio TIMESTAMP NOT NULL,
+    fin TIMESTAMP NOT NULL,
+    motivo TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(100),
+
+    -- Un bloqueo aplica a un profesional o a un establecimiento, no a ambos
+    CHECK ((id_conf_personal IS NULL) <> (id_conf_establecimiento IS NULL)),
+    CHECK (fin > inicio)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agenda_bloqueos_personal
+    ON ai_res.agenda_bloqueos (id_conf_personal, inicio, fin);
+
+CREATE INDEX IF NOT EXISTS idx_agenda_bloqueos_establecimiento
+    ON ai_res.agenda_bloqueos (id_conf_establecimiento, inicio, fin);
+
+-- Slot alcanzado por un bloqueo: queda BLOQUEADO si estaba libre
+ALTER TABLE ai_res.agenda_slots
+    ADD COLUMN IF NOT EXISTS id_bloqueo INT REFERENCES ai_res.agenda_bloqueos(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN ai_res.agenda_slots.estado IS 'LIBRE / OCUPADO / BLOQUEADO';
+
+RESET ROLE;
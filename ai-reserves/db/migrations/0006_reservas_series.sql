@@ -0,0 +1,27 @@
+-- Reservas recurrentes: una serie agrupa las reservas creadas a partir de una regla de repetición
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.reservas_series (
+    id SERIAL PRIMARY KEY,
+    rrule VARCHAR(255) NOT NULL, -- FREQ=WEEKLY;INTERVAL=n;COUNT=n / UNTIL=...
+    fecha_inicio DATE NOT NULL,
+    hora_inicio TIME NOT NULL,
+    id_sub_tipo_unidad_reserva INT NOT NULL REFERENCES ai_res.sub_tipo_unidad_reserva(id),
+    id_paciente INT REFERENCES ai_res.personas(id),
+    id_profesional INT REFERENCES ai_res.personas(id),
+    id_conf_establecimiento INT REFERENCES ai_res.conf_establecimiento(id) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'ACTIVA', -- ACTIVA / CANCELADA
+    observaciones TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(100),
+    updated_by VARCHAR(100)
+);
+
+ALTER TABLE ai_res.reservas
+    ADD COLUMN IF NOT EXISTS id_serie INT REFERENCES ai_res.reservas_series(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reservas_serie
+    ON ai_res.reservas (id_serie, fecha);
+
+RESET ROLE;
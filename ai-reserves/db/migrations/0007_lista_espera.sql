@@ -0,0 +1,65 @@
+-- Lista de espera: cuando una agenda está completa la persona se anota y, al liberarse un turno,
+-- se le ofrece retenido por un tiempo limitado antes de pasar al siguiente
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.lista_espera (
+    id SERIAL PRIMARY KEY,
+    id_persona INT NOT NULL REFERENCES ai_res.personas(id) ON DELETE CASCADE,
+
+    -- Profesional o establecimiento preferido (ambos NULL = cualquiera que ofrezca el sub tipo)
+    id_conf_personal INT REFERENCES ai_res.conf_personal(id) ON DELETE CASCADE,
+    id_conf_establecimiento INT REFERENCES ai_res.conf_establecimiento(id) ON DELETE CASCADE,
+    id_sub_tipo_unidad_reserva INT NOT NULL REFERENCES ai_res.sub_tipo_unidad_reserva(id),
+
+    -- Ventana preferida
+    fecha_desde DATE NOT NULL,
+    fecha_hasta DATE NOT NULL,
+    hora_desde TIME,
+    hora_hasta TIME,
+
+    estado VARCHAR(20) NOT NULL DEFAULT 'ESPERANDO', -- ESPERANDO / OFRECIDO / ASIGNADO / CANCELADO
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(100),
+    updated_by VARCHAR(100),
+
+    CHECK (fecha_hasta >= fecha_desde)
+);
+
+-- El orden de llegada decide a quién se ofrece primero
+CREATE INDEX IF NOT EXISTS idx_lista_espera_pendientes
+    ON ai_res.lista_espera (id_sub_tipo_unidad_reserva, estado, created_at);
+
+CREATE TABLE IF NOT EXISTS ai_res.lista_espera_ofertas (
+    id SERIAL PRIMARY KEY,
+    id_lista_espera INT NOT NULL REFERENCES ai_res.lista_espera(id) ON DELETE CASCADE,
+
+    -- Turno ofrecido (id_slot NULL en agendas MULTIAGENDA)
+    id_agenda INT NOT NULL REFERENCES ai_res.agendas(id) ON DELETE CASCADE,
+    id_slot INT REFERENCES ai_res.agenda_slots(id) ON DELETE SET NULL,
+    id_conf_personal INT REFERENCES ai_res.conf_personal(id) ON DELETE CASCADE,
+    id_conf_establecimiento INT REFERENCES ai_res.conf_establecimiento(id) ON DELETE CASCADE,
+    id_sub_tipo_unidad_reserva INT NOT NULL REFERENCES ai_res.sub_tipo_unidad_reserva(id),
+    fecha DATE NOT NULL,
+    hora_inicio TIME NOT NULL,
+    hora_fin TIME NOT NULL,
+
+    estado VARCHAR(20) NOT NULL DEFAULT 'PENDIENTE', -- PENDIENTE / ACEPTADA / RECHAZADA / VENCIDA
+    vence_en TIMESTAMP NOT NULL,
+    respondido_en TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lista_espera_ofertas_vencimiento
+    ON ai_res.lista_espera_ofertas (estado, vence_en);
+
+CREATE INDEX IF NOT EXISTS idx_lista_espera_ofertas_agenda
+    ON ai_res.lista_espera_ofertas (id_agenda, hora_inicio);
+
+-- Slot retenido para una oferta de la lista de espera: nadie más lo puede reservar hasta retenido_hasta
+ALTER TABLE ai_res.agenda_slots
+    ADD COLUMN IF NOT EXISTS retenido_hasta TIMESTAMP;
+
+COMMENT ON COLUMN ai_res.agenda_slots.estado IS 'LIBRE / OCUPADO / BLOQUEADO / RETENIDO';
+
+RESET ROLE;
@@ -0,0 +1,18 @@
+-- Retención temporal de slots durante el checkout: el slot queda RETENIDO con un token hasta
+-- retenido_hasta; la reserva se confirma presentando el token y un barrido libera las vencidas
+SET ROLE ai_reserves;
+
+-- Token del checkout que retiene el slot (NULL en las retenciones de la lista de espera)
+ALTER TABLE ai_res.agenda_slots
+    ADD COLUMN IF NOT EXISTS token_retencion VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agenda_slots_token_retencion
+    ON ai_res.agenda_slots (token_retencion)
+    WHERE token_retencion IS NOT NULL;
+
+-- Barrido de retenciones vencidas
+CREATE INDEX IF NOT EXISTS idx_agenda_slots_retenido_hasta
+    ON ai_res.agenda_slots (retenido_hasta)
+    WHERE estado = 'RETENIDO';
+
+RESET ROLE;
@@ -0,0 +1,19 @@
+-- Feeds iCalendar de solo lectura: el token en la URL identifica a la persona y el rol
+-- (sus reservas como paciente o las de su agenda como profesional / establecimiento)
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.calendario_feeds (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    id_persona INT NOT NULL REFERENCES ai_res.personas(id) ON DELETE CASCADE,
+    rol VARCHAR(20) NOT NULL, -- PACIENTE / PROFESIONAL
+    activo BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(100),
+    revocado_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendario_feeds_persona
+    ON ai_res.calendario_feeds (id_persona);
+
+RESET ROLE;
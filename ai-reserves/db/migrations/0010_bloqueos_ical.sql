@@ -0,0 +1,13 @@
+-- Bloqueos importados desde el calendario personal (.ics) de un profesional: cada ocurrencia
+-- de un VEVENT ocupado es un bloqueo identificado por UID + ocurrencia, así reimportar actualiza
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.agenda_bloqueos
+    ADD COLUMN IF NOT EXISTS uid_ical TEXT,
+    ADD COLUMN IF NOT EXISTS ocurrencia_ical TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agenda_bloqueos_ical
+    ON ai_res.agenda_bloqueos (id_conf_personal, uid_ical, ocurrencia_ical)
+    WHERE uid_ical IS NOT NULL;
+
+RESET ROLE;
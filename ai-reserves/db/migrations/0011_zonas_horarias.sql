@@ -0,0 +1,74 @@
+-- Zonas horarias: cada profesional y establecimiento atiende en su zona IANA. Las agendas siguen
+-- guardando fecha y hora de reloj locales (lo que ve el usuario) y además el instante UTC de cada
+-- slot, reserva y bloqueo, que es lo que se compara con "ahora" y entre zonas distintas.
+-- Hasta esta migración todo se interpretaba en la hora de Buenos Aires: es la zona de los datos existentes.
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.conf_personal
+    ADD COLUMN IF NOT EXISTS zona_horaria VARCHAR(64) NOT NULL DEFAULT 'America/Argentina/Buenos_Aires';
+
+ALTER TABLE ai_res.conf_establecimiento
+    ADD COLUMN IF NOT EXISTS zona_horaria VARCHAR(64) NOT NULL DEFAULT 'America/Argentina/Buenos_Aires';
+
+-- Zona con la que se generó la agenda del día: cambiar la zona de la configuración no mueve los turnos ya generados
+ALTER TABLE ai_res.agendas
+    ADD COLUMN IF NOT EXISTS zona_horaria VARCHAR(64) NOT NULL DEFAULT 'America/Argentina/Buenos_Aires';
+
+-- Instantes UTC de slots y reservas
+ALTER TABLE ai_res.agenda_slots
+    ADD COLUMN IF NOT EXISTS inicio_utc TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS fin_utc TIMESTAMPTZ;
+
+UPDATE ai_res.agenda_slots s
+   SET inicio_utc = (a.fecha + s.hora_inicio) AT TIME ZONE a.zona_horaria,
+       fin_utc = (a.fecha + s.hora_fin) AT TIME ZONE a.zona_horaria
+  FROM ai_res.agendas a
+ WHERE a.id = s.id_agenda
+   AND s.inicio_utc IS NULL;
+
+ALTER TABLE ai_res.reservas
+    ADD COLUMN IF NOT EXISTS inicio_utc TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS fin_utc TIMESTAMPTZ;
+
+UPDATE ai_res.reservas r
+   SET inicio_utc = (r.fecha + r.hora_inicio) AT TIME ZONE a.zona_horaria,
+       fin_utc = (r.fecha + r.hora_fin) AT TIME ZONE a.zona_horaria
+  FROM ai_res.agendas a
+ WHERE a.id = r.id_agenda
+   AND r.inicio_utc IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_agenda_slots_inicio_utc
+    ON ai_res.agenda_slots (inicio_utc);
+
+CREATE INDEX IF NOT EXISTS idx_reservas_inicio_utc
+    ON ai_res.reservas (inicio_utc);
+
+-- Bloqueos, ofertas y retenciones pasan a TIMESTAMPTZ (solo la primera vez que corre la migración)
+DO $$
+BEGIN
+  IF (SELECT data_type
+        FROM information_schema.columns
+       WHERE table_schema = 'ai_res' AND table_name = 'agenda_bloqueos' AND column_name = 'inicio') = 'timestamp without time zone' THEN
+    ALTER TABLE ai_res.agenda_bloqueos
+      ALTER COLUMN inicio TYPE TIMESTAMPTZ USING inicio AT TIME ZONE 'America/Argentina/Buenos_Aires',
+      ALTER COLUMN fin TYPE TIMESTAMPTZ USING fin AT TIME ZONE 'America/Argentina/Buenos_Aires',
+      ALTER COLUMN ocurrencia_ical TYPE TIMESTAMPTZ USING ocurrencia_ical AT TIME ZONE 'America/Argentina/Buenos_Aires';
+  END IF;
+
+  -- Se escribieron y compararon contra CURRENT_TIMESTAMP: están en la zona de la sesión
+  IF (SELECT data_type
+        FROM information_schema.columns
+       WHERE table_schema = 'ai_res' AND table_name = 'lista_espera_ofertas' AND column_name = 'vence_en') = 'timestamp without time zone' THEN
+    ALTER TABLE ai_res.lista_espera_ofertas
+      ALTER COLUMN vence_en TYPE TIMESTAMPTZ;
+  END IF;
+
+  IF (SELECT data_type
+        FROM information_schema.columns
+       WHERE table_schema = 'ai_res' AND table_name = 'agenda_slots' AND column_name = 'retenido_hasta') = 'timestamp without time zone' THEN
+    ALTER TABLE ai_res.agenda_slots
+      ALTER COLUMN retenido_hasta TYPE TIMESTAMPTZ;
+  END IF;
+END$$;
+
+RESET ROLE;
@@ -0,0 +1,23 @@
+-- Reglas de reserva por sub tipo, con override opcional por profesional (NULL = usa la del sub tipo).
+-- En todas 0 significa "sin límite".
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.sub_tipo_unidad_reserva
+    ADD COLUMN IF NOT EXISTS anticipacion_minima_minutos INT NOT NULL DEFAULT 0 CHECK (anticipacion_minima_minutos >= 0),
+    ADD COLUMN IF NOT EXISTS horizonte_dias INT NOT NULL DEFAULT 0 CHECK (horizonte_dias >= 0),
+    ADD COLUMN IF NOT EXISTS max_reservas_dia INT NOT NULL DEFAULT 0 CHECK (max_reservas_dia >= 0),
+    ADD COLUMN IF NOT EXISTS max_reservas_semana INT NOT NULL DEFAULT 0 CHECK (max_reservas_semana >= 0),
+    ADD COLUMN IF NOT EXISTS buffer_minutos INT NOT NULL DEFAULT 0 CHECK (buffer_minutos >= 0);
+
+ALTER TABLE ai_res.conf_personal_sub_tipo_unidad_reserva
+    ADD COLUMN IF NOT EXISTS anticipacion_minima_minutos INT CHECK (anticipacion_minima_minutos >= 0),
+    ADD COLUMN IF NOT EXISTS horizonte_dias INT CHECK (horizonte_dias >= 0),
+    ADD COLUMN IF NOT EXISTS max_reservas_dia INT CHECK (max_reservas_dia >= 0),
+    ADD COLUMN IF NOT EXISTS max_reservas_semana INT CHECK (max_reservas_semana >= 0),
+    ADD COLUMN IF NOT EXISTS buffer_minutos INT CHECK (buffer_minutos >= 0);
+
+-- Topes por paciente: se cuentan sus reservas vigentes del día / semana
+CREATE INDEX IF NOT EXISTS idx_reservas_paciente_fecha
+    ON ai_res.reservas (id_paciente, fecha);
+
+RESET ROLE;
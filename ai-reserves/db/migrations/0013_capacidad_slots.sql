@@ -0,0 +1,35 @@
+-- Turnos con cupo: un sub tipo (clase, taller, recurso compartido) define cuántas reservas entran
+-- en el mismo slot. El slot guarda su capacidad al generarse y cuántos lugares están ocupados;
+-- pasa a OCUPADO recién cuando se llena. Con capacidad 1 el comportamiento es el de siempre.
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.sub_tipo_unidad_reserva
+    ADD COLUMN IF NOT EXISTS capacidad INT NOT NULL DEFAULT 1 CHECK (capacidad >= 1);
+
+ALTER TABLE ai_res.agenda_slots
+    ADD COLUMN IF NOT EXISTS capacidad INT NOT NULL DEFAULT 1 CHECK (capacidad >= 1),
+    ADD COLUMN IF NOT EXISTS ocupados INT NOT NULL DEFAULT 0;
+
+-- Slots ya ocupados por la reserva exclusiva de siempre
+UPDATE ai_res.agenda_slots
+   SET ocupados = 1
+ WHERE id_reserva IS NOT NULL
+   AND ocupados = 0;
+
+-- Red de seguridad contra sobreventa: nunca más ocupados que lugares
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'agenda_slots_ocupados_check') THEN
+        ALTER TABLE ai_res.agenda_slots
+            ADD CONSTRAINT agenda_slots_ocupados_check CHECK (ocupados BETWEEN 0 AND capacidad);
+    END IF;
+END $$;
+
+COMMENT ON COLUMN ai_res.agenda_slots.estado IS 'LIBRE (quedan lugares) / OCUPADO (completo) / BLOQUEADO / RETENIDO';
+COMMENT ON COLUMN ai_res.agenda_slots.id_reserva IS 'Reserva que ocupa el slot cuando es exclusivo (capacidad 1)';
+
+-- Las reservas de un slot compartido se ubican por agenda y hora de inicio
+CREATE INDEX IF NOT EXISTS idx_reservas_agenda_hora
+    ON ai_res.reservas (id_agenda, hora_inicio);
+
+RESET ROLE;
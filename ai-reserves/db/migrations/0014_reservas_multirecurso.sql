@@ -0,0 +1,11 @@
+-- Reservas que ocupan varios recursos a la vez (profesional + sillón / sala): la agenda principal
+-- sigue en reservas.id_agenda y las de los recursos adicionales se registran en agendas_reservas.
+SET ROLE ai_reserves;
+
+COMMENT ON TABLE ai_res.agendas_reservas IS 'Agendas de los recursos adicionales que ocupa una reserva, además de reservas.id_agenda';
+
+-- Al cancelar o mover una reserva se buscan sus recursos por id_reserva
+CREATE INDEX IF NOT EXISTS idx_agendas_reservas_reserva
+    ON ai_res.agendas_reservas (id_reserva);
+
+RESET ROLE;
@@ -0,0 +1,24 @@
+-- Recordatorios de reservas confirmadas: cada sub tipo define cuántas horas antes del turno se avisa
+-- (puede haber varios, p. ej. {48, 2}). Cada aviso enviado se registra una sola vez por reserva,
+-- así un reinicio o varias réplicas del scheduler no lo repiten.
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.sub_tipo_unidad_reserva
+    ADD COLUMN IF NOT EXISTS recordatorios_horas INT[] NOT NULL DEFAULT '{}'
+        CHECK (0 < ALL (recordatorios_horas));
+
+CREATE TABLE IF NOT EXISTS ai_res.reservas_recordatorios (
+    id SERIAL PRIMARY KEY,
+    id_reserva INT NOT NULL REFERENCES ai_res.reservas(id) ON DELETE CASCADE,
+    horas_antes INT NOT NULL,
+    enviado_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (id_reserva, horas_antes)
+);
+
+-- El scheduler recorre las reservas confirmadas que empiezan pronto
+CREATE INDEX IF NOT EXISTS idx_reservas_confirmadas_inicio
+    ON ai_res.reservas (inicio_utc)
+    WHERE estado = 'CONFIRMADA';
+
+RESET ROLE;
@@ -0,0 +1,31 @@
+-- Outbox de eventos: los eventos de las reservas se graban en la misma transacción que el cambio
+-- y un relay los publica en RabbitMQ. Entrega at-least-once: un evento puede llegar repetido
+-- (los consumidores deduplican por id_event) pero no se pierde si el broker no está disponible.
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    id_event VARCHAR(64) NOT NULL UNIQUE,
+    tipo VARCHAR(100) NOT NULL,
+    routing_key VARCHAR(100) NOT NULL,
+    origen VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    ocurrido_at TIMESTAMPTZ NOT NULL,
+
+    -- PENDING: por publicar / reintentar, SENT: publicado, FAILED: agotó los reintentos
+    estado VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (estado IN ('PENDING', 'SENT', 'FAILED')),
+    intentos INT NOT NULL DEFAULT 0,
+    proximo_intento TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ultimo_error TEXT,
+    enviado_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- El relay recorre solo lo pendiente, en orden de alta
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pendientes
+    ON ai_res.outbox_events (proximo_intento, id)
+    WHERE estado = 'PENDING';
+
+RESET ROLE;
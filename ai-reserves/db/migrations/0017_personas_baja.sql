@@ -0,0 +1,17 @@
+-- Baja de usuarios desde auth-security: una persona revocada o eliminada no puede tomar reservas
+-- nuevas. La eliminación además anonimiza sus datos o cancela sus reservas futuras, según
+-- USER_DELETED_POLICY del servicio.
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.personas
+    ADD COLUMN IF NOT EXISTS revocada_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS eliminada_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN ai_res.personas.revocada_at IS 'Acceso revocado en auth-security (user.revoked / user.deleted)';
+COMMENT ON COLUMN ai_res.personas.eliminada_at IS 'Usuario eliminado en auth-security (user.deleted)';
+
+-- Reservas futuras de un paciente, para cancelarlas en la baja
+CREATE INDEX IF NOT EXISTS idx_reservas_paciente_inicio
+    ON ai_res.reservas (id_paciente, inicio_utc);
+
+RESET ROLE;
@@ -0,0 +1,16 @@
+-- Idempotencia del consumer: cada evento recibido se registra por su id una vez procesado,
+-- así una redelivery (reintento, reconexión, réplica) se confirma sin volver a aplicarse.
+SET ROLE ai_reserves;
+
+CREATE TABLE IF NOT EXISTS ai_res.eventos_procesados (
+    id_event VARCHAR(64) PRIMARY KEY,
+    routing_key VARCHAR(100) NOT NULL,
+    origen VARCHAR(100),
+    procesado_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Para purgar los registros viejos
+CREATE INDEX IF NOT EXISTS idx_eventos_procesados_fecha
+    ON ai_res.eventos_procesados (procesado_at);
+
+RESET ROLE;
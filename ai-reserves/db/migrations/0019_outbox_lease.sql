@@ -0,0 +1,21 @@
+-- Lease del outbox: el relay reclama los eventos en una transacción corta (estado PROCESSING hasta
+-- locked_until) y los publica fuera de ella, marcando cada uno en su propia transacción. Si el relay
+-- se cae a mitad del lote, los eventos reclamados vuelven a salir cuando vence el lease.
+SET ROLE ai_reserves;
+
+ALTER TABLE ai_res.outbox_events
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+ALTER TABLE ai_res.outbox_events
+    DROP CONSTRAINT IF EXISTS outbox_events_estado_check;
+
+ALTER TABLE ai_res.outbox_events
+    ADD CONSTRAINT outbox_events_estado_check
+        CHECK (estado IN ('PENDING', 'PROCESSING', 'SENT', 'FAILED'));
+
+-- Para recuperar los leases vencidos
+CREATE INDEX IF NOT EXISTS idx_outbox_events_procesando
+    ON ai_res.outbox_events (locked_until, id)
+    WHERE estado = 'PROCESSING';
+
+RESET ROLE;
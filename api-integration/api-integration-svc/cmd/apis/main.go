@@ -66,8 +66,8 @@ func main() {
 	var messageQueue ports.MessageQueue = rabbitMQAdapter
 
 	// 4) Servicios (application)
-	versionService := application.NewVersionService(versionRepository, *cfg.App)             // <- AJUSTAR a tu firma real
-	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App) // <- AJUSTAR a tu firma real
+	versionService := application.NewVersionService(versionRepository, *cfg.App)                              // <- AJUSTAR a tu firma real
+	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App, rabbitMQAdapter) // <- AJUSTAR a tu firma real
 	apiIntegrationService := application.NewApiIntegrationService(apiIntegrationRepository, *cfg.App, messageQueue, httpClient)
 
 	// 5) Handlers (adapters in/http)
@@ -0,0 +1,156 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/FrancoRebollo/api-integration-svc/internal/domain"
+	"github.com/streadway/amqp"
+)
+
+// Publisher confirms: el canal de publish está en modo confirm y se publica con mandatory, así
+// Publish recién devuelve nil cuando el broker confirmó el mensaje. Si el mensaje no tenía cola
+// a la que rutearse el broker lo devuelve (basic.return) antes de confirmarlo y Publish falla con
+// domain.ErrEventoNoRuteado; un nack o la caída del canal también son error.
+
+// Espera máxima de la confirmación del broker
+const confirmTimeout = 10 * time.Second
+
+// confirmador publica en un canal en modo confirm y le entrega a cada Publish la respuesta del
+// broker para su delivery tag. Vive lo mismo que el canal: la reconexión crea uno nuevo.
+type confirmador struct {
+	mu         sync.Mutex // serializa los publish para que el delivery tag sea el esperado
+	ch         *amqp.Channel
+	ultimoTag  uint64
+	pendientes map[uint64]chan error
+	cerrado    bool
+}
+
+func newConfirmador(ch *amqp.Channel) (*confirmador, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("confirm mode: %w", err)
+	}
+
+	c := &confirmador{
+		ch:         ch,
+		pendientes: map[uint64]chan error{},
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 100))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 100))
+	go c.escuchar(confirms, returns)
+
+	return c, nil
+}
+
+// publish publica con mandatory y espera la confirmación del broker
+func (c *confirmador) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	c.mu.Lock()
+	if c.cerrado {
+		c.mu.Unlock()
+		return domain.ErrBrokerDesconectado
+	}
+	tag := c.ultimoTag + 1
+	// El delivery tag viaja como correlation id para asociar el basic.return con su publish
+	msg.CorrelationId = strconv.FormatUint(tag, 10)
+	if err := c.ch.Publish(exchange, routingKey, true, false, msg); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.ultimoTag = tag
+	respuesta := make(chan error, 1)
+	c.pendientes[tag] = respuesta
+	c.mu.Unlock()
+
+	select {
+	case err := <-respuesta:
+		return err
+	case <-ctx.Done():
+		c.olvidar(tag)
+		return ctx.Err()
+	case <-time.After(confirmTimeout):
+		c.olvidar(tag)
+		return fmt.Errorf("rabbitmq: no confirmation for %s after %s", routingKey, confirmTimeout)
+	}
+}
+
+// escuchar resuelve cada publish pendiente con la confirmación del broker hasta que se cierra el canal
+func (c *confirmador) escuchar(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	devueltos := map[string]amqp.Return{}
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			devueltos[ret.CorrelationId] = ret
+		case conf, ok := <-confirms:
+			if !ok {
+				c.cerrar()
+				return
+			}
+
+			// El broker manda el basic.return antes que la confirmación del mismo mensaje
+			returns = drenarDevueltos(returns, devueltos)
+
+			var err error
+			id := strconv.FormatUint(conf.DeliveryTag, 10)
+			if ret, ok := devueltos[id]; ok {
+				delete(devueltos, id)
+				err = fmt.Errorf("%w: exchange %q routing key %q (%d %s)",
+					domain.ErrEventoNoRuteado, ret.Exchange, ret.RoutingKey, ret.ReplyCode, ret.ReplyText)
+			} else if !conf.Ack {
+				err = fmt.Errorf("rabbitmq: broker nacked delivery %d", conf.DeliveryTag)
+			}
+			c.resolver(conf.DeliveryTag, err)
+		}
+	}
+}
+
+// drenarDevueltos junta los basic.return ya recibidos sin bloquear; devuelve nil si el canal se cerró
+func drenarDevueltos(returns <-chan amqp.Return, devueltos map[string]amqp.Return) <-chan amqp.Return {
+	for returns != nil {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return nil
+			}
+			devueltos[ret.CorrelationId] = ret
+		default:
+			return returns
+		}
+	}
+	return nil
+}
+
+func (c *confirmador) resolver(tag uint64, err error) {
+	c.mu.Lock()
+	respuesta, ok := c.pendientes[tag]
+	delete(c.pendientes, tag)
+	c.mu.Unlock()
+
+	if ok {
+		respuesta <- err
+	}
+}
+
+func (c *confirmador) olvidar(tag uint64) {
+	c.mu.Lock()
+	delete(c.pendientes, tag)
+	c.mu.Unlock()
+}
+
+// cerrar falla los publish que quedaron esperando: sin canal no va a llegar su confirmación
+func (c *confirmador) cerrar() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cerrado = true
+	for tag, respuesta := range c.pendientes {
+		respuesta <- domain.ErrBrokerDesconectado
+		delete(c.pendientes, tag)
+	}
+}
@@ -111,7 +111,7 @@ func main() {
 	// 7️⃣ Servicios de aplicación
 	fmt.Println("🧠 Creando servicios de aplicación...")
 	versionService := application.NewVersionService(versionRepository, *cfg.App)
-	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App)
+	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App, rabbitMQAdapter)
 	messageService := application.NewMessageService(messageRepository, messageQueue, *cfg.App)
 	fmt.Println("✅ Servicios creados")
 
@@ -1,6 +1,11 @@
 package rabbitmq
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
 
 func (r *RabbitMQAdapter) InitializeTopology() error {
 	r.exchange = "app_events" // ✅ setea el nombre del exchange
@@ -14,11 +19,32 @@ func (r *RabbitMQAdapter) InitializeTopology() error {
 	// Declarar colas
 	r.pubCh.QueueDeclare("config_updates_q", true, false, false, false, nil)
 	r.pubCh.QueueDeclare("user_created_q", true, false, false, false, nil)
+	r.pubCh.QueueDeclare("ai_reserves_users_q", true, false, false, false, nil)
+	r.pubCh.QueueDeclare("reserve_events_q", true, false, false, false, amqp.Table{
+		"x-max-length":  int32(10000),
+		"x-message-ttl": int32(7 * 24 * time.Hour / time.Millisecond),
+	})
 
 	// Vincular colas
 	r.pubCh.QueueBind("config_updates_q", "config.updated", r.exchange, false, nil)
 	r.pubCh.QueueBind("user_created_q", "user.created", r.exchange, false, nil)
 
-	fmt.Println("✅ Topología creada: 1 exchange, 2 colas, 2 bindings")
+	// ai-reserves sigue el ciclo de vida completo de los usuarios en su propia cola
+	for _, rk := range []string{"user.created", "user.updated", "user.revoked", "user.deleted"} {
+		r.pubCh.QueueBind("ai_reserves_users_q", rk, r.exchange, false, nil)
+	}
+
+	// ai-reserves publica sus eventos con mandatory y todavía ningún servicio los consume: sin una
+	// cola atada el broker los devuelve y el outbox los termina marcando FAILED. Esta cola los retiene
+	// hasta que haya consumidores reales, acotada (10000 mensajes, 7 días) para que no crezca sin
+	// límite. Se atan solo las routing keys conocidas: una key mal escrita se sigue devolviendo.
+	for _, rk := range []string{
+		"reserve.created", "reserve.confirmed", "reserve.cancelled",
+		"reserve.slot_offered", "reserve.rescheduled", "reserve.reminder",
+	} {
+		r.pubCh.QueueBind("reserve_events_q", rk, r.exchange, false, nil)
+	}
+
+	fmt.Println("✅ Topología creada: 1 exchange, 4 colas, 12 bindings")
 	return nil
 }
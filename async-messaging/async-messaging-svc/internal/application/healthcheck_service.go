@@ -12,14 +12,16 @@ import (
 )
 
 type HealthcheckService struct {
-	hr   ports.HealthcheckRepository
-	conf config.App
+	hr     ports.HealthcheckRepository
+	conf   config.App
+	broker ports.BrokerStatus
 }
 
-func NewHealthcheckService(hr ports.HealthcheckRepository, conf config.App) *HealthcheckService {
+func NewHealthcheckService(hr ports.HealthcheckRepository, conf config.App, broker ports.BrokerStatus) *HealthcheckService {
 	return &HealthcheckService{
 		hr,
 		conf,
+		broker,
 	}
 }
 
@@ -32,14 +34,22 @@ func (hs *HealthcheckService) GetHealthcheck(ctx context.Context) (*domain.Healt
 		return &domain.Healthcheck{}, serviceErr
 	}
 
-	return &domain.Healthcheck{
+	healthcheck := &domain.Healthcheck{
 		NombreApi:     hs.conf.Name,
 		Cliente:       hs.conf.Client,
 		Version:       hs.conf.Version,
 		VersionModelo: "",
 		FechaStartUp:  hs.conf.FechaStartUp,
 		BasesDeDatos:  listDBPing,
-	}, nil
+	}
+
+	// El broker caído no tira el healthcheck: el adapter reconecta solo y acá se informa el estado
+	if hs.broker != nil {
+		broker := hs.broker.Status()
+		healthcheck.Mensajeria = &broker
+	}
+
+	return healthcheck, nil
 }
 
 func mapServiceError(err error) error {
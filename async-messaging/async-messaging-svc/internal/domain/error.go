@@ -41,3 +41,11 @@ func (e *HealthcheckError) Error() string {
 }
 
 var ErrDuplicateEvent = errors.New("duplicate event ignored")
+
+// ErrBrokerDesconectado lo devuelve el adapter de mensajería mientras reconecta con el broker:
+// el evento no se publicó y hay que reintentarlo más tarde
+var ErrBrokerDesconectado = errors.New("message broker disconnected")
+
+// ErrEventoNoRuteado lo devuelve el adapter de mensajería cuando el broker devolvió el evento
+// publicado porque ninguna cola estaba atada a su routing key
+var ErrEventoNoRuteado = errors.New("event could not be routed to any queue")
@@ -15,5 +15,5 @@ type MessageRepository interface {
 
 type MessageQueue interface {
 	Publish(ctx context.Context, event domain.Event) error
-	Consume(ctx context.Context, queue string, handler func(domain.Event)) error
+	Consume(ctx context.Context, queue string, handler func(domain.Event) error) error
 }
@@ -76,7 +76,7 @@ func main() {
 
 	// 5️⃣ Servicios (application layer)
 	versionService := application.NewVersionService(versionRepository, *cfg.App)
-	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App)
+	healthcheckService := application.NewHealthcheckService(healthcheckRepository, *cfg.App, rmq)
 	securityService := application.NewSecurityService(securityRepository, *cfg.App, messageQueue)
 
 	// 6️⃣ Handlers HTTP (inbound adapters)
@@ -24,16 +24,17 @@ func NewUserEventConsumer(service *application.SecurityService, rabbit *rabbitmq
 
 // Start escucha la cola y enruta los eventos según el RoutingKey
 func (c *UserEventConsumer) Start(ctx context.Context, queue string) {
-	handler := func(evt domain.Event) {
+	handler := func(evt domain.Event) error {
 		fmt.Printf("📩 Received event: %s | RoutingKey: %s\n", evt.ID, evt.RoutingKey)
 
 		switch evt.RoutingKey {
 		case "user.created":
-			c.handleUserCreated(ctx, evt)
+			return c.handleUserCreated(ctx, evt)
 		//case "user.deleted":
-		//	c.handleUserDeleted(ctx, evt)
+		//	return c.handleUserDeleted(ctx, evt)
 		default:
 			fmt.Printf("⚠️ Unknown routing key: %s (ignored)\n", evt.RoutingKey)
+			return nil
 		}
 	}
 	fmt.Println("STARTING CONSUMER")
@@ -43,12 +44,14 @@ func (c *UserEventConsumer) Start(ctx context.Context, queue string) {
 }
 
 // 🧩 Handler para user.created
-func (c *UserEventConsumer) handleUserCreated(ctx context.Context, evt domain.Event) {
+// Un payload inválido se descarta (reintentarlo no cambia nada); un error al crear el usuario vuelve
+// al adapter para que reencole el mensaje
+func (c *UserEventConsumer) handleUserCreated(ctx context.Context, evt domain.Event) error {
 	var payload domain.UserCreated
 	data, _ := json.Marshal(evt.Payload)
 	if err := json.Unmarshal(data, &payload); err != nil {
 		fmt.Printf("⚠️ Invalid payload for user.created: %v\n", err)
-		return
+		return nil
 	}
 	fmt.Println("calling from handleUserCreated")
 	fmt.Printf("DEBUG Payload type: %T\n", evt.Payload)
@@ -56,9 +59,9 @@ func (c *UserEventConsumer) handleUserCreated(ctx context.Context, evt domain.Ev
 
 	fmt.Printf("DEBUG payload value: %+v\n", payload)
 	if _, err := c.service.CreateUserAPI(ctx, payload); err != nil {
-		fmt.Printf("❌ Error creating user: %v\n", err)
-		return
+		return fmt.Errorf("creating user %s: %w", payload.LoginName, err)
 	}
 
 	fmt.Printf("✅ User created successfully: %s\n", payload.LoginName)
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/FrancoRebollo/auth-security-svc/internal/domain"
@@ -11,47 +12,55 @@ import (
 )
 
 type RabbitMQAdapter struct {
-	conn  *amqp.Connection
-	pubCh *amqp.Channel
-	conCh *amqp.Channel
+	url string
+
+	// conexión y canales vigentes; los reemplaza la reconexión (ver connection.go)
+	mu           sync.RWMutex
+	conn         *amqp.Connection
+	pubCh        *amqp.Channel
+	conCh        *amqp.Channel
+	publicador   *confirmador
+	conectado    bool
+	ultimoCambio time.Time
+	ultimoError  string
+	reconexiones int
+	consumers    []consumer
+	subMu        sync.Mutex // Consume y resubscribe no se intercalan: ningún consumer se pierde en una reconexión
+	done         chan struct{}
+	closeOnce    sync.Once
+
 	// topology
 	exchange string // e.g. "app_events"
 }
 
 func NewRabbitMQAdapter(amqpURL, exchange string) (*RabbitMQAdapter, error) {
-	conn, err := amqp.Dial(amqpURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
-	}
-	pubCh, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open publish channel: %w", err)
+	r := &RabbitMQAdapter{
+		url:      amqpURL,
+		done:     make(chan struct{}),
+		exchange: "app_events",
 	}
-	conCh, err := conn.Channel()
-	if err != nil {
-		pubCh.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to open consume channel: %w", err)
+	// La primera conexión tiene que funcionar; las caídas posteriores las recupera supervise
+	if err := r.connect(); err != nil {
+		return nil, err
 	}
+	go r.supervise()
 
-	return &RabbitMQAdapter{
-		conn:     conn,
-		pubCh:    pubCh,
-		conCh:    conCh,
-		exchange: "app_events",
-	}, nil
+	return r, nil
 }
 
-// Publish with routing key (e.g., "config.updated", "user.created")
+// Publish with routing key (e.g., "config.updated", "user.created").
+// Devuelve nil recién cuando el broker confirmó el mensaje (ver confirms.go).
 func (r *RabbitMQAdapter) Publish(ctx context.Context, event domain.Event) error {
 	body, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
-	return r.pubCh.Publish(
+	publicador, err := r.publisher()
+	if err != nil {
+		return err
+	}
+	return publicador.publish(ctx,
 		r.exchange, event.RoutingKey,
-		false, false,
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        body,
@@ -60,13 +69,35 @@ func (r *RabbitMQAdapter) Publish(ctx context.Context, event domain.Event) error
 	)
 }
 
-// Consume from a named queue that has already been bound to routing keys
-func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler func(domain.Event)) error {
-	// (Optional) QoS to avoid overwhelming consumers
-	if err := r.conCh.Qos(50, 0, false); err != nil {
-		return fmt.Errorf("qos: %w", err)
+// Consume from a named queue that has already been bound to routing keys.
+// El consumer queda registrado antes de suscribirse y se vuelve a suscribir después de cada
+// reconexión: si la primera suscripción falla la reintenta la reconexión, con la misma espera creciente.
+func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler func(domain.Event) error) error {
+	c := consumer{ctx: ctx, queue: queue, handler: handler}
+
+	// Registrar y suscribir con subMu tomado: si el broker se cae en el medio, resubscribe espera
+	// y encuentra el consumer ya en la lista
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.mu.Lock()
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+
+	if err := r.subscribe(c); err != nil {
+		fmt.Printf("⚠️ Consumer on %s not subscribed yet, retrying on reconnection: %v\n", c.queue, err)
+		r.reintentarConsumers()
 	}
-	msgs, err := r.conCh.Consume(queue, "", false, false, false, false, nil)
+	return nil
+}
+
+// subscribe empieza a consumir la cola en el canal vigente
+func (r *RabbitMQAdapter) subscribe(c consumer) error {
+	_, conCh, err := r.channels()
+	if err != nil {
+		return err
+	}
+	msgs, err := conCh.Consume(c.queue, "", false, false, false, false, nil)
 	if err != nil {
 		return fmt.Errorf("consume: %w", err)
 	}
@@ -74,10 +105,12 @@ func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler fun
 	go func() {
 		for {
 			select {
-			case <-ctx.Done():
+			case <-c.ctx.Done():
 				return
 			case d, ok := <-msgs:
 				if !ok {
+					// Se cerró el canal: supervise vuelve a suscribir el consumer al reconectar
+					fmt.Printf("⚠️ Consumer on %s stopped, waiting for reconnection\n", c.queue)
 					return
 				}
 
@@ -89,9 +122,14 @@ func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler fun
 				}
 
 				// 👉 Ahora sí, pasamos el evento de dominio al handler
-				handler(event)
+				if err := c.handler(event); err != nil {
+					// Se reencola una vez; si vuelve a fallar se descarta
+					fmt.Printf("❌ Error handling %s on %s (redelivered=%t): %v\n", event.ID, c.queue, d.Redelivered, err)
+					_ = d.Nack(false, !d.Redelivered)
+					continue
+				}
 
-				// (Opcional) confirmar procesamiento exitoso
+				// Procesado: recién ahora se confirma
 				_ = d.Ack(false)
 
 			}
@@ -103,14 +141,22 @@ func (r *RabbitMQAdapter) Consume(ctx context.Context, queue string, handler fun
 func (r *RabbitMQAdapter) Ack(d amqp.Delivery)                { _ = d.Ack(false) }
 func (r *RabbitMQAdapter) Nack(d amqp.Delivery, requeue bool) { _ = d.Nack(false, requeue) }
 
+// Close detiene la supervisión y cierra canales y conexión; se puede llamar más de una vez
 func (r *RabbitMQAdapter) Close() {
-	if r.pubCh != nil {
-		_ = r.pubCh.Close()
-	}
-	if r.conCh != nil {
-		_ = r.conCh.Close()
-	}
-	if r.conn != nil {
-		_ = r.conn.Close()
-	}
+	r.closeOnce.Do(func() {
+		close(r.done)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.conectado = false
+		if r.pubCh != nil {
+			_ = r.pubCh.Close()
+		}
+		if r.conCh != nil {
+			_ = r.conCh.Close()
+		}
+		if r.conn != nil {
+			_ = r.conn.Close()
+		}
+	})
 }
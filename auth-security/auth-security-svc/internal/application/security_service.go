@@ -347,6 +347,10 @@ func (s *SecurityService) ProcessOutboxEvents(ctx context.Context) error {
 		// Intentamos publicar en RabbitMQ
 		if err := s.rmq.Publish(ctx, evt); err != nil {
 			fmt.Printf("❌ Error publishing event %s: %v\n", evt.ID, err)
+			// Broker caído: el adapter está reconectando, los eventos quedan pendientes para la próxima pasada
+			if errors.Is(err, domain.ErrBrokerDesconectado) {
+				return nil
+			}
 			// Se marca como failed (pero no interrumpe el batch)
 			err = s.hr.MarkOutboxAsFailed(ctx, evt.ID)
 			if err != nil {
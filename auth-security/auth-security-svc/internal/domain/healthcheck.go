@@ -7,9 +7,19 @@ type Healthcheck struct {
 	VersionModelo string     `json:"version_modelo"`
 	FechaStartUp  string     `json:"fecha_start_up"`
 	BasesDeDatos  []Database `json:"bases_de_datos"`
+	Mensajeria    *Broker    `json:"mensajeria,omitempty"`
 }
 
 type Database struct {
 	Base                     string `json:"base"`
 	FechaHoraUltimaActividad string `json:"fecha_hora_ultima_actividad"`
 }
+
+// Broker es el estado de la conexión con el broker de mensajería
+type Broker struct {
+	Broker                string `json:"broker"`
+	Conectado             bool   `json:"conectado"`
+	FechaHoraUltimoCambio string `json:"fecha_hora_ultimo_cambio"`
+	Reconexiones          int    `json:"reconexiones"`
+	UltimoError           string `json:"ultimo_error,omitempty"`
+}